@@ -4,19 +4,30 @@ package main
 
 import (
 	"context"
+
 	"github.com/thegodeveloper/data-gateway/internal/app"
 	"github.com/thegodeveloper/data-gateway/internal/config"
-	"github.com/thegodeveloper/data-gateway/internal/datasource/dynamodb"
-	"github.com/thegodeveloper/data-gateway/internal/datasource/mongodb"
-	"github.com/thegodeveloper/data-gateway/internal/datasource/postgres"
-	"github.com/thegodeveloper/data-gateway/internal/domain"
+	"github.com/thegodeveloper/data-gateway/internal/datasource"
+	"github.com/thegodeveloper/data-gateway/internal/graphql"
+	"github.com/thegodeveloper/data-gateway/internal/route"
 	"github.com/thegodeveloper/data-gateway/internal/transport/http"
 	"github.com/thegodeveloper/data-gateway/pkg/common"
 	"github.com/thegodeveloper/data-gateway/pkg/otel"
+
+	// Adapters register their factories with the datasource registry.
+	_ "github.com/thegodeveloper/data-gateway/internal/datasource/dynamodb"
+	_ "github.com/thegodeveloper/data-gateway/internal/datasource/mongodb"
+	_ "github.com/thegodeveloper/data-gateway/internal/datasource/postgres"
 )
 
 func main() {
-	cfg := config.Load()
+	ctx := context.Background()
+
+	cfg, err := config.Load()
+	if err != nil {
+		common.Error("failed to load configuration: %v", err)
+		return
+	}
 
 	shutdown, err := otel.InitTracer("data-gateway")
 	if err != nil {
@@ -25,30 +36,61 @@ func main() {
 	}
 	defer shutdown(context.Background())
 
-	pg, err := postgres.NewPostgresSource(cfg.PostgresConnStr)
+	shutdownMeter, err := otel.InitMeter("data-gateway")
 	if err != nil {
-		common.Error("Postgres init failed: %v", err)
+		common.Error("failed to init OpenTelemetry metrics: %v", err)
 		return
 	}
+	defer shutdownMeter(context.Background())
 
-	dynamo, err := dynamodb.NewDynamoDBSource()
+	sources, err := datasource.Open(ctx, cfg.DataSources)
 	if err != nil {
-		common.Error("DynamoDB init failed: %v", err)
+		common.Error("data source init failed: %v", err)
 		return
 	}
+	defer func() {
+		if err := datasource.Close(context.Background(), sources); err != nil {
+			common.Error("failed to close data sources: %v", err)
+		}
+	}()
+	for name, ds := range cfg.DataSources {
+		common.Info("Data source %q ready (type %s)", name, ds.Type)
+	}
 
-	mongo, err := mongodb.NewMongoSource(cfg.MongoURI)
+	routes, err := route.NewTable(cfg.Routes)
 	if err != nil {
-		common.Error("MongoDB init failed: %v", err)
+		common.Error("invalid route table: %v", err)
 		return
 	}
+	for _, rt := range routes.Routes() {
+		if _, ok := sources[rt.Source]; !ok {
+			common.Error("route %s %s: unknown data source '%s'", rt.Method, rt.Pattern, rt.Source)
+			return
+		}
+	}
+
+	svc := app.NewGatewayService(sources, routes)
+	if cfg.PageTokenKey != "" {
+		svc.SetPageTokenKey([]byte(cfg.PageTokenKey))
+	}
+	for name, ds := range cfg.DataSources {
+		if ds.Writes {
+			svc.AllowWrites(name)
+		}
+	}
 
-	svc := app.NewGatewayService(map[string]domain.DataSource{
-		"postgres": pg,
-		"dynamodb": dynamo,
-		"mongodb":  mongo,
-	})
+	var schema *graphql.Schema
+	if cfg.GraphQL != nil {
+		schema, err = graphql.NewSchema(ctx, svc, *cfg.GraphQL)
+		if err != nil {
+			common.Error("invalid GraphQL configuration: %v", err)
+			return
+		}
+		common.Info("GraphQL endpoint ready with %d types", len(cfg.GraphQL.Types))
+	}
 
 	common.Info("Starting HTTP server on port %s", cfg.HTTPPort)
-	http.StartServer(svc, cfg.HTTPPort)
+	if err := http.StartServer(svc, schema, cfg.HTTPPort); err != nil {
+		common.Error("HTTP server stopped: %v", err)
+	}
 }
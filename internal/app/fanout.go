@@ -0,0 +1,248 @@
+// Package app
+// internal/app/fanout.go
+package app
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/thegodeveloper/data-gateway/internal/domain"
+	"go.opentelemetry.io/otel"
+)
+
+// HandleFanOut runs every sub-query at once under one deadline and merges
+// what comes back. A sub-query that fails or runs out of time is reported
+// in the result's sources instead of failing the request; only a fan-out
+// where nothing succeeded is an error.
+//
+// Structured sub-queries of a sorted merge are sorted by SortBy at the
+// source unless they sort themselves, and take the request's Limit unless
+// they set one or sort some other way, so each source returns no more than
+// the merge can use.
+func (s *GatewayService) HandleFanOut(ctx context.Context, req domain.FanOutRequest) (*domain.FanOutResult, error) {
+	ctx, span := otel.Tracer("data-gateway").Start(ctx, "GatewayService.HandleFanOut")
+	defer span.End()
+
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	for _, q := range req.Queries {
+		if _, ok := s.dataSources[q.Source]; !ok {
+			return nil, fmt.Errorf("%w: '%s'", domain.ErrUnknownSource, q.Source)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, req.Timeout())
+	defer cancel()
+
+	type outcome struct {
+		i    int
+		rows []map[string]any
+		err  error
+	}
+	outcomes := make(chan outcome, len(req.Queries))
+	for i, q := range req.Queries {
+		q = pushDown(req, q)
+		go func() {
+			rows, err := s.queryRows(ctx, q)
+			outcomes <- outcome{i, rows, err}
+		}()
+	}
+
+	res := &domain.FanOutResult{Sources: make([]domain.SubQueryResult, len(req.Queries))}
+	results := make([][]map[string]any, len(req.Queries))
+	errs := make([]error, len(req.Queries))
+	winner := -1
+	// Every sub-query is waited for, so none outlives the request; after a
+	// MergeFirst win the others return as soon as they notice the cancel.
+	for range req.Queries {
+		o := <-outcomes
+		sub := &res.Sources[o.i]
+		sub.Source = req.Queries[o.i].Source
+		errs[o.i] = o.err
+		switch {
+		case winner >= 0:
+			sub.Status = domain.SubQuerySkipped
+		case o.err == nil:
+			sub.Status, sub.Rows = domain.SubQueryOK, len(o.rows)
+			results[o.i] = o.rows
+			if req.Merge == domain.MergeFirst {
+				winner = o.i
+				cancel()
+			}
+		case errors.Is(o.err, context.DeadlineExceeded) || ctx.Err() == context.DeadlineExceeded:
+			sub.Status, sub.Error = domain.SubQueryTimedOut, o.err.Error()
+			res.Partial = true
+		default:
+			sub.Status, sub.Error = domain.SubQueryFailed, o.err.Error()
+			res.Partial = true
+		}
+	}
+
+	if !slices.ContainsFunc(res.Sources, func(sub domain.SubQueryResult) bool { return sub.Status == domain.SubQueryOK }) {
+		// The first sub-query's error decides the response status.
+		err := fmt.Errorf("every sub-query failed, the first with: %w", errs[0])
+		span.RecordError(err)
+		return nil, err
+	}
+
+	switch req.Merge {
+	case domain.MergeFirst:
+		res.Items = results[winner]
+	case domain.MergeSorted:
+		res.Items = mergeSorted(results, req.SortBy)
+	default:
+		for _, rows := range results {
+			res.Items = append(res.Items, rows...)
+		}
+	}
+	if req.Limit > 0 && len(res.Items) > req.Limit {
+		res.Items = res.Items[:req.Limit]
+	}
+	if res.Items == nil {
+		res.Items = []map[string]any{}
+	}
+	return res, nil
+}
+
+// pushDown gives a structured sub-query the merge's order and limit when it
+// has none of its own. A sub-query of a sorted merge that sorts some other
+// way keeps every row: the ones the merge keeps need not be among its first.
+func pushDown(req domain.FanOutRequest, q domain.QueryRequest) domain.QueryRequest {
+	if q.Query == nil {
+		return q
+	}
+	query := *q.Query
+	if req.Merge == domain.MergeSorted && len(query.Sort) == 0 {
+		query.Sort = req.SortBy
+	}
+	inOrder := req.Merge != domain.MergeSorted || slices.Equal(query.Sort, req.SortBy)
+	if req.Limit > 0 && query.Limit == 0 && query.Offset == 0 && inOrder {
+		query.Limit = req.Limit
+	}
+	q.Query = &query
+	return q
+}
+
+// mergeSorted interleaves the results by the sort fields. Results that are
+// not already in that order, e.g. of raw params reads, are sorted first.
+// Ties keep the order of the sub-queries. There are few results, so the
+// next row is found by comparing their heads.
+func mergeSorted(results [][]map[string]any, sortBy []domain.SortField) []map[string]any {
+	compare := func(a, b map[string]any) int { return compareRows(a, b, sortBy) }
+	total := 0
+	for _, rows := range results {
+		if !slices.IsSortedFunc(rows, compare) {
+			slices.SortStableFunc(rows, compare)
+		}
+		total += len(rows)
+	}
+
+	merged := make([]map[string]any, 0, total)
+	heads := make([]int, len(results))
+	for len(merged) < total {
+		next := -1
+		for i, rows := range results {
+			if heads[i] == len(rows) {
+				continue
+			}
+			if next < 0 || compare(rows[heads[i]], results[next][heads[next]]) < 0 {
+				next = i
+			}
+		}
+		merged = append(merged, results[next][heads[next]])
+		heads[next]++
+	}
+	return merged
+}
+
+func compareRows(a, b map[string]any, sortBy []domain.SortField) int {
+	for _, s := range sortBy {
+		c := compareValues(a[s.Field], b[s.Field])
+		if s.Desc {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// compareValues orders the values sources return: missing values first,
+// then booleans, numbers of any type, strings and times. Values of other
+// kinds compare by their text.
+func compareValues(a, b any) int {
+	ra, rb := valueRank(a), valueRank(b)
+	if ra != rb {
+		return cmp.Compare(ra, rb)
+	}
+	switch x := a.(type) {
+	case nil:
+		return 0
+	case bool:
+		y := b.(bool)
+		switch {
+		case x == y:
+			return 0
+		case !x:
+			return -1
+		}
+		return 1
+	case string:
+		return strings.Compare(x, b.(string))
+	case time.Time:
+		return x.Compare(b.(time.Time))
+	}
+	if fa, ok := toFloat(a); ok {
+		fb, _ := toFloat(b)
+		return cmp.Compare(fa, fb)
+	}
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+func valueRank(v any) int {
+	switch v.(type) {
+	case nil:
+		return 0
+	case bool:
+		return 1
+	case string:
+		return 3
+	case time.Time:
+		return 4
+	}
+	if _, ok := toFloat(v); ok {
+		return 2
+	}
+	return 5
+}
+
+func toFloat(v any) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case float32:
+		return float64(n), true
+	case float64:
+		return n, true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
@@ -0,0 +1,210 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/thegodeveloper/data-gateway/internal/domain"
+)
+
+// sourceFunc is a data source made of its Query method.
+type sourceFunc func(ctx context.Context, req domain.QueryRequest) (any, error)
+
+func (f sourceFunc) Query(ctx context.Context, req domain.QueryRequest) (any, error) {
+	return f(ctx, req)
+}
+
+func returns(rows ...map[string]any) sourceFunc {
+	return func(context.Context, domain.QueryRequest) (any, error) { return rows, nil }
+}
+
+// blocks waits for its context to end and records that it did.
+func blocks(ended *atomic.Value) sourceFunc {
+	return func(ctx context.Context, _ domain.QueryRequest) (any, error) {
+		<-ctx.Done()
+		ended.Store(ctx.Err())
+		return nil, ctx.Err()
+	}
+}
+
+func TestPushDown(t *testing.T) {
+	byDate := []domain.SortField{{Field: "created_at", Desc: true}}
+	byName := []domain.SortField{{Field: "name"}}
+	tests := []struct {
+		name      string
+		req       domain.FanOutRequest
+		query     domain.Query
+		wantSort  []domain.SortField
+		wantLimit int
+	}{
+		{"concat takes the limit", domain.FanOutRequest{Limit: 10}, domain.Query{}, nil, 10},
+		{"own limit kept", domain.FanOutRequest{Limit: 10}, domain.Query{Limit: 3}, nil, 3},
+		{"offset keeps every row", domain.FanOutRequest{Limit: 10}, domain.Query{Offset: 5}, nil, 0},
+		{"sorted takes order and limit", domain.FanOutRequest{Merge: domain.MergeSorted, SortBy: byDate, Limit: 10}, domain.Query{}, byDate, 10},
+		{"same order takes the limit", domain.FanOutRequest{Merge: domain.MergeSorted, SortBy: byDate, Limit: 10}, domain.Query{Sort: byDate}, byDate, 10},
+		{"other order keeps every row", domain.FanOutRequest{Merge: domain.MergeSorted, SortBy: byDate, Limit: 10}, domain.Query{Sort: byName}, byName, 0},
+		{"first takes the limit", domain.FanOutRequest{Merge: domain.MergeFirst, Limit: 10}, domain.Query{Sort: byName}, byName, 10},
+	}
+	for _, tt := range tests {
+		q := tt.query
+		got := pushDown(tt.req, domain.QueryRequest{Source: "a", Query: &q})
+		if !reflect.DeepEqual(got.Query.Sort, tt.wantSort) || got.Query.Limit != tt.wantLimit {
+			t.Errorf("%s: sort %v limit %d, want sort %v limit %d", tt.name, got.Query.Sort, got.Query.Limit, tt.wantSort, tt.wantLimit)
+		}
+	}
+	q := domain.Query{}
+	pushDown(domain.FanOutRequest{Limit: 1}, domain.QueryRequest{Query: &q})
+	if q.Limit != 0 {
+		t.Error("pushDown changed the caller's query")
+	}
+}
+
+func TestCompareValues(t *testing.T) {
+	at := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		a, b any
+		want int
+	}{
+		{nil, nil, 0},
+		{nil, false, -1},
+		{false, true, -1},
+		{true, true, 0},
+		{true, 0, -1},
+		{int64(2), 1.5, 1},
+		{int32(7), json.Number("7"), 0},
+		{uint64(3), float32(3.5), -1},
+		{1e9, "a", -1},
+		{"a", "b", -1},
+		{"b", at, -1},
+		{at, at.Add(time.Second), -1},
+		{at, []any{1}, -1},
+		{map[string]any{"a": 2}, map[string]any{"a": 1}, 1},
+	}
+	for _, tt := range tests {
+		if got := compareValues(tt.a, tt.b); got != tt.want {
+			t.Errorf("compareValues(%#v, %#v) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+		if got := compareValues(tt.b, tt.a); got != -tt.want {
+			t.Errorf("compareValues(%#v, %#v) = %d, want %d", tt.b, tt.a, got, -tt.want)
+		}
+	}
+}
+
+func TestMergeSorted(t *testing.T) {
+	row := func(id string, n any) map[string]any { return map[string]any{"id": id, "n": n} }
+	tests := []struct {
+		name    string
+		results [][]map[string]any
+		sortBy  []domain.SortField
+		want    []string
+	}{
+		{
+			"interleaves sorted results",
+			[][]map[string]any{{row("a1", 1), row("a3", 3)}, {row("b2", int64(2)), row("b4", 4.0)}},
+			[]domain.SortField{{Field: "n"}},
+			[]string{"a1", "b2", "a3", "b4"},
+		},
+		{
+			"sorts unsorted results first",
+			[][]map[string]any{{row("a3", 3), row("a1", 1)}, {row("b2", 2)}},
+			[]domain.SortField{{Field: "n"}},
+			[]string{"a1", "b2", "a3"},
+		},
+		{
+			"descending, missing values last",
+			[][]map[string]any{{row("a1", 1), {"id": "a-"}}, {row("b2", 2)}},
+			[]domain.SortField{{Field: "n", Desc: true}},
+			[]string{"b2", "a1", "a-"},
+		},
+		{
+			"ties keep the order of the sub-queries",
+			[][]map[string]any{{row("a1", 1)}, {row("b1", 1)}, {}, {row("d1", 1)}},
+			[]domain.SortField{{Field: "n"}},
+			[]string{"a1", "b1", "d1"},
+		},
+		{
+			"second field breaks ties",
+			[][]map[string]any{{{"id": "a", "n": 1, "m": "y"}}, {{"id": "b", "n": 1, "m": "x"}}},
+			[]domain.SortField{{Field: "n"}, {Field: "m"}},
+			[]string{"b", "a"},
+		},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, r := range mergeSorted(tt.results, tt.sortBy) {
+			got = append(got, r["id"].(string))
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: merged %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFanOutReportsFailuresAndTimeouts(t *testing.T) {
+	var ended atomic.Value
+	svc := NewGatewayService(map[string]domain.DataSource{
+		"ok":     returns(map[string]any{"id": 1}),
+		"broken": sourceFunc(func(context.Context, domain.QueryRequest) (any, error) { return nil, errors.New("connection refused") }),
+		"slow":   blocks(&ended),
+	}, nil)
+
+	res, err := svc.HandleFanOut(context.Background(), domain.FanOutRequest{
+		Queries:   []domain.QueryRequest{{Source: "broken"}, {Source: "ok"}, {Source: "slow"}},
+		TimeoutMS: 20,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.Partial || len(res.Items) != 1 {
+		t.Fatalf("partial = %v, items = %v", res.Partial, res.Items)
+	}
+	want := []domain.SubQueryStatus{domain.SubQueryFailed, domain.SubQueryOK, domain.SubQueryTimedOut}
+	for i, sub := range res.Sources {
+		if sub.Status != want[i] {
+			t.Errorf("sources[%d] = %+v, want status %s", i, sub, want[i])
+		}
+	}
+	if ended.Load() != context.DeadlineExceeded {
+		t.Errorf("slow source ended with %v, want the deadline", ended.Load())
+	}
+
+	_, err = svc.HandleFanOut(context.Background(), domain.FanOutRequest{
+		Queries:   []domain.QueryRequest{{Source: "slow"}, {Source: "broken"}},
+		TimeoutMS: 20,
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("all failed: error = %v, want the first sub-query's", err)
+	}
+}
+
+func TestFanOutFirstWinsAndCancelsTheRest(t *testing.T) {
+	var ended atomic.Value
+	svc := NewGatewayService(map[string]domain.DataSource{
+		"fast": returns(map[string]any{"id": "fast"}),
+		"slow": blocks(&ended),
+	}, nil)
+
+	res, err := svc.HandleFanOut(context.Background(), domain.FanOutRequest{
+		Queries: []domain.QueryRequest{{Source: "slow"}, {Source: "fast"}},
+		Merge:   domain.MergeFirst,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Items) != 1 || res.Items[0]["id"] != "fast" || res.Partial {
+		t.Fatalf("result = %+v", res)
+	}
+	if res.Sources[0].Status != domain.SubQuerySkipped || res.Sources[1].Status != domain.SubQueryOK {
+		t.Fatalf("sources = %+v", res.Sources)
+	}
+	// HandleFanOut waits for every sub-query, so the loser has already seen
+	// the cancel rather than the 10 second default deadline.
+	if ended.Load() != context.Canceled {
+		t.Fatalf("slow source ended with %v, want it cancelled", ended.Load())
+	}
+}
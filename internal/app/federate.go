@@ -0,0 +1,253 @@
+// Package app
+// internal/app/federate.go
+package app
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/thegodeveloper/data-gateway/internal/domain"
+	"go.opentelemetry.io/otel"
+)
+
+// stageRows are the rows a stage's joins attach to and the path of those
+// rows in the result. A stage merged into its parent shares the parent's
+// rows, since that is where its fields went.
+type stageRows struct {
+	rows []map[string]any
+	path string
+}
+
+// HandleFederated runs the stages of a federated request in order. The
+// first stage reads the root rows. Each later stage collects the distinct
+// join values of the stage it joins onto and reads its matches with an
+// "in" condition, Batch keys at a time (or fewer, for a domain.KeyBatcher),
+// which the adapters run as IN lists, $in filters or BatchGetItem calls.
+func (s *GatewayService) HandleFederated(ctx context.Context, req domain.FederatedRequest) (*domain.FederatedResult, error) {
+	ctx, span := otel.Tracer("data-gateway").Start(ctx, "GatewayService.HandleFederated")
+	defer span.End()
+
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	selectJoinFields(req.Stages)
+
+	res := &domain.FederatedResult{Provenance: make(map[string]domain.FieldSource)}
+	done := make(map[string]stageRows, len(req.Stages))
+	for i, st := range req.Stages {
+		origin := domain.FieldSource{Stage: st.Name, Source: st.Source, Target: st.Query.Target}
+		if i == 0 {
+			rows, err := s.readStage(ctx, st)
+			if err != nil {
+				span.RecordError(err)
+				return nil, err
+			}
+			for _, row := range rows {
+				for field := range row {
+					res.Provenance[field] = origin
+				}
+			}
+			res.Items = rows
+			done[st.Name] = stageRows{rows: rows}
+			continue
+		}
+
+		parentName := st.Join.Stage
+		if parentName == "" {
+			parentName = req.Stages[i-1].Name
+		}
+		parent := done[parentName]
+		rows, err := s.joinStage(ctx, st, parent.rows)
+		if err != nil {
+			span.RecordError(err)
+			return nil, err
+		}
+		done[st.Name] = attach(st, parent, rows, origin, res.Provenance)
+	}
+	if res.Items == nil {
+		res.Items = []map[string]any{}
+	}
+	return res, nil
+}
+
+// readStage runs the first stage, asking for one row more than its limit so
+// that going over it is noticed.
+func (s *GatewayService) readStage(ctx context.Context, st domain.Stage) ([]map[string]any, error) {
+	limit := stageLimit(st)
+	q := st.Query
+	if q.Limit == 0 || q.Limit > limit {
+		q.Limit = limit + 1
+	}
+	rows, err := s.stageQuery(ctx, st, q)
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) > limit {
+		return nil, stageOverflow(st, limit)
+	}
+	return rows, nil
+}
+
+// joinStage reads the rows of st whose On field matches the From field of
+// the parent rows.
+func (s *GatewayService) joinStage(ctx context.Context, st domain.Stage, parents []map[string]any) ([]map[string]any, error) {
+	var keys []any
+	seen := make(map[string]bool)
+	for _, row := range parents {
+		v := row[st.Join.From]
+		if k, ok := domain.JoinKey(v); ok && !seen[k] {
+			seen[k] = true
+			keys = append(keys, v)
+		}
+	}
+
+	batch := st.Join.Batch
+	if batch == 0 {
+		batch = domain.DefaultJoinBatch
+	}
+	if kb, ok := s.dataSources[st.Source].(domain.KeyBatcher); ok {
+		batch = min(batch, kb.MaxKeys())
+	}
+	limit := stageLimit(st)
+	var rows []map[string]any
+	for chunk := range slices.Chunk(keys, batch) {
+		q := st.Query
+		match := domain.Condition{Field: st.Join.On, Op: domain.OpIn, Value: chunk}
+		if q.Where != nil {
+			match = domain.Condition{And: []domain.Condition{*q.Where, match}}
+		}
+		q.Where = &match
+		q.Limit = limit - len(rows) + 1
+		found, err := s.stageQuery(ctx, st, q)
+		if err != nil {
+			return nil, err
+		}
+		if rows = append(rows, found...); len(rows) > limit {
+			return nil, stageOverflow(st, limit)
+		}
+	}
+	return rows, nil
+}
+
+// stageQuery runs one query of a stage.
+func (s *GatewayService) stageQuery(ctx context.Context, st domain.Stage, q domain.Query) ([]map[string]any, error) {
+	rows, err := s.queryRows(ctx, domain.QueryRequest{Source: st.Source, Params: st.Params, Query: &q})
+	if err != nil {
+		return nil, fmt.Errorf("stage '%s': %w", st.Name, err)
+	}
+	return rows, nil
+}
+
+// queryRows runs a read through HandleQuery, so the source's own limits and
+// policies apply, and expects rows back.
+func (s *GatewayService) queryRows(ctx context.Context, req domain.QueryRequest) ([]map[string]any, error) {
+	result, err := s.HandleQuery(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	rows, ok := result.([]map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("source '%s' returned %T instead of rows", req.Source, result)
+	}
+	return rows, nil
+}
+
+// attach puts the matches of each parent row into it and records where the
+// new fields came from. It returns the rows later stages joining onto st
+// attach to.
+func attach(st domain.Stage, parent stageRows, rows []map[string]any, origin domain.FieldSource, provenance map[string]domain.FieldSource) stageRows {
+	j := st.Join
+	matches := make(map[string][]map[string]any)
+	for _, row := range rows {
+		if k, ok := domain.JoinKey(row[j.On]); ok {
+			matches[k] = append(matches[k], row)
+		}
+	}
+
+	if j.As == "" {
+		for _, row := range parent.rows {
+			k, ok := domain.JoinKey(row[j.From])
+			if !ok || len(matches[k]) == 0 {
+				continue
+			}
+			for field, v := range matches[k][0] {
+				if _, clash := row[field]; !clash {
+					row[field] = v
+					provenance[joinPath(parent.path, field)] = origin
+				}
+			}
+		}
+		return parent
+	}
+
+	path := joinPath(parent.path, j.As)
+	provenance[path] = origin
+	for _, row := range rows {
+		for field := range row {
+			provenance[joinPath(path, field)] = origin
+		}
+	}
+	for _, row := range parent.rows {
+		k, _ := domain.JoinKey(row[j.From])
+		found := matches[k]
+		switch {
+		case j.Many && found == nil:
+			row[j.As] = []map[string]any{}
+		case j.Many:
+			row[j.As] = found
+		case len(found) > 0:
+			row[j.As] = found[0]
+		default:
+			row[j.As] = nil
+		}
+	}
+	return stageRows{rows: rows, path: path}
+}
+
+func joinPath(path, field string) string {
+	if path == "" {
+		return field
+	}
+	return path + "." + field
+}
+
+// selectJoinFields adds the fields joins match on to stages that select
+// only some fields. A stage merged into its parent is left alone: the field
+// may belong to the parent.
+func selectJoinFields(stages []domain.Stage) {
+	index := make(map[string]int, len(stages))
+	for i, st := range stages {
+		index[st.Name] = i
+	}
+	need := func(q *domain.Query, field string) {
+		if len(q.Select) > 0 && !slices.Contains(q.Select, field) {
+			q.Select = append(slices.Clip(q.Select), field)
+		}
+	}
+	for i := range stages {
+		j := stages[i].Join
+		if j == nil {
+			continue
+		}
+		need(&stages[i].Query, j.On)
+		parent := i - 1
+		if j.Stage != "" {
+			parent = index[j.Stage]
+		}
+		if p := stages[parent]; p.Join == nil || p.Join.As != "" {
+			need(&stages[parent].Query, j.From)
+		}
+	}
+}
+
+func stageLimit(st domain.Stage) int {
+	if st.Limit > 0 {
+		return st.Limit
+	}
+	return domain.DefaultStageLimit
+}
+
+func stageOverflow(st domain.Stage, limit int) error {
+	return fmt.Errorf("%w: stage '%s' matched more than %d rows; narrow its query or raise its 'limit'", domain.ErrInvalidRequest, st.Name, limit)
+}
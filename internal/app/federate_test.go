@@ -0,0 +1,146 @@
+package app
+
+import (
+	"context"
+	"testing"
+
+	"github.com/thegodeveloper/data-gateway/internal/domain"
+)
+
+// rowsSource answers every query with its rows, keeping those that match
+// the query's "in" condition, if any, and records the keys it was asked for.
+type rowsSource struct {
+	rows []map[string]any
+	// maxKeys, when set, makes the source a domain.KeyBatcher.
+	maxKeys int
+	lookups [][]any
+}
+
+func (s *rowsSource) Query(_ context.Context, req domain.QueryRequest) (any, error) {
+	in := findIn(req.Query.Where)
+	if in == nil {
+		return s.rows, nil
+	}
+	keys := in.Value.([]any)
+	s.lookups = append(s.lookups, keys)
+	want := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		id, _ := domain.JoinKey(k)
+		want[id] = true
+	}
+	var out []map[string]any
+	for _, row := range s.rows {
+		if id, ok := domain.JoinKey(row[in.Field]); ok && want[id] {
+			out = append(out, row)
+		}
+	}
+	return out, nil
+}
+
+func findIn(c *domain.Condition) *domain.Condition {
+	if c == nil {
+		return nil
+	}
+	if c.Op == domain.OpIn {
+		return c
+	}
+	for i := range c.And {
+		if in := findIn(&c.And[i]); in != nil {
+			return in
+		}
+	}
+	return nil
+}
+
+// batchingSource is a rowsSource that takes at most maxKeys keys at once.
+type batchingSource struct{ *rowsSource }
+
+func (s batchingSource) MaxKeys() int { return s.maxKeys }
+
+func TestFederatedJoinMatchesNumbersAcrossTypes(t *testing.T) {
+	orders := &rowsSource{rows: []map[string]any{
+		{"id": "o1", "customer_id": int64(1000000)},
+		{"id": "o2", "customer_id": int32(7)},
+		{"id": "o3", "customer_id": int64(8)},
+	}}
+	// DynamoDB numbers decode as float64, whose default text is 1e+06.
+	customers := &rowsSource{rows: []map[string]any{
+		{"id": float64(1000000), "name": "big"},
+		{"id": float64(7), "name": "seven"},
+		{"id": 8.5, "name": "fraction"},
+	}}
+	svc := NewGatewayService(map[string]domain.DataSource{"orders": orders, "customers": customers}, nil)
+
+	res, err := svc.HandleFederated(context.Background(), domain.FederatedRequest{Stages: []domain.Stage{
+		{Name: "orders", Source: "orders", Query: domain.Query{Target: "orders"}},
+		{Name: "customer", Source: "customers", Query: domain.Query{Target: "customers"},
+			Join: &domain.Join{From: "customer_id", On: "id", As: "customer"}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]any{"o1": "big", "o2": "seven", "o3": nil}
+	for _, row := range res.Items {
+		var got any
+		if c, ok := row["customer"].(map[string]any); ok {
+			got = c["name"]
+		}
+		if got != want[row["id"].(string)] {
+			t.Errorf("order %v joined %v, want %v", row["id"], got, want[row["id"].(string)])
+		}
+	}
+}
+
+func TestJoinKey(t *testing.T) {
+	tests := []struct {
+		in   any
+		want string
+		ok   bool
+	}{
+		{int64(1000000), "1000000", true},
+		{float64(1000000), "1000000", true},
+		{float32(7), "7", true},
+		{1e21, "1000000000000000000000", true},
+		{2.5, "2.5", true},
+		{"1000000", "1000000", true},
+		{nil, "", false},
+		{map[string]any{"a": 1}, "", false},
+		{[]any{1}, "", false},
+	}
+	for _, tt := range tests {
+		got, ok := domain.JoinKey(tt.in)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("JoinKey(%#v) = %q, %v; want %q, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestFederatedJoinBatchesRespectSourceCap(t *testing.T) {
+	var orders []map[string]any
+	for i := range 5 {
+		orders = append(orders, map[string]any{"customer_id": i})
+	}
+	customers := batchingSource{&rowsSource{maxKeys: 2}}
+	svc := NewGatewayService(map[string]domain.DataSource{
+		"orders":    &rowsSource{rows: orders},
+		"customers": customers,
+	}, nil)
+
+	_, err := svc.HandleFederated(context.Background(), domain.FederatedRequest{Stages: []domain.Stage{
+		{Name: "orders", Source: "orders", Query: domain.Query{Target: "orders"}},
+		{Name: "customer", Source: "customers", Query: domain.Query{Target: "customers"},
+			Join: &domain.Join{From: "customer_id", On: "id", As: "customer", Batch: 500}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(customers.lookups) != 3 {
+		t.Fatalf("lookups = %v, want 3 batches", customers.lookups)
+	}
+	for _, keys := range customers.lookups {
+		if len(keys) > 2 {
+			t.Errorf("batch of %d keys, want at most 2", len(keys))
+		}
+	}
+}
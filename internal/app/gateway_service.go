@@ -4,36 +4,379 @@ package app
 
 import (
 	"context"
-	"errors"
+	"encoding/json"
 	"fmt"
+	"net/http"
+
 	"github.com/thegodeveloper/data-gateway/internal/domain"
+	"github.com/thegodeveloper/data-gateway/internal/route"
+	"go.opentelemetry.io/otel"
 )
 
 type GatewayService struct {
 	dataSources map[string]domain.DataSource
+	routes      *route.Table
+	pageTokens  *pageTokens
+	// writable are the sources open to explicit mutations and transactions.
+	writable map[string]bool
 }
 
-func NewGatewayService(dataSources map[string]domain.DataSource) *GatewayService {
+func NewGatewayService(dataSources map[string]domain.DataSource, routes *route.Table) *GatewayService {
 	return &GatewayService{
 		dataSources: dataSources,
+		routes:      routes,
+		pageTokens:  newPageTokens(),
+		writable:    make(map[string]bool),
 	}
 }
 
+// SetPageTokenKey sets the key page tokens are signed with. Instances behind
+// one load balancer need the same key to accept each other's tokens.
+func (s *GatewayService) SetPageTokenKey(key []byte) {
+	s.pageTokens = &pageTokens{key: key}
+}
+
+// AllowWrites opens a data source to HandleMutation and HandleTransaction.
+// Without it a source only takes the writes of routes, whose templates and
+// allow-lists the configuration controls.
+func (s *GatewayService) AllowWrites(source string) {
+	s.writable[source] = true
+}
+
 // HandleQuery processes the request and routes it to the correct data source.
 func (s *GatewayService) HandleQuery(ctx context.Context, req domain.QueryRequest) (any, error) {
+	ctx, span := otel.Tracer("data-gateway").Start(ctx, "GatewayService.HandleQuery")
+	defer span.End()
+
 	if req.Source == "" {
-		return nil, errors.New("missing 'source' field in request")
+		return nil, fmt.Errorf("%w: missing 'source' field in request", domain.ErrInvalidRequest)
 	}
 
 	ds, ok := s.dataSources[req.Source]
 	if !ok {
-		return nil, fmt.Errorf("data source '%s' not supported", req.Source)
+		return nil, fmt.Errorf("%w: '%s'", domain.ErrUnknownSource, req.Source)
+	}
+
+	if req.Query != nil {
+		if err := req.Query.Validate(); err != nil {
+			return nil, err
+		}
+		if req.Query.PageToken != "" {
+			cursor, err := s.pageTokens.verify(req, req.Query.PageToken)
+			if err != nil {
+				return nil, err
+			}
+			req.Query.Cursor = cursor
+		}
 	}
 
 	result, err := ds.Query(ctx, req)
 	if err != nil {
+		span.RecordError(err)
 		return nil, fmt.Errorf("query failed for '%s': %w", req.Source, err)
 	}
 
+	if page, ok := result.(*domain.Page); ok && page.Next != nil {
+		if page.NextToken, err = s.pageTokens.sign(req, page.Next); err != nil {
+			return nil, err
+		}
+	}
+
 	return result, nil
 }
+
+// HandleStream validates a query like HandleQuery but returns its rows as a
+// stream, for data sources that implement domain.Streamer. Paginated
+// queries are not streamed; their pages are already bounded.
+func (s *GatewayService) HandleStream(ctx context.Context, req domain.QueryRequest) (domain.RowStream, error) {
+	if req.Source == "" {
+		return nil, fmt.Errorf("%w: missing 'source' field in request", domain.ErrInvalidRequest)
+	}
+
+	ds, ok := s.dataSources[req.Source]
+	if !ok {
+		return nil, fmt.Errorf("%w: '%s'", domain.ErrUnknownSource, req.Source)
+	}
+	streamer, ok := ds.(domain.Streamer)
+	if !ok {
+		return nil, fmt.Errorf("%w: data source '%s' does not support streaming", domain.ErrInvalidRequest, req.Source)
+	}
+
+	if req.Query != nil {
+		if err := req.Query.Validate(); err != nil {
+			return nil, err
+		}
+		if req.Query.PageSize > 0 {
+			return nil, fmt.Errorf("%w: paginated queries cannot be streamed", domain.ErrInvalidRequest)
+		}
+	}
+
+	rows, err := streamer.Stream(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("query failed for '%s': %w", req.Source, err)
+	}
+	return rows, nil
+}
+
+// HandleWatch subscribes to the changes of a data source that implements
+// domain.Watcher.
+func (s *GatewayService) HandleWatch(ctx context.Context, req domain.WatchRequest) (domain.ChangeStream, error) {
+	if req.Source == "" {
+		return nil, fmt.Errorf("%w: missing 'source' field in request", domain.ErrInvalidRequest)
+	}
+
+	ds, ok := s.dataSources[req.Source]
+	if !ok {
+		return nil, fmt.Errorf("%w: '%s'", domain.ErrUnknownSource, req.Source)
+	}
+	watcher, ok := ds.(domain.Watcher)
+	if !ok {
+		return nil, fmt.Errorf("%w: data source '%s' does not support watching changes", domain.ErrInvalidRequest, req.Source)
+	}
+
+	changes, err := watcher.Watch(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("watch failed for '%s': %w", req.Source, err)
+	}
+	return changes, nil
+}
+
+// Describes reports whether a data source implements domain.Describer.
+func (s *GatewayService) Describes(source string) (bool, error) {
+	ds, ok := s.dataSources[source]
+	if !ok {
+		return false, fmt.Errorf("%w: '%s'", domain.ErrUnknownSource, source)
+	}
+	_, ok = ds.(domain.Describer)
+	return ok, nil
+}
+
+// HandleDescribe reports the fields of a table or collection of a data
+// source that implements domain.Describer.
+func (s *GatewayService) HandleDescribe(ctx context.Context, req domain.DescribeRequest) (*domain.TableSchema, error) {
+	if req.Source == "" {
+		return nil, fmt.Errorf("%w: missing 'source' field in request", domain.ErrInvalidRequest)
+	}
+
+	ds, ok := s.dataSources[req.Source]
+	if !ok {
+		return nil, fmt.Errorf("%w: '%s'", domain.ErrUnknownSource, req.Source)
+	}
+	describer, ok := ds.(domain.Describer)
+	if !ok {
+		return nil, fmt.Errorf("%w: data source '%s' cannot describe its tables", domain.ErrInvalidRequest, req.Source)
+	}
+
+	schema, err := describer.Describe(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("describe failed for '%s': %w", req.Source, err)
+	}
+	return schema, nil
+}
+
+// HandleMutation validates a write and runs it against a data source that
+// supports writes and was opened to them with AllowWrites.
+func (s *GatewayService) HandleMutation(ctx context.Context, req domain.MutationRequest) (*domain.MutationResult, error) {
+	if err := s.checkWritable(req.Source); err != nil {
+		return nil, err
+	}
+	return s.mutate(ctx, req)
+}
+
+// checkWritable refuses explicit writes to sources not opened with
+// AllowWrites. Unknown sources are left to the handlers to report.
+func (s *GatewayService) checkWritable(source string) error {
+	if _, ok := s.dataSources[source]; ok && !s.writable[source] {
+		return fmt.Errorf("%w: data source '%s' does not accept writes outside routes", domain.ErrForbidden, source)
+	}
+	return nil
+}
+
+// mutate runs a write without the AllowWrites check, for routes.
+func (s *GatewayService) mutate(ctx context.Context, req domain.MutationRequest) (*domain.MutationResult, error) {
+	ctx, span := otel.Tracer("data-gateway").Start(ctx, "GatewayService.HandleMutation")
+	defer span.End()
+
+	if req.Source == "" {
+		return nil, fmt.Errorf("%w: missing 'source' field in request", domain.ErrInvalidRequest)
+	}
+
+	ds, ok := s.dataSources[req.Source]
+	if !ok {
+		return nil, fmt.Errorf("%w: '%s'", domain.ErrUnknownSource, req.Source)
+	}
+	mutator, ok := ds.(domain.Mutator)
+	if !ok {
+		return nil, fmt.Errorf("%w: data source '%s' does not support writes", domain.ErrInvalidRequest, req.Source)
+	}
+
+	if err := req.Mutation.Validate(); err != nil {
+		return nil, err
+	}
+
+	result, err := mutator.Mutate(ctx, req)
+	if err != nil {
+		span.RecordError(err)
+		return nil, fmt.Errorf("%s failed for '%s': %w", req.Mutation.Op, req.Source, err)
+	}
+
+	return result, nil
+}
+
+// HandleTransaction validates every step and runs them atomically against a
+// data source that supports transactions and was opened to writes with
+// AllowWrites. A failure names the step through domain.StepError.
+func (s *GatewayService) HandleTransaction(ctx context.Context, req domain.TransactionRequest) (*domain.TransactionResult, error) {
+	ctx, span := otel.Tracer("data-gateway").Start(ctx, "GatewayService.HandleTransaction")
+	defer span.End()
+
+	if req.Source == "" {
+		return nil, fmt.Errorf("%w: missing 'source' field in request", domain.ErrInvalidRequest)
+	}
+	if err := s.checkWritable(req.Source); err != nil {
+		return nil, err
+	}
+
+	ds, ok := s.dataSources[req.Source]
+	if !ok {
+		return nil, fmt.Errorf("%w: '%s'", domain.ErrUnknownSource, req.Source)
+	}
+	transactor, ok := ds.(domain.Transactor)
+	if !ok {
+		return nil, fmt.Errorf("%w: data source '%s' does not support transactions", domain.ErrInvalidRequest, req.Source)
+	}
+
+	if len(req.Steps) == 0 {
+		return nil, fmt.Errorf("%w: transaction has no 'steps'", domain.ErrInvalidRequest)
+	}
+	for i := range req.Steps {
+		if err := req.Steps[i].Validate(); err != nil {
+			return nil, &domain.StepError{Step: i, Op: req.Steps[i].Op, Err: err}
+		}
+	}
+
+	result, err := transactor.Transact(ctx, req)
+	if err != nil {
+		span.RecordError(err)
+		return nil, fmt.Errorf("transaction failed for '%s': %w", req.Source, err)
+	}
+
+	return result, nil
+}
+
+// HandleRoute matches a business endpoint in the route table, fills the
+// route's query template from the request and runs it against the route's
+// data source.
+func (s *GatewayService) HandleRoute(ctx context.Context, req domain.RouteRequest) (any, error) {
+	query, mutation, err := s.resolveRoute(req)
+	if err != nil {
+		return nil, err
+	}
+	if mutation != nil {
+		return s.mutate(ctx, *mutation)
+	}
+	return s.HandleQuery(ctx, *query)
+}
+
+// StreamRoute is HandleRoute for streamed responses; only query routes can
+// be streamed.
+func (s *GatewayService) StreamRoute(ctx context.Context, req domain.RouteRequest) (domain.RowStream, error) {
+	query, mutation, err := s.resolveRoute(req)
+	if err != nil {
+		return nil, err
+	}
+	if mutation != nil {
+		return nil, fmt.Errorf("%w: write routes cannot be streamed", domain.ErrInvalidRequest)
+	}
+	return s.HandleStream(ctx, *query)
+}
+
+// resolveRoute matches the route and renders its templates into either a
+// query or a mutation request.
+func (s *GatewayService) resolveRoute(req domain.RouteRequest) (*domain.QueryRequest, *domain.MutationRequest, error) {
+	rt, pathVars, err := s.routes.Match(req.Method, req.Path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	vars := route.Vars{Path: pathVars, Query: req.Query, Body: req.Body}
+	params, err := route.Render(rt.Params, vars)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if rt.Mutation != nil {
+		rendered, err := route.Render(rt.Mutation, vars)
+		if err != nil {
+			return nil, nil, err
+		}
+		mutation, err := decodeMutation(rendered, req.Method)
+		if err != nil {
+			return nil, nil, err
+		}
+		return nil, &domain.MutationRequest{Source: rt.Source, Params: params, Mutation: *mutation, Access: rt.Access, Tables: rt.Tables}, nil
+	}
+
+	var query *domain.Query
+	if rt.Query != nil {
+		rendered, err := route.Render(rt.Query, vars)
+		if err != nil {
+			return nil, nil, err
+		}
+		if query, err = decodeQuery(rendered); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// Routes without a filter template take it from the body's "payload",
+	// as path-routed requests always have.
+	if _, ok := params["filter"]; !ok {
+		filter, _ := req.Body["payload"].(map[string]any)
+		if filter == nil {
+			filter = map[string]any{}
+		}
+		params["filter"] = filter
+	}
+
+	return &domain.QueryRequest{Source: rt.Source, Params: params, Query: query, Access: rt.Access, Tables: rt.Tables}, nil, nil
+}
+
+// decodeQuery turns a rendered query template into a domain.Query by way of
+// its JSON form, the same shape callers send to POST /query.
+func decodeQuery(rendered map[string]any) (*domain.Query, error) {
+	data, err := json.Marshal(rendered)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", domain.ErrInvalidRequest, err)
+	}
+	var query domain.Query
+	if err := json.Unmarshal(data, &query); err != nil {
+		return nil, fmt.Errorf("%w: route query template: %v", domain.ErrInvalidRequest, err)
+	}
+	return &query, nil
+}
+
+// methodOps is the op a mutation route performs when its template does not
+// name one.
+var methodOps = map[string]domain.MutationOp{
+	http.MethodPost:   domain.MutationInsert,
+	http.MethodPut:    domain.MutationUpsert,
+	http.MethodPatch:  domain.MutationUpdate,
+	http.MethodDelete: domain.MutationDelete,
+}
+
+// decodeMutation turns a rendered mutation template into a domain.Mutation,
+// the same shape callers send to POST /mutate.
+func decodeMutation(rendered map[string]any, method string) (*domain.Mutation, error) {
+	data, err := json.Marshal(rendered)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", domain.ErrInvalidRequest, err)
+	}
+	var mutation domain.Mutation
+	if err := json.Unmarshal(data, &mutation); err != nil {
+		return nil, fmt.Errorf("%w: route mutation template: %v", domain.ErrInvalidRequest, err)
+	}
+	if mutation.Op == "" {
+		mutation.Op = methodOps[method]
+	}
+	return &mutation, nil
+}
@@ -0,0 +1,90 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/thegodeveloper/data-gateway/internal/config"
+	"github.com/thegodeveloper/data-gateway/internal/domain"
+	"github.com/thegodeveloper/data-gateway/internal/route"
+)
+
+// writer accepts every mutation and transaction and counts them.
+type writer struct{ writes int }
+
+func (w *writer) Query(context.Context, domain.QueryRequest) (any, error) { return nil, nil }
+
+func (w *writer) Mutate(_ context.Context, req domain.MutationRequest) (*domain.MutationResult, error) {
+	w.writes++
+	return &domain.MutationResult{Op: req.Mutation.Op, Affected: 1}, nil
+}
+
+func (w *writer) Transact(_ context.Context, req domain.TransactionRequest) (*domain.TransactionResult, error) {
+	w.writes++
+	return &domain.TransactionResult{}, nil
+}
+
+func TestExplicitWritesNeedAllowWrites(t *testing.T) {
+	ctx := context.Background()
+	w := &writer{}
+	routes, err := route.NewTable([]config.Route{{
+		Method: "DELETE", Path: "/orders/{id}", Source: "orders",
+		Mutation: map[string]any{"target": "orders", "key": map[string]any{"id": "{path.id}"}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc := NewGatewayService(map[string]domain.DataSource{"orders": w}, routes)
+
+	mutation := domain.Mutation{Op: domain.MutationDelete, Target: "orders", All: true}
+	if _, err := svc.HandleMutation(ctx, domain.MutationRequest{Source: "orders", Mutation: mutation}); !errors.Is(err, domain.ErrForbidden) {
+		t.Fatalf("HandleMutation error = %v, want ErrForbidden", err)
+	}
+	if _, err := svc.HandleTransaction(ctx, domain.TransactionRequest{Source: "orders", Steps: []domain.Mutation{mutation}}); !errors.Is(err, domain.ErrForbidden) {
+		t.Fatalf("HandleTransaction error = %v, want ErrForbidden", err)
+	}
+	if w.writes != 0 {
+		t.Fatalf("%d writes reached the source", w.writes)
+	}
+
+	if _, err := svc.HandleRoute(ctx, domain.RouteRequest{Method: "DELETE", Path: "/orders/7"}); err != nil {
+		t.Fatalf("HandleRoute: %v", err)
+	}
+
+	svc.AllowWrites("orders")
+	if _, err := svc.HandleMutation(ctx, domain.MutationRequest{Source: "orders", Mutation: mutation}); err != nil {
+		t.Fatalf("HandleMutation: %v", err)
+	}
+	if _, err := svc.HandleTransaction(ctx, domain.TransactionRequest{Source: "orders", Steps: []domain.Mutation{mutation}}); err != nil {
+		t.Fatalf("HandleTransaction: %v", err)
+	}
+	if w.writes != 3 {
+		t.Fatalf("writes = %d, want 3", w.writes)
+	}
+}
+
+func TestRoutesPassTheirTableMapping(t *testing.T) {
+	routes, err := route.NewTable([]config.Route{{
+		Method: "GET", Path: "/archive/{customer}", Source: "ddb",
+		Params: map[string]any{"table": "invoices", "key": map[string]any{"customer_id": "{path.customer}"}},
+		Tables: map[string]string{"invoices": "invoices_archive"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got domain.QueryRequest
+	svc := NewGatewayService(map[string]domain.DataSource{
+		"ddb": sourceFunc(func(_ context.Context, req domain.QueryRequest) (any, error) {
+			got = req
+			return []map[string]any{}, nil
+		}),
+	}, routes)
+
+	if _, err := svc.HandleRoute(context.Background(), domain.RouteRequest{Method: "GET", Path: "/archive/c1"}); err != nil {
+		t.Fatal(err)
+	}
+	if got.Tables["invoices"] != "invoices_archive" {
+		t.Fatalf("source got tables %v", got.Tables)
+	}
+}
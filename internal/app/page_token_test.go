@@ -0,0 +1,74 @@
+package app
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/thegodeveloper/data-gateway/internal/domain"
+)
+
+func TestPageTokens(t *testing.T) {
+	tokens := &pageTokens{key: []byte("key")}
+	request := func(edit func(*domain.QueryRequest)) domain.QueryRequest {
+		req := domain.QueryRequest{
+			Source: "orders",
+			Query: &domain.Query{
+				Target:   "orders",
+				Where:    &domain.Condition{Field: "status", Op: domain.OpEq, Value: "open"},
+				Sort:     []domain.SortField{{Field: "id"}},
+				PageSize: 10,
+			},
+			Params: map[string]any{"index": "by_status"},
+			Tables: map[string]string{"orders": "orders-prod"},
+		}
+		if edit != nil {
+			edit(&req)
+		}
+		return req
+	}
+	token, err := tokens.sign(request(nil), []byte("cursor"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, _ := base64.RawURLEncoding.DecodeString(token)
+	raw[fingerprintSize] ^= 1
+	tampered := base64.RawURLEncoding.EncodeToString(raw)
+	resigned, err := (&pageTokens{key: []byte("other key")}).sign(request(nil), []byte("cursor"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		req   domain.QueryRequest
+		token string
+		err   string // "" for a token that must verify
+	}{
+		{"same query", request(nil), token, ""},
+		{"next page size", request(func(r *domain.QueryRequest) { r.Query.PageSize = 50 }), token, ""},
+		{"tampered cursor", request(nil), tampered, "invalid"},
+		{"truncated", request(nil), token[:10], "invalid"},
+		{"signed with another key", request(nil), resigned, "invalid"},
+		{"replayed on another source", request(func(r *domain.QueryRequest) { r.Source = "archive" }), token, "different query"},
+		{"replayed on another target", request(func(r *domain.QueryRequest) { r.Query.Target = "invoices" }), token, "different query"},
+		{"replayed with another filter", request(func(r *domain.QueryRequest) { r.Query.Where.Value = "closed" }), token, "different query"},
+		{"replayed in another order", request(func(r *domain.QueryRequest) { r.Query.Sort[0].Desc = true }), token, "different query"},
+		{"replayed with other params", request(func(r *domain.QueryRequest) { r.Params["index"] = "by_customer" }), token, "different query"},
+		{"replayed without params", request(func(r *domain.QueryRequest) { r.Params = nil }), token, "different query"},
+		{"replayed on another table mapping", request(func(r *domain.QueryRequest) { r.Tables["orders"] = "orders-staging" }), token, "different query"},
+	}
+	for _, tt := range tests {
+		cursor, err := tokens.verify(tt.req, tt.token)
+		if tt.err == "" {
+			if err != nil || string(cursor) != "cursor" {
+				t.Errorf("%s: verify = %q, %v; want the cursor", tt.name, cursor, err)
+			}
+			continue
+		}
+		if !errors.Is(err, domain.ErrInvalidRequest) || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: error = %v, want an invalid request (%s)", tt.name, err, tt.err)
+		}
+	}
+}
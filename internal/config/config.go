@@ -2,19 +2,223 @@
 // internal/config/config.go
 package config
 
-import "os"
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+const defaultConfigFile = "./config/config.yaml"
 
 type Config struct {
-	PostgresConnStr string
-	MongoURI        string
-	HTTPPort        string
+	HTTPPort string
+	// PageTokenKey signs pagination tokens. When empty each process uses a
+	// random key, so tokens do not survive restarts or cross instances.
+	PageTokenKey string
+	DataSources  map[string]DataSource
+	Routes       []Route
+	// GraphQL configures the /graphql endpoint; nil leaves it off.
+	GraphQL *GraphQL
 }
 
-func Load() *Config {
-	return &Config{
-		PostgresConnStr: os.Getenv("POSTGRES_CONN_STR"),
-		MongoURI:        getEnv("MONGO_URI", "mongodb://localhost:27017"),
-		HTTPPort:        getEnv("HTTP_PORT", "8080"),
+// DataSource declares one named data source instance. Type selects the
+// adapter factory ("postgres", "mongodb", "dynamodb", ...) and Settings are
+// handed to it undecoded, so each adapter owns its own settings schema.
+// Writes opens the source to the /mutate and /transaction endpoints, which
+// are not bound by any route's allow-list; routes with a mutation template
+// can write either way.
+type DataSource struct {
+	Type     string
+	Writes   bool
+	Settings map[string]any
+}
+
+// Route maps a business endpoint to a data source. Path may contain {name}
+// segments and a trailing {name...} catch-all; Params is the query template
+// sent to the source, where string values such as "{path.id}",
+// "{query.status?}" or "{body.total:float}" are replaced per request. Query,
+// when set, is a template for a backend-neutral domain.Query instead, and
+// Mutation a template for a domain.Mutation that makes the route a write.
+type Route struct {
+	Method string         `yaml:"method"`
+	Path   string         `yaml:"path"`
+	Source string         `yaml:"source"`
+	Params map[string]any `yaml:"params"`
+	Query  map[string]any `yaml:"query"`
+	// Mutation takes its op from the method when it does not set one:
+	// POST inserts, PUT upserts, PATCH updates and DELETE deletes.
+	Mutation map[string]any `yaml:"mutation"`
+	// Allow restricts the route to the listed tables (or collections) and,
+	// per table, to the listed columns; ["*"] exposes every column.
+	Allow map[string][]string `yaml:"allow"`
+	// Tables maps the table names the route uses to the source's own, for
+	// sources that rename tables (dynamodb). The source's table_prefix
+	// still applies.
+	Tables map[string]string `yaml:"tables"`
+}
+
+// GraphQL declares the types the /graphql endpoint serves and the
+// relationships between them, which may cross data sources.
+type GraphQL struct {
+	Types     []GraphQLType     `yaml:"types"`
+	Relations []GraphQLRelation `yaml:"relations"`
+	// MaxDepth bounds how deeply a query nests its fields (default 6).
+	MaxDepth int `yaml:"max_depth"`
+	// MaxRows bounds the rows one request reads across all its fields
+	// (default 10000).
+	MaxRows int `yaml:"max_rows"`
+}
+
+// GraphQLType exposes one table or collection as a GraphQL object type with
+// a root query field.
+type GraphQLType struct {
+	// Name is the GraphQL type name, e.g. "Customer".
+	Name string `yaml:"name"`
+	// Query is the root field listing the type; by default the name with a
+	// lowercase first letter.
+	Query  string         `yaml:"query"`
+	Source string         `yaml:"source"`
+	Target string         `yaml:"target"`
+	Params map[string]any `yaml:"params"`
+	// Fields declares field types (string, int, bigint, float, decimal,
+	// bool, time or json). They are added to, and override, the fields the
+	// source describes; sources that cannot describe need them.
+	Fields map[string]string `yaml:"fields"`
+	// Allow lists the fields exposed; empty exposes every field.
+	Allow []string `yaml:"allow"`
+	// Sample is how many documents a MongoDB collection is sampled for to
+	// infer its fields.
+	Sample int `yaml:"sample"`
+	// Limit caps the rows one field returns (default 100).
+	Limit int `yaml:"limit"`
+}
+
+// GraphQLRelation adds a field to a type that resolves to the rows of
+// another type whose On field equals the From field of the row.
+type GraphQLRelation struct {
+	Type  string `yaml:"type"`
+	Field string `yaml:"field"`
+	// Target is the type the field resolves to.
+	Target string `yaml:"target"`
+	From   string `yaml:"from"`
+	On     string `yaml:"on"`
+	// Many resolves to a list of every match instead of the first one.
+	Many bool `yaml:"many"`
+}
+
+// file is the layout of the YAML config file. It is decoded with yaml.v3
+// rather than viper because viper lowercases map keys, which would corrupt
+// Mongo field names and SQL bind names inside route templates.
+type file struct {
+	DataSources map[string]map[string]any `yaml:"datasources"`
+	Routes      []Route                   `yaml:"routes"`
+	GraphQL     *GraphQL                  `yaml:"graphql"`
+}
+
+// Load reads the data source instances and the route table from the YAML
+// file named by CONFIG_FILE (./config/config.yaml by default). The file is
+// optional unless CONFIG_FILE is set explicitly; without a datasources section
+// the gateway falls back to one postgres, mongodb and dynamodb instance
+// configured from the environment.
+func Load() (*Config, error) {
+	cfg := &Config{
+		HTTPPort:     getEnv("HTTP_PORT", "8080"),
+		PageTokenKey: os.Getenv("PAGE_TOKEN_KEY"),
+		DataSources:  defaultDataSources(),
+	}
+
+	path, explicit := os.LookupEnv("CONFIG_FILE")
+	if !explicit {
+		path = defaultConfigFile
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !explicit && errors.Is(err, os.ErrNotExist) {
+			return cfg, nil
+		}
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var f file
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse config file '%s': %w", path, err)
+	}
+
+	if f.DataSources != nil {
+		dataSources, err := parseDataSources(f.DataSources)
+		if err != nil {
+			return nil, err
+		}
+		cfg.DataSources = dataSources
+	}
+	cfg.Routes = f.Routes
+	cfg.GraphQL = f.GraphQL
+
+	return cfg, nil
+}
+
+func defaultDataSources() map[string]DataSource {
+	return map[string]DataSource{
+		"postgres": {Type: "postgres", Settings: map[string]any{
+			"conn_str": os.Getenv("POSTGRES_CONN_STR"),
+		}},
+		"mongodb": {Type: "mongodb", Settings: map[string]any{
+			"uri": getEnv("MONGO_URI", "mongodb://localhost:27017"),
+		}},
+		"dynamodb": {Type: "dynamodb", Settings: map[string]any{
+			"region":       getEnv("AWS_REGION", "us-east-1"),
+			"endpoint":     os.Getenv("DYNAMODB_ENDPOINT"),
+			"table_prefix": os.Getenv("DYNAMODB_TABLE_PREFIX"),
+		}},
+	}
+}
+
+// parseDataSources reads "name: {type: ..., writes: ..., <settings>}"
+// entries. String settings may reference environment variables as ${VAR} so
+// secrets stay out of the file.
+func parseDataSources(raw map[string]map[string]any) (map[string]DataSource, error) {
+	dataSources := make(map[string]DataSource, len(raw))
+	for name, v := range raw {
+		typ, ok := v["type"].(string)
+		if !ok || typ == "" {
+			return nil, fmt.Errorf("missing type for data source '%s'", name)
+		}
+		writes, ok := v["writes"].(bool)
+		if _, set := v["writes"]; set && !ok {
+			return nil, fmt.Errorf("data source '%s': writes must be true or false", name)
+		}
+		settings := make(map[string]any, len(v))
+		for key, value := range v {
+			if key != "type" && key != "writes" {
+				settings[key] = expandEnv(value)
+			}
+		}
+		dataSources[name] = DataSource{Type: typ, Writes: writes, Settings: settings}
+	}
+	return dataSources, nil
+}
+
+func expandEnv(value any) any {
+	switch v := value.(type) {
+	case string:
+		return os.ExpandEnv(v)
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for key, item := range v {
+			out[key] = expandEnv(item)
+		}
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, item := range v {
+			out[i] = expandEnv(item)
+		}
+		return out
+	default:
+		return value
 	}
 }
 
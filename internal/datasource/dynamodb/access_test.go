@@ -0,0 +1,48 @@
+package dynamodb
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/thegodeveloper/data-gateway/internal/domain"
+)
+
+func TestReadAccess(t *testing.T) {
+	access := &domain.Access{Tables: map[string][]string{"sessions": {"user_id", "expires_at"}}}
+	allowed := []domain.QueryRequest{
+		{Params: map[string]any{"table": "sessions", "key": map[string]any{"user_id": "u1"}}},
+		{Params: map[string]any{"table": "sessions", "filter": map[string]any{"expires_at": 1.0}}},
+		{Query: &domain.Query{Target: "sessions", Select: []string{"user_id"}, Where: &domain.Condition{Field: "user_id", Op: domain.OpEq, Value: "u1"}}},
+	}
+	for _, req := range allowed {
+		req.Access = access
+		columns, err := readAccess(req)
+		if err != nil {
+			t.Errorf("readAccess(%+v): %v", req, err)
+		} else if !columns["user_id"] || columns["token"] {
+			t.Errorf("readAccess columns = %v", columns)
+		}
+	}
+
+	refused := map[string]domain.QueryRequest{
+		"table":        {Params: map[string]any{"table": "secrets"}},
+		"key":          {Params: map[string]any{"table": "sessions", "key": map[string]any{"token": "t"}}},
+		"filter":       {Params: map[string]any{"table": "sessions", "filter": map[string]any{"token.raw": "t"}}},
+		"query table":  {Query: &domain.Query{Target: "secrets"}},
+		"query select": {Query: &domain.Query{Target: "sessions", Select: []string{"token"}}},
+		"query where":  {Query: &domain.Query{Target: "sessions", Where: &domain.Condition{Field: "token", Op: domain.OpExists, Value: true}}},
+		"query sort":   {Query: &domain.Query{Target: "sessions", Sort: []domain.SortField{{Field: "token"}}}},
+	}
+	for name, req := range refused {
+		req.Access = access
+		if _, err := readAccess(req); !errors.Is(err, domain.ErrInvalidRequest) {
+			t.Errorf("%s: error = %v, want ErrInvalidRequest", name, err)
+		}
+	}
+
+	row := map[string]any{"user_id": "u1", "token": "t"}
+	domain.Restrict(row, map[string]bool{"user_id": true})
+	if _, ok := row["token"]; ok || row["user_id"] != "u1" {
+		t.Errorf("Restrict left %v", row)
+	}
+}
@@ -0,0 +1,276 @@
+// Package dynamodb
+// internal/datasource/dynamodb/capacity.go
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	sdynamodb "github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/smithy-go"
+	"github.com/aws/smithy-go/middleware"
+	"github.com/thegodeveloper/data-gateway/internal/domain"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// Budget caps the capacity units per second the gateway spends on one
+// table. A zero rate leaves that kind of access unlimited.
+type Budget struct {
+	Read  float64 `mapstructure:"read"`
+	Write float64 `mapstructure:"write"`
+	// Burst is how many seconds of unused budget can be saved up (default 1).
+	Burst float64 `mapstructure:"burst"`
+}
+
+var errBudgetExhausted = errors.New("capacity budget exhausted")
+
+// bucket is a token bucket in capacity units. A call is admitted while the
+// bucket is positive and then charged what it actually consumed, which can
+// take the bucket below zero; later calls wait until it refills.
+type bucket struct {
+	mu     sync.Mutex
+	rate   float64
+	max    float64
+	tokens float64
+	last   time.Time
+}
+
+func newBucket(rate, burst float64) *bucket {
+	if rate <= 0 {
+		return nil
+	}
+	if burst <= 0 {
+		burst = 1
+	}
+	return &bucket{rate: rate, max: rate * burst, tokens: rate * burst, last: time.Now()}
+}
+
+func (b *bucket) refill() {
+	now := time.Now()
+	b.tokens = math.Min(b.max, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+}
+
+// wait returns how long until the bucket admits a call; zero if it does now.
+func (b *bucket) wait() time.Duration {
+	if b == nil {
+		return 0
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill()
+	if b.tokens > 0 {
+		return 0
+	}
+	return time.Duration((-b.tokens/b.rate)*float64(time.Second)) + time.Millisecond
+}
+
+func (b *bucket) take(units float64) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill()
+	b.tokens -= units
+}
+
+type tableBudget struct {
+	read, write *bucket
+}
+
+func (t tableBudget) bucket(write bool) *bucket {
+	if write {
+		return t.write
+	}
+	return t.read
+}
+
+// SetBudgets replaces the per-table capacity budgets. Calls that would
+// start while a table's budget is spent fail with a domain.ThrottleError
+// instead of reaching AWS.
+func (s *Source) SetBudgets(budgets map[string]Budget) {
+	tables := make(map[string]tableBudget, len(budgets))
+	for table, b := range budgets {
+		tables[table] = tableBudget{read: newBucket(b.Read, b.Burst), write: newBucket(b.Write, b.Burst)}
+	}
+	s.mu.Lock()
+	s.budgets = tables
+	s.mu.Unlock()
+}
+
+func (s *Source) budget(table string) tableBudget {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.budgets[table]
+}
+
+// capacityMetrics are the counters every DynamoDB call reports to.
+type capacityMetrics struct {
+	read, write metric.Float64Counter
+	throttled   metric.Int64Counter
+}
+
+func newCapacityMetrics() capacityMetrics {
+	meter := otel.Meter("data-gateway")
+	// Instruments only fail to build for invalid names, and come back as
+	// no-ops when they do.
+	read, _ := meter.Float64Counter("dynamodb.consumed_read_capacity",
+		metric.WithUnit("{capacity_unit}"),
+		metric.WithDescription("Read capacity units consumed by DynamoDB calls"))
+	write, _ := meter.Float64Counter("dynamodb.consumed_write_capacity",
+		metric.WithUnit("{capacity_unit}"),
+		metric.WithDescription("Write capacity units consumed by DynamoDB calls"))
+	throttled, _ := meter.Int64Counter("dynamodb.throttled_requests",
+		metric.WithUnit("{request}"),
+		metric.WithDescription("DynamoDB calls refused by a capacity budget or by AWS throttling"))
+	return capacityMetrics{read: read, write: write, throttled: throttled}
+}
+
+// instrument routes every call of the client through meterCapacity.
+func (s *Source) instrument(client *sdynamodb.Client) *sdynamodb.Client {
+	return sdynamodb.New(client.Options(), func(o *sdynamodb.Options) {
+		o.APIOptions = append(o.APIOptions[:len(o.APIOptions):len(o.APIOptions)], func(stack *middleware.Stack) error {
+			return stack.Initialize.Add(middleware.InitializeMiddlewareFunc("GatewayCapacity", s.meterCapacity), middleware.Before)
+		})
+	})
+}
+
+// meterCapacity wraps one operation, retries included. It checks the budgets
+// of the tables involved, asks for the consumed capacity, records it against
+// the request, the metrics and the budgets, and turns throttling that
+// outlasted the retries into a domain.ThrottleError.
+func (s *Source) meterCapacity(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (middleware.InitializeOutput, middleware.Metadata, error) {
+	tables, write, ok := capacityRequest(in.Parameters)
+	if !ok {
+		return next.HandleInitialize(ctx, in)
+	}
+	op := attribute.String("operation", middleware.GetOperationName(ctx))
+
+	for _, table := range tables {
+		if wait := s.budget(table).bucket(write).wait(); wait > 0 {
+			s.metrics.throttled.Add(ctx, 1, metric.WithAttributes(attribute.String("table", table), op, attribute.String("reason", "budget")))
+			return middleware.InitializeOutput{}, middleware.Metadata{}, &domain.ThrottleError{Target: table, RetryAfter: wait, Err: errBudgetExhausted}
+		}
+	}
+
+	out, md, err := next.HandleInitialize(ctx, in)
+
+	used := domain.CapacityFrom(ctx)
+	for _, c := range consumedCapacity(out.Result) {
+		table := aws.ToString(c.TableName)
+		units := aws.ToFloat64(c.CapacityUnits)
+		s.budget(table).bucket(write).take(units)
+		attrs := metric.WithAttributes(attribute.String("table", table), op)
+		if write {
+			s.metrics.write.Add(ctx, units, attrs)
+			if used != nil {
+				used.Add(0, units)
+			}
+		} else {
+			s.metrics.read.Add(ctx, units, attrs)
+			if used != nil {
+				used.Add(units, 0)
+			}
+		}
+	}
+
+	var apiErr smithy.APIError
+	if err != nil && errors.As(err, &apiErr) {
+		if _, throttled := retry.DefaultThrottleErrorCodes[apiErr.ErrorCode()]; throttled {
+			s.metrics.throttled.Add(ctx, 1, metric.WithAttributes(attribute.String("table", tables[0]), op, attribute.String("reason", "aws")))
+			err = &domain.ThrottleError{Target: tables[0], Err: err}
+		}
+	}
+	return out, md, err
+}
+
+// capacityRequest reports the tables an operation touches and whether it
+// writes, and asks DynamoDB to return the capacity it consumes. ok is false
+// for operations that do not consume capacity.
+func capacityRequest(params any) (tables []string, write, ok bool) {
+	total := types.ReturnConsumedCapacityTotal
+	switch in := params.(type) {
+	case *sdynamodb.GetItemInput:
+		in.ReturnConsumedCapacity = total
+		return []string{aws.ToString(in.TableName)}, false, true
+	case *sdynamodb.QueryInput:
+		in.ReturnConsumedCapacity = total
+		return []string{aws.ToString(in.TableName)}, false, true
+	case *sdynamodb.ScanInput:
+		in.ReturnConsumedCapacity = total
+		return []string{aws.ToString(in.TableName)}, false, true
+	case *sdynamodb.BatchGetItemInput:
+		in.ReturnConsumedCapacity = total
+		for table := range in.RequestItems {
+			tables = append(tables, table)
+		}
+		return tables, false, len(tables) > 0
+	case *sdynamodb.PutItemInput:
+		in.ReturnConsumedCapacity = total
+		return []string{aws.ToString(in.TableName)}, true, true
+	case *sdynamodb.UpdateItemInput:
+		in.ReturnConsumedCapacity = total
+		return []string{aws.ToString(in.TableName)}, true, true
+	case *sdynamodb.DeleteItemInput:
+		in.ReturnConsumedCapacity = total
+		return []string{aws.ToString(in.TableName)}, true, true
+	case *sdynamodb.TransactWriteItemsInput:
+		in.ReturnConsumedCapacity = total
+		seen := make(map[string]bool)
+		for _, item := range in.TransactItems {
+			var table *string
+			switch {
+			case item.Put != nil:
+				table = item.Put.TableName
+			case item.Update != nil:
+				table = item.Update.TableName
+			case item.Delete != nil:
+				table = item.Delete.TableName
+			case item.ConditionCheck != nil:
+				table = item.ConditionCheck.TableName
+			}
+			if name := aws.ToString(table); !seen[name] {
+				seen[name] = true
+				tables = append(tables, name)
+			}
+		}
+		return tables, true, len(tables) > 0
+	default:
+		return nil, false, false
+	}
+}
+
+func consumedCapacity(result any) []types.ConsumedCapacity {
+	var one *types.ConsumedCapacity
+	switch out := result.(type) {
+	case *sdynamodb.GetItemOutput:
+		one = out.ConsumedCapacity
+	case *sdynamodb.QueryOutput:
+		one = out.ConsumedCapacity
+	case *sdynamodb.ScanOutput:
+		one = out.ConsumedCapacity
+	case *sdynamodb.PutItemOutput:
+		one = out.ConsumedCapacity
+	case *sdynamodb.UpdateItemOutput:
+		one = out.ConsumedCapacity
+	case *sdynamodb.DeleteItemOutput:
+		one = out.ConsumedCapacity
+	case *sdynamodb.BatchGetItemOutput:
+		return out.ConsumedCapacity
+	case *sdynamodb.TransactWriteItemsOutput:
+		return out.ConsumedCapacity
+	}
+	if one == nil {
+		return nil
+	}
+	return []types.ConsumedCapacity{*one}
+}
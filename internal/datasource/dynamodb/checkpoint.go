@@ -0,0 +1,77 @@
+// Package dynamodb
+// internal/datasource/dynamodb/checkpoint.go
+package dynamodb
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	sdynamodb "github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// checkpointStore saves the sequence number a stream feed has read up to
+// in each shard, so a restarted gateway picks up where it stopped.
+type checkpointStore interface {
+	// load returns the checkpoints of a stream by shard ID.
+	load(ctx context.Context, stream string) (map[string]string, error)
+	// save stores checkpoints by shard ID; an empty one removes the shard's.
+	save(ctx context.Context, stream string, checkpoints map[string]string) error
+}
+
+// tableCheckpoints keeps checkpoints in a DynamoDB table whose partition
+// key is the string "stream", holding the stream ARN, and whose sort key is
+// the string "shard".
+type tableCheckpoints struct {
+	client *sdynamodb.Client
+	table  string
+}
+
+func (t *tableCheckpoints) load(ctx context.Context, stream string) (map[string]string, error) {
+	checkpoints := make(map[string]string)
+	var start map[string]types.AttributeValue
+	for {
+		out, err := t.client.Query(ctx, &sdynamodb.QueryInput{
+			TableName:                 aws.String(t.table),
+			KeyConditionExpression:    aws.String("#stream = :stream"),
+			ExpressionAttributeNames:  map[string]string{"#stream": "stream"},
+			ExpressionAttributeValues: map[string]types.AttributeValue{":stream": &types.AttributeValueMemberS{Value: stream}},
+			ConsistentRead:            aws.Bool(true),
+			ExclusiveStartKey:         start,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to load stream checkpoints from '%s': %w", t.table, err)
+		}
+		for _, item := range out.Items {
+			shard, _ := item["shard"].(*types.AttributeValueMemberS)
+			sequence, _ := item["sequence"].(*types.AttributeValueMemberS)
+			if shard != nil && sequence != nil {
+				checkpoints[shard.Value] = sequence.Value
+			}
+		}
+		if start = out.LastEvaluatedKey; len(start) == 0 {
+			return checkpoints, nil
+		}
+	}
+}
+
+func (t *tableCheckpoints) save(ctx context.Context, stream string, checkpoints map[string]string) error {
+	for shard, sequence := range checkpoints {
+		key := map[string]types.AttributeValue{
+			"stream": &types.AttributeValueMemberS{Value: stream},
+			"shard":  &types.AttributeValueMemberS{Value: shard},
+		}
+		var err error
+		if sequence == "" {
+			_, err = t.client.DeleteItem(ctx, &sdynamodb.DeleteItemInput{TableName: aws.String(t.table), Key: key})
+		} else {
+			key["sequence"] = &types.AttributeValueMemberS{Value: sequence}
+			_, err = t.client.PutItem(ctx, &sdynamodb.PutItemInput{TableName: aws.String(t.table), Item: key})
+		}
+		if err != nil {
+			return fmt.Errorf("failed to save the checkpoint of shard '%s' to '%s': %w", shard, t.table, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+// Package dynamodb
+// internal/datasource/dynamodb/client.go
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/ratelimit"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	sdynamodb "github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodbstreams"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+// defaultMaxAttempts is how often a call is tried, throttled retries
+// included, unless the instance configures max_attempts.
+const defaultMaxAttempts = 5
+
+// retryMaxBackoff caps the jittered delay between two attempts.
+const retryMaxBackoff = 5 * time.Second
+
+// ClientOptions configure how a DynamoDB client reaches AWS.
+type ClientOptions struct {
+	Region string `mapstructure:"region"`
+	// Endpoint overrides the service endpoint, e.g. http://localhost:8000
+	// for DynamoDB Local or http://localhost:4566 for LocalStack.
+	Endpoint string `mapstructure:"endpoint"`
+	// Credentials replace the default credential chain when set.
+	Credentials Credentials `mapstructure:"credentials"`
+	// RetryMode is "adaptive" (default) or "standard".
+	RetryMode string `mapstructure:"retry_mode"`
+	// MaxAttempts is how often a call is tried before its error, throttling
+	// included, is returned (default 5).
+	MaxAttempts int `mapstructure:"max_attempts"`
+}
+
+// Credentials are static keys, a role to assume, or both: the role is then
+// assumed with the static keys instead of the default chain.
+type Credentials struct {
+	AccessKeyID     string `mapstructure:"access_key_id"`
+	SecretAccessKey string `mapstructure:"secret_access_key"`
+	SessionToken    string `mapstructure:"session_token"`
+	RoleARN         string `mapstructure:"role_arn"`
+	ExternalID      string `mapstructure:"external_id"`
+	SessionName     string `mapstructure:"session_name"`
+}
+
+func (o ClientOptions) validate() error {
+	switch o.RetryMode {
+	case "", "adaptive", "standard":
+	default:
+		return fmt.Errorf("invalid 'retry_mode' setting '%s', want \"adaptive\" or \"standard\"", o.RetryMode)
+	}
+	c := o.Credentials
+	if (c.AccessKeyID == "") != (c.SecretAccessKey == "") {
+		return errors.New("'credentials' need both 'access_key_id' and 'secret_access_key'")
+	}
+	if c.RoleARN == "" && (c.ExternalID != "" || c.SessionName != "") {
+		return errors.New("'credentials.external_id' and 'credentials.session_name' require 'role_arn'")
+	}
+	return nil
+}
+
+// NewClient builds a DynamoDB client. Without credentials it uses the default
+// AWS credential chain. In adaptive retry mode throttled calls back off with
+// jitter and also slow down the client's later calls, so a hot table is not
+// hammered; standard mode only backs off.
+func NewClient(ctx context.Context, opts ClientOptions) (*sdynamodb.Client, error) {
+	cfg, err := loadConfig(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return sdynamodb.NewFromConfig(cfg, func(o *sdynamodb.Options) {
+		if opts.Endpoint != "" {
+			o.BaseEndpoint = aws.String(opts.Endpoint)
+		}
+	}), nil
+}
+
+// NewStreamsClient builds a DynamoDB Streams client with the same options as
+// NewClient. DynamoDB Local and LocalStack serve streams on the same
+// endpoint as tables.
+func NewStreamsClient(ctx context.Context, opts ClientOptions) (*dynamodbstreams.Client, error) {
+	cfg, err := loadConfig(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return dynamodbstreams.NewFromConfig(cfg, func(o *dynamodbstreams.Options) {
+		if opts.Endpoint != "" {
+			o.BaseEndpoint = aws.String(opts.Endpoint)
+		}
+	}), nil
+}
+
+func loadConfig(ctx context.Context, opts ClientOptions) (aws.Config, error) {
+	if err := opts.validate(); err != nil {
+		return aws.Config{}, err
+	}
+	maxAttempts := opts.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = defaultMaxAttempts
+	}
+	standard := func(so *retry.StandardOptions) {
+		so.MaxAttempts = maxAttempts
+		so.MaxBackoff = retryMaxBackoff
+		// Without a retry quota a burst of throttling keeps backing off
+		// instead of failing fast with a quota error that hides the
+		// throttling.
+		so.RateLimiter = ratelimit.None
+	}
+
+	loadOpts := []func(*config.LoadOptions) error{
+		config.WithRegion(opts.Region),
+		config.WithRetryer(func() aws.Retryer {
+			if opts.RetryMode == "standard" {
+				return retry.NewStandard(standard)
+			}
+			return retry.NewAdaptiveMode(func(o *retry.AdaptiveModeOptions) {
+				o.StandardOptions = append(o.StandardOptions, standard)
+			})
+		}),
+	}
+	if c := opts.Credentials; c.AccessKeyID != "" {
+		loadOpts = append(loadOpts, config.WithCredentialsProvider(
+			credentials.NewStaticCredentialsProvider(c.AccessKeyID, c.SecretAccessKey, c.SessionToken)))
+	}
+
+	cfg, err := config.LoadDefaultConfig(ctx, loadOpts...)
+	if err != nil {
+		return aws.Config{}, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+
+	if c := opts.Credentials; c.RoleARN != "" {
+		provider := stscreds.NewAssumeRoleProvider(sts.NewFromConfig(cfg), c.RoleARN, func(o *stscreds.AssumeRoleOptions) {
+			if c.ExternalID != "" {
+				o.ExternalID = aws.String(c.ExternalID)
+			}
+			if c.SessionName != "" {
+				o.RoleSessionName = c.SessionName
+			}
+		})
+		cfg.Credentials = aws.NewCredentialsCache(provider)
+	}
+	return cfg, nil
+}
@@ -0,0 +1,44 @@
+// Package dynamodb
+// internal/datasource/dynamodb/describe.go
+package dynamodb
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	sdynamodb "github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/thegodeveloper/data-gateway/internal/domain"
+)
+
+// attributeTypes maps key attribute types to the types of their decoded
+// values. Numbers decode as floats; binary values as base64 text.
+var attributeTypes = map[types.ScalarAttributeType]domain.FieldType{
+	types.ScalarAttributeTypeS: domain.FieldString,
+	types.ScalarAttributeTypeN: domain.FieldFloat,
+	types.ScalarAttributeTypeB: domain.FieldString,
+}
+
+// Describe reports the key attributes of a table. DynamoDB only declares
+// the attributes of its keys and indexes, so other attributes have to be
+// declared by the caller.
+func (s *Source) Describe(ctx context.Context, req domain.DescribeRequest) (*domain.TableSchema, error) {
+	table := s.physical(nil, req.Target)
+	out, err := s.client.DescribeTable(ctx, &sdynamodb.DescribeTableInput{TableName: aws.String(table)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe DynamoDB table '%s': %w", table, err)
+	}
+	keys := keysOf(out.Table.KeySchema)
+	schema := &domain.TableSchema{Key: []string{keys.partition}}
+	if keys.sort != "" {
+		schema.Key = append(schema.Key, keys.sort)
+	}
+	for _, def := range out.Table.AttributeDefinitions {
+		schema.Fields = append(schema.Fields, domain.Field{
+			Name: aws.ToString(def.AttributeName),
+			Type: attributeTypes[def.AttributeType],
+		})
+	}
+	return schema, nil
+}
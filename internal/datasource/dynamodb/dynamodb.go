@@ -4,8 +4,9 @@ package dynamodb
 
 import (
 	"context"
-	"errors"
 	"fmt"
+	"sync"
+
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	sdynamodb "github.com/aws/aws-sdk-go-v2/service/dynamodb"
@@ -14,32 +15,212 @@ import (
 )
 
 type Source struct {
-	client *sdynamodb.Client
+	client  *sdynamodb.Client
+	metrics capacityMetrics
+
+	mu      sync.RWMutex
+	schemas map[string]tableSchema
+	budgets map[string]tableBudget
+	names   tableNames
+	feeds   map[string]*streamFeed
 }
 
+// NewSource wraps client so that every call reports its consumed capacity
+// and respects the budgets set with SetBudgets.
 func NewSource(client *sdynamodb.Client) *Source {
-	return &Source{client: client}
+	s := &Source{metrics: newCapacityMetrics(), schemas: make(map[string]tableSchema)}
+	s.client = s.instrument(client)
+	return s
 }
 
+// MaxKeys implements domain.KeyBatcher: an "in" condition on the key runs
+// as one BatchGetItem call, which takes at most 100 keys.
+func (s *Source) MaxKeys() int {
+	return maxBatchGet
+}
+
+// Query runs a structured query, a key-condition Query when params.key is
+// given, otherwise a Scan of params.table filtered by the equality conditions
+// in params.filter. Unpaginated reads follow LastEvaluatedKey to the end.
+// Attributes outside the route's allow-list are dropped from the results.
 func (s *Source) Query(ctx context.Context, req domain.QueryRequest) (any, error) {
+	columns, err := readAccess(req)
+	if err != nil {
+		return nil, err
+	}
+	if req.Query != nil {
+		q := *req.Query
+		q.Target = s.physical(req.Tables, q.Target)
+		result, err := s.structured(ctx, q, req.Params)
+		if err != nil {
+			return nil, err
+		}
+		rows := result
+		if page, ok := result.(*domain.Page); ok {
+			rows = page.Items
+		}
+		for _, row := range rows.([]map[string]any) {
+			domain.Restrict(row, columns)
+		}
+		return result, nil
+	}
+
+	read, err := s.paramsReader(ctx, req.Params, req.Tables)
+	if err != nil {
+		return nil, err
+	}
+	var items []map[string]types.AttributeValue
+	for page, err := range sequential(read) {
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, page...)
+	}
+
+	rows, err := unmarshalItems(items)
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		domain.Restrict(row, columns)
+	}
+	return rows, nil
+}
+
+// readAccess checks the table and the attributes a read uses against the
+// route's allow-list and returns the columns its results are restricted to,
+// nil for all.
+func readAccess(req domain.QueryRequest) (map[string]bool, error) {
+	if err := checkTablesParam(req.Params); err != nil {
+		return nil, err
+	}
+	table, _ := req.Params["table"].(string)
+	if req.Query != nil {
+		table = req.Query.Target
+	}
+	columns, ok := req.Access.Table(table)
+	if !ok {
+		return nil, fmt.Errorf("%w: table '%s' is not exposed by this route", domain.ErrInvalidRequest, table)
+	}
+	if req.Query != nil {
+		return columns, domain.CheckQuery(req.Query, columns, table)
+	}
+	if columns != nil {
+		for _, param := range []string{"key", "filter"} {
+			conditions, _ := req.Params[param].(map[string]any)
+			for field := range conditions {
+				if !columns[domain.TopLevel(field)] {
+					return nil, fmt.Errorf("%w: unknown attribute '%s' on '%s'", domain.ErrInvalidRequest, field, table)
+				}
+			}
+		}
+	}
+	return columns, nil
+}
+
+// Stream runs the same reads as Query, fetching the next page only once the
+// previous one has been consumed. Stopping the iteration, or cancelling ctx,
+// stops paging.
+func (s *Source) Stream(ctx context.Context, req domain.QueryRequest) (domain.RowStream, error) {
+	columns, err := readAccess(req)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		pages        pageSeq
+		skip, remain int
+		limited      bool
+	)
+	if req.Query != nil {
+		q := *req.Query
+		q.Target = s.physical(req.Tables, q.Target)
+		p, keys, err := s.prepare(ctx, q, req.Params)
+		if err != nil {
+			return nil, err
+		}
+		pages = s.pages(ctx, q, p, keys)
+		skip, remain, limited = q.Offset, q.Limit, q.Limit > 0
+	} else {
+		read, err := s.paramsReader(ctx, req.Params, req.Tables)
+		if err != nil {
+			return nil, err
+		}
+		pages = sequential(read)
+	}
 
-	tableName, ok := req.Params["table"].(string)
+	return func(yield func(map[string]any, error) bool) {
+		for page, err := range pages {
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			for _, item := range page {
+				if skip > 0 {
+					skip--
+					continue
+				}
+				var record map[string]any
+				if err := attributevalue.UnmarshalMap(item, &record); err != nil {
+					yield(nil, fmt.Errorf("failed to unmarshal result: %w", err))
+					return
+				}
+				domain.Restrict(record, columns)
+				if !yield(record, nil) {
+					return
+				}
+				if remain--; limited && remain == 0 {
+					return
+				}
+			}
+		}
+	}, nil
+}
+
+// paramsReader builds the Query or Scan of a params request. The index and
+// consistent_read options apply to both; segments is only taken by
+// structured queries.
+func (s *Source) paramsReader(ctx context.Context, params map[string]any, tables map[string]string) (pageReader, error) {
+	tableName, ok := params["table"].(string)
 	if !ok || tableName == "" {
-		return nil, errors.New("missing or invalid 'table' parameter")
+		return nil, fmt.Errorf("%w: missing or invalid 'table' parameter", domain.ErrInvalidRequest)
 	}
+	tableName = s.physical(tables, tableName)
 
-	keyMap, ok := req.Params["key"].(map[string]interface{})
+	opts, err := parseReadOptions(params)
+	if err != nil {
+		return nil, err
+	}
+	if opts.segments > 1 {
+		return nil, fmt.Errorf("%w: 'segments' requires a structured query", domain.ErrInvalidRequest)
+	}
+	if opts.index != "" {
+		idx, err := s.indexSchema(ctx, tableName, opts.index)
+		if err != nil {
+			return nil, err
+		}
+		if idx.global && opts.consistent {
+			return nil, fmt.Errorf("%w: global secondary index '%s' does not support consistent reads", domain.ErrInvalidRequest, opts.index)
+		}
+	}
+
+	if _, ok := params["key"]; !ok {
+		filter, _ := params["filter"].(map[string]interface{})
+		return s.scan(ctx, tableName, filter, opts)
+	}
+
+	keyMap, ok := params["key"].(map[string]interface{})
 	if !ok || len(keyMap) == 0 {
-		return nil, errors.New("missing or invalid 'key' parameter")
+		return nil, fmt.Errorf("%w: missing or invalid 'key' parameter", domain.ErrInvalidRequest)
 	}
 
 	keyCondition := ""
+	exprAttrNames := make(map[string]string)
 	exprAttrValues := make(map[string]types.AttributeValue)
 	index := 0
 
 	for key, value := range keyMap {
-		placeholder := fmt.Sprintf(":v%d", index)
-		keyCondition += fmt.Sprintf("%s = %s", key, placeholder)
+		name, placeholder := fmt.Sprintf("#k%d", index), fmt.Sprintf(":v%d", index)
+		keyCondition += fmt.Sprintf("%s = %s", name, placeholder)
 		if index < len(keyMap)-1 {
 			keyCondition += " AND "
 		}
@@ -47,27 +228,80 @@ func (s *Source) Query(ctx context.Context, req domain.QueryRequest) (any, error
 		if err != nil {
 			return nil, fmt.Errorf("failed to marshal key value: %w", err)
 		}
+		exprAttrNames[name] = key
 		exprAttrValues[placeholder] = av
 		index++
 	}
 
-	out, err := s.client.Query(ctx, &sdynamodb.QueryInput{
-		TableName:                 aws.String(tableName),
-		KeyConditionExpression:    aws.String(keyCondition),
-		ExpressionAttributeValues: exprAttrValues,
-	})
-	if err != nil {
-		return nil, fmt.Errorf("dynamodb query failed: %w", err)
+	return func(startKey map[string]types.AttributeValue) ([]map[string]types.AttributeValue, map[string]types.AttributeValue, error) {
+		out, err := s.client.Query(ctx, &sdynamodb.QueryInput{
+			TableName:                 aws.String(tableName),
+			IndexName:                 optional(opts.index),
+			KeyConditionExpression:    aws.String(keyCondition),
+			ExpressionAttributeNames:  exprAttrNames,
+			ExpressionAttributeValues: exprAttrValues,
+			ConsistentRead:            consistent(opts.consistent),
+			ExclusiveStartKey:         startKey,
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("dynamodb query failed: %w", err)
+		}
+		return out.Items, out.LastEvaluatedKey, nil
+	}, nil
+}
+
+func (s *Source) scan(ctx context.Context, tableName string, filter map[string]interface{}, opts readOptions) (pageReader, error) {
+	input := sdynamodb.ScanInput{
+		TableName:      aws.String(tableName),
+		IndexName:      optional(opts.index),
+		ConsistentRead: consistent(opts.consistent),
+	}
+
+	if len(filter) > 0 {
+		filterExpr := ""
+		exprAttrNames := make(map[string]string)
+		exprAttrValues := make(map[string]types.AttributeValue)
+		index := 0
+
+		for key, value := range filter {
+			name, placeholder := fmt.Sprintf("#n%d", index), fmt.Sprintf(":v%d", index)
+			if index > 0 {
+				filterExpr += " AND "
+			}
+			filterExpr += fmt.Sprintf("%s = %s", name, placeholder)
+			av, err := attributevalue.Marshal(value)
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal attribute '%s': %w", key, err)
+			}
+			exprAttrNames[name] = key
+			exprAttrValues[placeholder] = av
+			index++
+		}
+
+		input.FilterExpression = aws.String(filterExpr)
+		input.ExpressionAttributeNames = exprAttrNames
+		input.ExpressionAttributeValues = exprAttrValues
 	}
 
+	return func(startKey map[string]types.AttributeValue) ([]map[string]types.AttributeValue, map[string]types.AttributeValue, error) {
+		page := input
+		page.ExclusiveStartKey = startKey
+		out, err := s.client.Scan(ctx, &page)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to scan DynamoDB table '%s': %w", tableName, err)
+		}
+		return out.Items, out.LastEvaluatedKey, nil
+	}, nil
+}
+
+func unmarshalItems(items []map[string]types.AttributeValue) ([]map[string]interface{}, error) {
 	var results []map[string]interface{}
-	for _, item := range out.Items {
+	for _, item := range items {
 		var record map[string]interface{}
 		if err := attributevalue.UnmarshalMap(item, &record); err != nil {
 			return nil, fmt.Errorf("failed to unmarshal result: %w", err)
 		}
 		results = append(results, record)
 	}
-
 	return results, nil
 }
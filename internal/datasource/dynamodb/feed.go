@@ -0,0 +1,519 @@
+// Package dynamodb
+// internal/datasource/dynamodb/feed.go
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodbstreams"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodbstreams/types"
+	"github.com/thegodeveloper/data-gateway/internal/domain"
+	"github.com/thegodeveloper/data-gateway/pkg/common"
+)
+
+// discoverEvery is how often the stream's shards are listed. Shards split
+// every few hours, and a closed shard triggers a listing right away.
+const discoverEvery = 10 * time.Second
+
+// readGap keeps a busy shard under the five GetRecords calls per second
+// DynamoDB Streams allows.
+const readGap = 250 * time.Millisecond
+
+// checkpointEvery is how often shard checkpoints that moved are saved.
+const checkpointEvery = 5 * time.Second
+
+// streamOps maps stream event names to change kinds.
+var streamOps = map[types.OperationType]domain.ChangeOp{
+	types.OperationTypeInsert: domain.ChangeInsert,
+	types.OperationTypeModify: domain.ChangeUpdate,
+	types.OperationTypeRemove: domain.ChangeDelete,
+}
+
+// shard is the read state of one stream shard. checkpoint is the sequence
+// number of the last record read from it, so an expired iterator, or the
+// next process when checkpoints are saved, resumes right after it. saved is
+// the checkpoint last saved.
+type shard struct {
+	id         string
+	parent     string
+	start      types.ShardIteratorType
+	iterator   string
+	checkpoint string
+	saved      string
+	finished   bool
+}
+
+// streamChange is a decoded change with its place in the feed.
+type streamChange struct {
+	index  uint64
+	change domain.Change
+}
+
+// streamFeed reads a table's stream and fans the changes out to every
+// subscriber. Records are kept in memory in the order they were read, so
+// subscribers can resume from any change still buffered; within an item,
+// that is the order the item was written in.
+type streamFeed struct {
+	client *dynamodbstreams.Client
+	table  string // logical name
+	arn    string
+	poll   time.Duration
+	size   int
+	// store saves the shard checkpoints; nil when they are not saved.
+	store checkpointStore
+	// epoch tells the IDs of this feed from those of an earlier process,
+	// whose buffer is gone.
+	epoch string
+	stop  context.CancelFunc
+	done  chan struct{}
+
+	// shards are only touched by the reading goroutine, once it runs.
+	shards     map[string]*shard
+	discovered time.Time
+	savedAt    time.Time
+
+	mu      sync.Mutex
+	changes []streamChange // oldest first
+	next    uint64
+	// floor is the newest index no longer buffered.
+	floor uint64
+	// wake is closed when changes are added.
+	wake chan struct{}
+}
+
+// newStreamFeed lists the stream's shards and positions every open one at
+// its latest record, or right after its saved checkpoint, then starts
+// reading. Changes made before the start are not reported unless a
+// checkpoint covers them.
+func newStreamFeed(ctx context.Context, client *dynamodbstreams.Client, table, arn string, cfg Streams, store checkpointStore) (*streamFeed, error) {
+	f := &streamFeed{
+		client: client,
+		table:  table,
+		arn:    arn,
+		poll:   cfg.PollInterval,
+		size:   cfg.Buffer,
+		store:  store,
+		epoch:  strconv.FormatInt(time.Now().UnixNano(), 36),
+		done:   make(chan struct{}),
+		shards: make(map[string]*shard),
+		next:   1,
+		wake:   make(chan struct{}),
+	}
+	if f.poll <= 0 {
+		f.poll = time.Second
+	}
+	if f.size <= 0 {
+		f.size = 10000
+	}
+	if err := f.open(ctx); err != nil {
+		return nil, err
+	}
+
+	runCtx, stop := context.WithCancel(context.Background())
+	f.stop = stop
+	go f.run(runCtx)
+	return f, nil
+}
+
+// open lists the shards, applies the saved checkpoints and positions the
+// shards to read.
+func (f *streamFeed) open(ctx context.Context) error {
+	if err := f.discover(ctx, true); err != nil {
+		return err
+	}
+	if err := f.resume(ctx); err != nil {
+		return err
+	}
+	f.savedAt = time.Now()
+	for _, sh := range f.shards {
+		if sh.finished {
+			continue
+		}
+		if err := f.position(ctx, sh); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// close stops reading the stream and saves the checkpoints. Subscribers
+// keep what was buffered.
+func (f *streamFeed) close() {
+	f.stop()
+	<-f.done
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	f.checkpoint(ctx)
+}
+
+func (f *streamFeed) run(ctx context.Context) {
+	defer close(f.done)
+	for {
+		if time.Since(f.discovered) >= discoverEvery {
+			if err := f.discover(ctx, false); err != nil && ctx.Err() == nil {
+				common.Error("listing the shards of the '%s' stream: %v", f.table, err)
+			}
+		}
+		n := f.read(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if time.Since(f.savedAt) >= checkpointEvery {
+			f.checkpoint(ctx)
+			f.savedAt = time.Now()
+		}
+		wait := f.poll
+		if n > 0 {
+			wait = readGap
+		}
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// discover lists the stream's shards. At startup only open shards are
+// read, from their latest record; shards found later are children of split
+// or closed shards and are read from their first record. Shards no longer
+// listed have been trimmed.
+func (f *streamFeed) discover(ctx context.Context, initial bool) error {
+	listed := make(map[string]*shard, len(f.shards))
+	var start *string
+	for {
+		out, err := f.client.DescribeStream(ctx, &dynamodbstreams.DescribeStreamInput{
+			StreamArn:             aws.String(f.arn),
+			ExclusiveStartShardId: start,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to describe stream of table '%s': %w", f.table, err)
+		}
+		for _, s := range out.StreamDescription.Shards {
+			id := aws.ToString(s.ShardId)
+			if sh, ok := f.shards[id]; ok {
+				listed[id] = sh
+				continue
+			}
+			sh := &shard{id: id, parent: aws.ToString(s.ParentShardId), start: types.ShardIteratorTypeTrimHorizon}
+			if initial {
+				sh.start = types.ShardIteratorTypeLatest
+				sh.finished = s.SequenceNumberRange != nil && s.SequenceNumberRange.EndingSequenceNumber != nil
+			}
+			listed[id] = sh
+		}
+		if start = out.StreamDescription.LastEvaluatedShardId; start == nil {
+			break
+		}
+	}
+	f.shards = listed
+	f.discovered = time.Now()
+	return nil
+}
+
+// resume applies the saved checkpoints after the initial discover. Shards
+// with one are read from right after it, and their descendants from their
+// first record; the others start as they would without checkpoints.
+func (f *streamFeed) resume(ctx context.Context) error {
+	if f.store == nil {
+		return nil
+	}
+	saved, err := f.store.load(ctx, f.arn)
+	if err != nil {
+		return err
+	}
+	resumed := make(map[string]bool)
+	for _, id := range f.shardIDs() {
+		sh := f.shards[id]
+		switch {
+		case saved[id] != "":
+			sh.checkpoint, sh.saved = saved[id], saved[id]
+		case resumed[sh.parent]:
+			sh.start = types.ShardIteratorTypeTrimHorizon
+		default:
+			continue
+		}
+		sh.finished = false
+		resumed[id] = true
+	}
+	return nil
+}
+
+// checkpoint saves the checkpoints that moved since they were last saved.
+// A failure is logged and retried on the next call.
+func (f *streamFeed) checkpoint(ctx context.Context) {
+	if f.store == nil {
+		return
+	}
+	moved := make(map[string]string)
+	for id, sh := range f.shards {
+		if sh.checkpoint != sh.saved {
+			moved[id] = sh.checkpoint
+		}
+	}
+	if len(moved) == 0 {
+		return
+	}
+	if err := f.store.save(ctx, f.arn, moved); err != nil {
+		common.Error("saving the checkpoints of the '%s' stream: %v", f.table, err)
+		return
+	}
+	for id, seq := range moved {
+		f.shards[id].saved = seq
+	}
+}
+
+// shardIDs returns the IDs of the known shards in order. Shard IDs grow
+// with their creation time, so parents come first.
+func (f *streamFeed) shardIDs() []string {
+	ids := make([]string, 0, len(f.shards))
+	for id := range f.shards {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// ready reports whether sh can be read: records of a split shard come
+// after those of its parent, so the parent is read to its end first.
+func (f *streamFeed) ready(sh *shard) bool {
+	if sh.finished {
+		return false
+	}
+	parent, ok := f.shards[sh.parent]
+	return !ok || parent.finished
+}
+
+// position gets an iterator for sh, right after its checkpoint if it has
+// one.
+func (f *streamFeed) position(ctx context.Context, sh *shard) error {
+	in := &dynamodbstreams.GetShardIteratorInput{
+		StreamArn:         aws.String(f.arn),
+		ShardId:           aws.String(sh.id),
+		ShardIteratorType: sh.start,
+	}
+	if sh.checkpoint != "" {
+		in.ShardIteratorType = types.ShardIteratorTypeAfterSequenceNumber
+		in.SequenceNumber = aws.String(sh.checkpoint)
+	}
+	out, err := f.client.GetShardIterator(ctx, in)
+	if err != nil {
+		return fmt.Errorf("failed to position on shard '%s' of table '%s': %w", sh.id, f.table, err)
+	}
+	sh.iterator = aws.ToString(out.ShardIterator)
+	return nil
+}
+
+// read fetches the next records of every readable shard and buffers them.
+// It returns how many records it read.
+func (f *streamFeed) read(ctx context.Context) int {
+	var n int
+	for _, id := range f.shardIDs() {
+		sh := f.shards[id]
+		if !f.ready(sh) {
+			continue
+		}
+		records, err := f.readShard(ctx, sh)
+		if ctx.Err() != nil {
+			return n
+		}
+		if err != nil {
+			common.Error("reading the '%s' stream: %v", f.table, err)
+		}
+		n += len(records)
+		f.add(records)
+	}
+	return n
+}
+
+// readShard makes one GetRecords call on sh and advances its checkpoint.
+// An expired iterator is replaced from the checkpoint; a shard with no next
+// iterator is closed, and its children are listed.
+func (f *streamFeed) readShard(ctx context.Context, sh *shard) ([]domain.Change, error) {
+	if sh.iterator == "" {
+		if err := f.position(ctx, sh); err != nil {
+			return nil, err
+		}
+	}
+	out, err := f.client.GetRecords(ctx, &dynamodbstreams.GetRecordsInput{ShardIterator: aws.String(sh.iterator)})
+	var (
+		expired  *types.ExpiredIteratorException
+		trimmed  *types.TrimmedDataAccessException
+		notFound *types.ResourceNotFoundException
+	)
+	switch {
+	case errors.As(err, &expired):
+		sh.iterator = ""
+		return nil, nil
+	case errors.As(err, &trimmed):
+		// The checkpoint fell out of the 24 hour retention window. Rather
+		// than replay what is left of the shard under new IDs, skip to its
+		// latest record and make subscribers reload.
+		sh.iterator, sh.checkpoint, sh.start = "", "", types.ShardIteratorTypeLatest
+		f.gap()
+		return nil, fmt.Errorf("records of shard '%s' expired before they were read; changes were missed", sh.id)
+	case errors.As(err, &notFound):
+		sh.finished = true
+		return nil, nil
+	case err != nil:
+		return nil, fmt.Errorf("failed to read shard '%s': %w", sh.id, err)
+	}
+
+	changes := make([]domain.Change, 0, len(out.Records))
+	for _, record := range out.Records {
+		if record.Dynamodb != nil {
+			sh.checkpoint = aws.ToString(record.Dynamodb.SequenceNumber)
+		}
+		// A record that cannot be decoded is skipped rather than stalling
+		// the shard on it.
+		change, ok, err := f.decode(record)
+		if err != nil {
+			common.Error("reading the '%s' stream: %v", f.table, err)
+			continue
+		}
+		if ok {
+			changes = append(changes, change)
+		}
+	}
+	sh.iterator = aws.ToString(out.NextShardIterator)
+	if out.NextShardIterator == nil {
+		sh.finished = true
+		f.discovered = time.Time{}
+	}
+	return changes, nil
+}
+
+// decode converts a stream record. Images are only present when the
+// stream's view type includes them.
+func (f *streamFeed) decode(record types.Record) (domain.Change, bool, error) {
+	op, ok := streamOps[record.EventName]
+	if !ok || record.Dynamodb == nil {
+		return domain.Change{}, false, nil
+	}
+	r := record.Dynamodb
+	change := domain.Change{Op: op, Target: f.table}
+	if r.ApproximateCreationDateTime != nil {
+		change.Time = r.ApproximateCreationDateTime.UTC()
+	}
+	for _, image := range []struct {
+		from map[string]types.AttributeValue
+		to   *map[string]any
+	}{{r.Keys, &change.Key}, {r.NewImage, &change.Document}, {r.OldImage, &change.Before}} {
+		if image.from == nil {
+			continue
+		}
+		item, err := attributevalue.FromDynamoDBStreamsMap(image.from)
+		if err != nil {
+			return domain.Change{}, false, fmt.Errorf("invalid stream record %s: %w", aws.ToString(record.EventID), err)
+		}
+		if err := attributevalue.UnmarshalMap(item, image.to); err != nil {
+			return domain.Change{}, false, fmt.Errorf("invalid stream record %s: %w", aws.ToString(record.EventID), err)
+		}
+	}
+	return change, true, nil
+}
+
+// add buffers changes, drops the oldest beyond the buffer size and wakes
+// the subscribers.
+func (f *streamFeed) add(changes []domain.Change) {
+	if len(changes) == 0 {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, change := range changes {
+		change.ID = f.id(f.next)
+		f.changes = append(f.changes, streamChange{index: f.next, change: change})
+		f.next++
+	}
+	if drop := len(f.changes) - f.size; drop > 0 {
+		f.floor = f.changes[drop-1].index
+		f.changes = append([]streamChange(nil), f.changes[drop:]...)
+	}
+	close(f.wake)
+	f.wake = make(chan struct{})
+}
+
+// gap records that changes were missed. It takes an index of its own and
+// drops the buffer, so that subscribers at any earlier position fail as if
+// their changes had been dropped.
+func (f *streamFeed) gap() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.floor = f.next
+	f.next++
+	f.changes = nil
+	close(f.wake)
+	f.wake = make(chan struct{})
+}
+
+// id renders an index as "epoch:index".
+func (f *streamFeed) id(index uint64) string {
+	return f.epoch + ":" + strconv.FormatUint(index, 10)
+}
+
+// parseID reads back an ID of this feed.
+func (f *streamFeed) parseID(s string) (uint64, error) {
+	epoch, indexText, ok := strings.Cut(s, ":")
+	index, err := strconv.ParseUint(indexText, 10, 64)
+	if !ok || err != nil {
+		return 0, fmt.Errorf("%w: invalid resume position '%s'", domain.ErrInvalidRequest, s)
+	}
+	if epoch != f.epoch {
+		return 0, fmt.Errorf("%w: resume position '%s' is from before the gateway restarted; reload and watch from now", domain.ErrInvalidRequest, s)
+	}
+	return index, nil
+}
+
+// latest is the index of the newest buffered change.
+func (f *streamFeed) latest() uint64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.next - 1
+}
+
+// since returns the buffered changes after index and a channel closed when
+// more arrive. ok is false when changes after index were already dropped.
+func (f *streamFeed) since(index uint64) (changes []streamChange, wake <-chan struct{}, ok bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if index < f.floor {
+		return nil, nil, false
+	}
+	i := sort.Search(len(f.changes), func(i int) bool { return f.changes[i].index > index })
+	return append([]streamChange(nil), f.changes[i:]...), f.wake, true
+}
+
+// subscribe streams the changes after index, each limited to columns (nil
+// meaning all).
+func (f *streamFeed) subscribe(ctx context.Context, index uint64, columns map[string]bool) domain.ChangeStream {
+	return func(yield func(domain.Change, error) bool) {
+		for {
+			changes, wake, ok := f.since(index)
+			if !ok {
+				yield(domain.Change{}, fmt.Errorf("change feed moved past position %s; changes were dropped", f.id(index)))
+				return
+			}
+			for _, sc := range changes {
+				index = sc.index
+				if !yield(sc.change.Expose(columns), nil) {
+					return
+				}
+			}
+			select {
+			case <-wake:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
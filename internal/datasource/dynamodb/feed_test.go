@@ -0,0 +1,283 @@
+package dynamodb
+
+import (
+	"context"
+	"maps"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodbstreams"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodbstreams/types"
+	"github.com/thegodeveloper/data-gateway/internal/domain"
+)
+
+// memoryCheckpoints is a checkpointStore in a map.
+type memoryCheckpoints map[string]string
+
+func (m memoryCheckpoints) load(context.Context, string) (map[string]string, error) {
+	return maps.Clone(m), nil
+}
+
+func (m memoryCheckpoints) save(_ context.Context, _ string, checkpoints map[string]string) error {
+	for shard, sequence := range checkpoints {
+		m[shard] = sequence
+	}
+	return nil
+}
+
+// fakeStream serves a stream's shards: their listing, and for each
+// iterator the records it returns and the iterator after it ("" for none).
+type fakeStream struct {
+	shards    []any
+	records   map[string][]any
+	next      map[string]string
+	trimmed   map[string]bool
+	positions []map[string]any
+}
+
+func (s *fakeStream) client() *dynamodbstreams.Client {
+	return dynamodbstreams.New(dynamodbstreams.Options{
+		Region:       "us-east-1",
+		BaseEndpoint: aws.String("http://streams.test"),
+		Credentials:  aws.AnonymousCredentials{},
+		HTTPClient: fakeDynamo(func(op string, body map[string]any) any {
+			switch op {
+			case "DescribeStream":
+				return map[string]any{"StreamDescription": map[string]any{"Shards": s.shards}}
+			case "GetShardIterator":
+				s.positions = append(s.positions, body)
+				return map[string]any{"ShardIterator": body["ShardId"].(string) + "/0"}
+			}
+			it := body["ShardIterator"].(string)
+			if s.trimmed[it] {
+				return apiError("TrimmedDataAccessException")
+			}
+			out := map[string]any{"Records": s.records[it]}
+			if next := s.next[it]; next != "" {
+				out["NextShardIterator"] = next
+			}
+			return out
+		}),
+		Retryer: aws.NopRetryer{},
+	})
+}
+
+func testFeed(stream *fakeStream, store checkpointStore) *streamFeed {
+	return &streamFeed{
+		client: stream.client(),
+		table:  "orders",
+		arn:    "arn:aws:dynamodb:us-east-1:1:table/orders/stream/1",
+		size:   100,
+		store:  store,
+		epoch:  "e",
+		shards: make(map[string]*shard),
+		next:   1,
+		wake:   make(chan struct{}),
+	}
+}
+
+func insert(id, sequence string) map[string]any {
+	return map[string]any{
+		"eventID":   sequence,
+		"eventName": "INSERT",
+		"dynamodb": map[string]any{
+			"Keys":           map[string]any{"id": map[string]any{"S": id}},
+			"NewImage":       map[string]any{"id": map[string]any{"S": id}},
+			"SequenceNumber": sequence,
+		},
+	}
+}
+
+func bufferedKeys(f *streamFeed) []any {
+	changes, _, _ := f.since(0)
+	var keys []any
+	for _, sc := range changes {
+		keys = append(keys, sc.change.Key["id"])
+	}
+	return keys
+}
+
+func TestStreamFeedReadsParentsBeforeChildren(t *testing.T) {
+	ctx := context.Background()
+	// The child's ID sorts first, so only the parent check holds it back.
+	stream := &fakeStream{
+		shards: []any{
+			map[string]any{"ShardId": "shard-b"},
+			map[string]any{"ShardId": "shard-a", "ParentShardId": "shard-b"},
+		},
+		records: map[string][]any{
+			"shard-b/0": {insert("p1", "1")},
+			"shard-b/1": {insert("p2", "2")},
+			"shard-a/0": {insert("c1", "3")},
+		},
+		next: map[string]string{"shard-b/0": "shard-b/1", "shard-a/0": "shard-a/1"},
+	}
+	f := testFeed(stream, nil)
+	if err := f.open(ctx); err != nil {
+		t.Fatal(err)
+	}
+	for range 3 {
+		f.read(ctx)
+	}
+
+	if got, want := bufferedKeys(f), []any{"p1", "p2", "c1"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("changes = %v, want %v", got, want)
+	}
+	if !f.shards["shard-b"].finished || f.shards["shard-a"].checkpoint != "3" {
+		t.Fatalf("shards = %+v, %+v", *f.shards["shard-b"], *f.shards["shard-a"])
+	}
+}
+
+func TestStreamFeedResumesFromCheckpoints(t *testing.T) {
+	ctx := context.Background()
+	closed := map[string]any{"StartingSequenceNumber": "100", "EndingSequenceNumber": "200"}
+	stream := &fakeStream{
+		shards: []any{
+			map[string]any{"ShardId": "shard-1", "SequenceNumberRange": closed},
+			map[string]any{"ShardId": "shard-2", "ParentShardId": "shard-1"},
+			map[string]any{"ShardId": "shard-3"},
+			map[string]any{"ShardId": "shard-4", "SequenceNumberRange": closed},
+		},
+		records: map[string][]any{"shard-1/0": {insert("a", "160")}},
+		next:    map[string]string{"shard-2/0": "shard-2/0", "shard-3/0": "shard-3/0"},
+	}
+	store := memoryCheckpoints{"shard-1": "150", "gone": "1"}
+	f := testFeed(stream, store)
+	if err := f.open(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	starts := make(map[string]string)
+	for _, p := range stream.positions {
+		starts[p["ShardId"].(string)] = p["ShardIteratorType"].(string)
+		if p["ShardId"] == "shard-1" && p["SequenceNumber"] != "150" {
+			t.Errorf("shard-1 resumed after %v, want 150", p["SequenceNumber"])
+		}
+	}
+	want := map[string]string{
+		"shard-1": string(types.ShardIteratorTypeAfterSequenceNumber),
+		"shard-2": string(types.ShardIteratorTypeTrimHorizon),
+		"shard-3": string(types.ShardIteratorTypeLatest),
+	}
+	if !reflect.DeepEqual(starts, want) {
+		t.Fatalf("positions = %v, want %v", starts, want)
+	}
+
+	f.read(ctx)
+	f.checkpoint(ctx)
+	if store["shard-1"] != "160" {
+		t.Fatalf("saved checkpoint = %q, want 160", store["shard-1"])
+	}
+}
+
+func TestStreamFeedTrimmedShardReportsGap(t *testing.T) {
+	ctx := context.Background()
+	stream := &fakeStream{
+		shards:  []any{map[string]any{"ShardId": "shard-1"}},
+		records: map[string][]any{"shard-1/0": {insert("a", "1")}},
+		next:    map[string]string{"shard-1/0": "shard-1/1"},
+		trimmed: map[string]bool{"shard-1/1": true},
+	}
+	f := testFeed(stream, nil)
+	if err := f.open(ctx); err != nil {
+		t.Fatal(err)
+	}
+	f.read(ctx)
+	caughtUp := f.latest()
+	f.read(ctx)
+
+	if _, _, ok := f.since(caughtUp); ok {
+		t.Fatal("a subscriber from before the gap can still resume")
+	}
+	if _, _, ok := f.since(f.latest()); !ok {
+		t.Fatal("a subscriber from after the gap cannot resume")
+	}
+	sh := f.shards["shard-1"]
+	if sh.start != types.ShardIteratorTypeLatest || sh.checkpoint != "" {
+		t.Fatalf("trimmed shard restarts from %s after %q, want LATEST", sh.start, sh.checkpoint)
+	}
+}
+
+func TestStreamFeedDecode(t *testing.T) {
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	keys := map[string]types.AttributeValue{"id": &types.AttributeValueMemberS{Value: "o1"}}
+	image := map[string]types.AttributeValue{
+		"id":    &types.AttributeValueMemberS{Value: "o1"},
+		"total": &types.AttributeValueMemberN{Value: "12.5"},
+		"tags":  &types.AttributeValueMemberSS{Value: []string{"new"}},
+	}
+	decoded := map[string]any{"id": "o1", "total": 12.5, "tags": []string{"new"}}
+
+	tests := []struct {
+		name   string
+		record types.Record
+		want   domain.Change
+		ok     bool
+	}{
+		{"insert", types.Record{EventName: types.OperationTypeInsert, Dynamodb: &types.StreamRecord{
+			Keys: keys, NewImage: image, ApproximateCreationDateTime: &at,
+		}}, domain.Change{Op: domain.ChangeInsert, Target: "orders", Time: at, Key: map[string]any{"id": "o1"}, Document: decoded}, true},
+		{"modify", types.Record{EventName: types.OperationTypeModify, Dynamodb: &types.StreamRecord{
+			Keys: keys, NewImage: image, OldImage: image,
+		}}, domain.Change{Op: domain.ChangeUpdate, Target: "orders", Key: map[string]any{"id": "o1"}, Document: decoded, Before: decoded}, true},
+		{"remove", types.Record{EventName: types.OperationTypeRemove, Dynamodb: &types.StreamRecord{
+			Keys: keys,
+		}}, domain.Change{Op: domain.ChangeDelete, Target: "orders", Key: map[string]any{"id": "o1"}}, true},
+		{"unknown event", types.Record{EventName: "TTL", Dynamodb: &types.StreamRecord{Keys: keys}}, domain.Change{}, false},
+		{"no stream record", types.Record{EventName: types.OperationTypeInsert}, domain.Change{}, false},
+	}
+	f := &streamFeed{table: "orders"}
+	for _, tt := range tests {
+		got, ok, err := f.decode(tt.record)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if ok != tt.ok || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: decode = %#v, %v; want %#v, %v", tt.name, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestTableCheckpoints(t *testing.T) {
+	ctx := context.Background()
+	saved := make(map[string]string)
+	s := newFakeSource(func(op string, body map[string]any) any {
+		switch op {
+		case "PutItem":
+			item := body["Item"].(map[string]any)
+			shard := item["shard"].(map[string]any)["S"].(string)
+			saved[shard] = item["sequence"].(map[string]any)["S"].(string)
+		case "DeleteItem":
+			delete(saved, body["Key"].(map[string]any)["shard"].(map[string]any)["S"].(string))
+		case "Query":
+			var items []any
+			for shard, sequence := range saved {
+				items = append(items, map[string]any{
+					"stream":   map[string]any{"S": "arn"},
+					"shard":    map[string]any{"S": shard},
+					"sequence": map[string]any{"S": sequence},
+				})
+			}
+			return map[string]any{"Items": items}
+		}
+		return map[string]any{}
+	})
+	store := &tableCheckpoints{client: s.client, table: "gateway_checkpoints"}
+
+	if err := store.save(ctx, "arn", map[string]string{"shard-1": "10", "shard-2": "20"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.save(ctx, "arn", map[string]string{"shard-2": ""}); err != nil {
+		t.Fatal(err)
+	}
+	got, err := store.load(ctx, "arn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]string{"shard-1": "10"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("load = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,288 @@
+// Package dynamodb
+// internal/datasource/dynamodb/mutate.go
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	sdynamodb "github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/thegodeveloper/data-gateway/internal/domain"
+)
+
+// Mutate writes items one at a time: PutItem for inserts and upserts,
+// UpdateItem with an update expression, DeleteItem. Writes address items by
+// their primary key, and Condition becomes a ConditionExpression; a failed
+// condition is reported as domain.ErrConflict.
+func (s *Source) Mutate(ctx context.Context, req domain.MutationRequest) (*domain.MutationResult, error) {
+	mut := req.Mutation
+	if err := checkTablesParam(req.Params); err != nil {
+		return nil, err
+	}
+	if _, ok := req.Access.Table(mut.Target); !ok {
+		return nil, fmt.Errorf("%w: table '%s' is not exposed by this route", domain.ErrInvalidRequest, mut.Target)
+	}
+	mut.Target = s.physical(req.Tables, mut.Target)
+	keys, err := s.keySchema(ctx, mut.Target)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &domain.MutationResult{Op: mut.Op}
+	switch mut.Op {
+	case domain.MutationInsert, domain.MutationUpsert:
+		for _, doc := range mut.Documents {
+			input, err := putInput(mut, keys, doc)
+			if err != nil {
+				return nil, err
+			}
+			if _, err := s.client.PutItem(ctx, input); err != nil {
+				return nil, writeError(err, mut.Target)
+			}
+			res.Affected++
+			if len(mut.Returning) > 0 {
+				res.Rows = append(res.Rows, doc)
+			}
+		}
+
+	case domain.MutationUpdate:
+		input, err := updateInput(mut, keys)
+		if err != nil {
+			return nil, err
+		}
+		out, err := s.client.UpdateItem(ctx, input)
+		if err != nil {
+			var failed *types.ConditionalCheckFailedException
+			if errors.As(err, &failed) && mut.Condition == nil {
+				// Only the implicit "item exists" check failed.
+				return res, nil
+			}
+			return nil, writeError(err, mut.Target)
+		}
+		res.Affected = 1
+		if len(mut.Returning) > 0 {
+			if res.Rows, err = unmarshalItems([]map[string]types.AttributeValue{out.Attributes}); err != nil {
+				return nil, err
+			}
+		}
+
+	case domain.MutationDelete:
+		input, err := deleteInput(mut, keys, false)
+		if err != nil {
+			return nil, err
+		}
+		out, err := s.client.DeleteItem(ctx, input)
+		if err != nil {
+			return nil, writeError(err, mut.Target)
+		}
+		if len(out.Attributes) > 0 {
+			res.Affected = 1
+			if len(mut.Returning) > 0 {
+				if res.Rows, err = unmarshalItems([]map[string]types.AttributeValue{out.Attributes}); err != nil {
+					return nil, err
+				}
+			}
+		}
+
+	default:
+		return nil, fmt.Errorf("%w: unknown mutation op '%s'", domain.ErrInvalidRequest, mut.Op)
+	}
+	return res, nil
+}
+
+// putInput builds a PutItem. An insert must not overwrite an existing item,
+// so it is conditional on the partition key being absent.
+func putInput(mut domain.Mutation, keys keySchema, doc map[string]any) (*sdynamodb.PutItemInput, error) {
+	item, err := attributevalue.MarshalMap(doc)
+	if err != nil {
+		return nil, fmt.Errorf("%w: cannot marshal item: %v", domain.ErrInvalidRequest, err)
+	}
+	if err := checkKey(keys, doc); err != nil {
+		return nil, err
+	}
+
+	p := &plan{expr: newExpression()}
+	var conditions []string
+	if mut.Op == domain.MutationInsert {
+		conditions = append(conditions, fmt.Sprintf("attribute_not_exists(%s)", p.expr.name(keys.partition)))
+	}
+	if mut.Condition != nil {
+		cond, err := p.condition(*mut.Condition)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, cond)
+	}
+
+	input := &sdynamodb.PutItemInput{TableName: aws.String(mut.Target), Item: item}
+	p.applyCondition(conditions, &input.ConditionExpression, &input.ExpressionAttributeNames, &input.ExpressionAttributeValues)
+	return input, nil
+}
+
+// updateInput builds an UpdateItem from Set (SET), Inc (ADD) and Unset
+// (REMOVE). UpdateItem would create a missing item, so the update is
+// conditional on the item existing.
+func updateInput(mut domain.Mutation, keys keySchema) (*sdynamodb.UpdateItemInput, error) {
+	key, err := itemKey(mut, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &plan{expr: newExpression()}
+	var clauses []string
+	if len(mut.Set) > 0 {
+		parts := make([]string, 0, len(mut.Set))
+		for _, field := range sortedKeys(mut.Set) {
+			placeholder, err := p.expr.value(mut.Set[field])
+			if err != nil {
+				return nil, err
+			}
+			parts = append(parts, fmt.Sprintf("%s = %s", p.expr.name(field), placeholder))
+		}
+		clauses = append(clauses, "SET "+strings.Join(parts, ", "))
+	}
+	if len(mut.Inc) > 0 {
+		parts := make([]string, 0, len(mut.Inc))
+		for _, field := range sortedKeys(mut.Inc) {
+			placeholder, err := p.expr.value(mut.Inc[field])
+			if err != nil {
+				return nil, err
+			}
+			parts = append(parts, fmt.Sprintf("%s %s", p.expr.name(field), placeholder))
+		}
+		clauses = append(clauses, "ADD "+strings.Join(parts, ", "))
+	}
+	if len(mut.Unset) > 0 {
+		parts := make([]string, len(mut.Unset))
+		for i, field := range mut.Unset {
+			parts[i] = p.expr.name(field)
+		}
+		clauses = append(clauses, "REMOVE "+strings.Join(parts, ", "))
+	}
+	for _, field := range append(sortedKeys(mut.Set), append(sortedKeys(mut.Inc), mut.Unset...)...) {
+		if field == keys.partition || field == keys.sort {
+			return nil, fmt.Errorf("%w: dynamodb cannot update key attribute '%s'", domain.ErrInvalidRequest, field)
+		}
+	}
+
+	conditions := []string{fmt.Sprintf("attribute_exists(%s)", p.expr.name(keys.partition))}
+	if mut.Condition != nil {
+		cond, err := p.condition(*mut.Condition)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, cond)
+	}
+
+	input := &sdynamodb.UpdateItemInput{
+		TableName:        aws.String(mut.Target),
+		Key:              key,
+		UpdateExpression: aws.String(strings.Join(clauses, " ")),
+	}
+	if len(mut.Returning) > 0 {
+		input.ReturnValues = types.ReturnValueAllNew
+	}
+	p.applyCondition(conditions, &input.ConditionExpression, &input.ExpressionAttributeNames, &input.ExpressionAttributeValues)
+	return input, nil
+}
+
+// deleteInput builds a DeleteItem. The old item is always requested so the
+// affected count is accurate. With mustExist, deleting a missing item fails
+// the condition instead.
+func deleteInput(mut domain.Mutation, keys keySchema, mustExist bool) (*sdynamodb.DeleteItemInput, error) {
+	key, err := itemKey(mut, keys)
+	if err != nil {
+		return nil, err
+	}
+	input := &sdynamodb.DeleteItemInput{
+		TableName:    aws.String(mut.Target),
+		Key:          key,
+		ReturnValues: types.ReturnValueAllOld,
+	}
+	p := &plan{expr: newExpression()}
+	var conditions []string
+	if mustExist {
+		conditions = append(conditions, fmt.Sprintf("attribute_exists(%s)", p.expr.name(keys.partition)))
+	}
+	if mut.Condition != nil {
+		cond, err := p.condition(*mut.Condition)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, cond)
+	}
+	p.applyCondition(conditions, &input.ConditionExpression, &input.ExpressionAttributeNames, &input.ExpressionAttributeValues)
+	return input, nil
+}
+
+// applyCondition sets the ConditionExpression and the placeholders shared
+// with the rest of the request.
+func (p *plan) applyCondition(conditions []string, expr **string, names *map[string]string, values *map[string]types.AttributeValue) {
+	if len(conditions) > 0 {
+		*expr = aws.String(strings.Join(conditions, " AND "))
+	}
+	if len(p.expr.names) > 0 {
+		*names = p.expr.names
+	}
+	if len(p.expr.values) > 0 {
+		*values = p.expr.values
+	}
+}
+
+// itemKey returns the primary key of the single item an update or delete
+// addresses.
+func itemKey(mut domain.Mutation, keys keySchema) (map[string]types.AttributeValue, error) {
+	if mut.Where != nil || mut.All {
+		return nil, fmt.Errorf("%w: dynamodb writes address one item by 'key'; use 'condition' for other predicates", domain.ErrInvalidRequest)
+	}
+	if err := checkKey(keys, mut.Key); err != nil {
+		return nil, err
+	}
+	want := 1
+	if keys.sort != "" {
+		want = 2
+	}
+	if len(mut.Key) != want {
+		return nil, fmt.Errorf("%w: 'key' must hold exactly the table's key attributes", domain.ErrInvalidRequest)
+	}
+	key, err := attributevalue.MarshalMap(mut.Key)
+	if err != nil {
+		return nil, fmt.Errorf("%w: cannot marshal key: %v", domain.ErrInvalidRequest, err)
+	}
+	return key, nil
+}
+
+func checkKey(keys keySchema, values map[string]any) error {
+	for _, name := range []string{keys.partition, keys.sort} {
+		if name == "" {
+			continue
+		}
+		if _, ok := values[name]; !ok {
+			return fmt.Errorf("%w: missing key attribute '%s'", domain.ErrInvalidRequest, name)
+		}
+	}
+	return nil
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func writeError(err error, table string) error {
+	var failed *types.ConditionalCheckFailedException
+	if errors.As(err, &failed) {
+		return fmt.Errorf("%w: condition failed on DynamoDB table '%s'", domain.ErrConflict, table)
+	}
+	return fmt.Errorf("dynamodb write to '%s' failed: %w", table, err)
+}
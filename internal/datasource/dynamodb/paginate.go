@@ -0,0 +1,102 @@
+// Package dynamodb
+// internal/datasource/dynamodb/paginate.go
+package dynamodb
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/thegodeveloper/data-gateway/internal/domain"
+)
+
+// page reads one page of a translated query. Each request's Limit is the
+// number of items still missing, so a page never over-reads and the last
+// LastEvaluatedKey is exactly where the next page starts.
+func (s *Source) page(ctx context.Context, q domain.Query, p *plan) (*domain.Page, error) {
+	var startKey map[string]types.AttributeValue
+	if q.Cursor != nil {
+		var err error
+		if startKey, err = decodeKey(q.Cursor); err != nil {
+			return nil, err
+		}
+	}
+
+	var items []map[string]types.AttributeValue
+	for {
+		batch, lastKey, err := s.read(ctx, q.Target, p, startKey, aws.Int32(int32(q.PageSize-len(items))))
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, batch...)
+		startKey = lastKey
+		if len(lastKey) == 0 || len(items) >= q.PageSize {
+			break
+		}
+	}
+
+	records, err := unmarshalItems(items)
+	if err != nil {
+		return nil, err
+	}
+	page := &domain.Page{Items: records}
+	if page.Items == nil {
+		page.Items = []map[string]any{}
+	}
+	if len(startKey) > 0 {
+		if page.Next, err = encodeKey(startKey); err != nil {
+			return nil, err
+		}
+	}
+	return page, nil
+}
+
+// keyValue is the JSON form of a key attribute; keys are only ever strings,
+// numbers or binary.
+type keyValue struct {
+	S *string `json:"S,omitempty"`
+	N *string `json:"N,omitempty"`
+	B []byte  `json:"B,omitempty"`
+}
+
+func encodeKey(key map[string]types.AttributeValue) ([]byte, error) {
+	out := make(map[string]keyValue, len(key))
+	for name, av := range key {
+		switch v := av.(type) {
+		case *types.AttributeValueMemberS:
+			out[name] = keyValue{S: aws.String(v.Value)}
+		case *types.AttributeValueMemberN:
+			out[name] = keyValue{N: aws.String(v.Value)}
+		case *types.AttributeValueMemberB:
+			out[name] = keyValue{B: v.Value}
+		default:
+			return nil, fmt.Errorf("unexpected key attribute type %T for '%s'", av, name)
+		}
+	}
+	return json.Marshal(out)
+}
+
+func decodeKey(data []byte) (map[string]types.AttributeValue, error) {
+	invalid := fmt.Errorf("%w: 'page_token' does not match this query", domain.ErrInvalidRequest)
+
+	var in map[string]keyValue
+	if err := json.Unmarshal(data, &in); err != nil || len(in) == 0 {
+		return nil, invalid
+	}
+	key := make(map[string]types.AttributeValue, len(in))
+	for name, v := range in {
+		switch {
+		case v.S != nil:
+			key[name] = &types.AttributeValueMemberS{Value: *v.S}
+		case v.N != nil:
+			key[name] = &types.AttributeValueMemberN{Value: *v.N}
+		case v.B != nil:
+			key[name] = &types.AttributeValueMemberB{Value: v.B}
+		default:
+			return nil, invalid
+		}
+	}
+	return key, nil
+}
@@ -0,0 +1,332 @@
+// Package dynamodb
+// internal/datasource/dynamodb/read.go
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	sdynamodb "github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/thegodeveloper/data-gateway/internal/domain"
+)
+
+const (
+	// maxSegments bounds parallel Scan workers per request.
+	maxSegments = 64
+	// maxBatchGet is the BatchGetItem limit on keys per call.
+	maxBatchGet = 100
+	// maxBatchGetAttempts bounds the BatchGetItem calls spent on one set
+	// of keys while DynamoDB keeps returning some of them unprocessed.
+	maxBatchGetAttempts = 6
+)
+
+var errUnprocessedKeys = errors.New("keys still unprocessed after retries")
+
+// pageSeq yields the items of successive result pages.
+type pageSeq = iter.Seq2[[]map[string]types.AttributeValue, error]
+
+// pageReader reads the page of a request that starts after startKey.
+type pageReader func(startKey map[string]types.AttributeValue) ([]map[string]types.AttributeValue, map[string]types.AttributeValue, error)
+
+// readOptions are the DynamoDB settings a request takes from params:
+//
+//	index            query or scan a global or local secondary index
+//	consistent_read  strongly consistent reads (not on global indexes)
+//	segments         split a Scan into this many parallel segments
+type readOptions struct {
+	index      string
+	consistent bool
+	segments   int
+}
+
+func parseReadOptions(params map[string]any) (readOptions, error) {
+	var opts readOptions
+	if v, ok := params["index"]; ok {
+		name, ok := v.(string)
+		if !ok || name == "" {
+			return opts, fmt.Errorf("%w: 'index' must be an index name", domain.ErrInvalidRequest)
+		}
+		opts.index = name
+	}
+	if v, ok := params["consistent_read"]; ok {
+		b, ok := v.(bool)
+		if !ok {
+			return opts, fmt.Errorf("%w: 'consistent_read' must be a boolean", domain.ErrInvalidRequest)
+		}
+		opts.consistent = b
+	}
+	if v, ok := params["segments"]; ok {
+		n, ok := v.(float64)
+		if !ok || n != float64(int(n)) || n < 1 || n > maxSegments {
+			return opts, fmt.Errorf("%w: 'segments' must be an integer between 1 and %d", domain.ErrInvalidRequest, maxSegments)
+		}
+		opts.segments = int(n)
+	}
+	return opts, nil
+}
+
+// prepare translates a structured query against the table or the requested
+// index and applies the read options. It also returns the table's own keys,
+// which decide whether the GetItem fast paths apply.
+func (s *Source) prepare(ctx context.Context, q domain.Query, params map[string]any) (*plan, keySchema, error) {
+	opts, err := parseReadOptions(params)
+	if err != nil {
+		return nil, keySchema{}, err
+	}
+	keys, err := s.keySchema(ctx, q.Target)
+	if err != nil {
+		return nil, keySchema{}, err
+	}
+
+	planKeys := keys
+	if opts.index != "" {
+		idx, err := s.indexSchema(ctx, q.Target, opts.index)
+		if err != nil {
+			return nil, keySchema{}, err
+		}
+		if idx.global && opts.consistent {
+			return nil, keySchema{}, fmt.Errorf("%w: global secondary index '%s' does not support consistent reads", domain.ErrInvalidRequest, opts.index)
+		}
+		planKeys = idx.keys
+	}
+	if opts.index == "" && opts.segments <= 1 {
+		// A key lookup needs no expressions, and translate would refuse
+		// an "in" on the sort key as a filter on a key attribute.
+		if _, ok := fullKeys(q, keys); ok {
+			return &plan{consistent: opts.consistent}, keys, nil
+		}
+	}
+
+	p, err := translate(q, planKeys)
+	if err != nil {
+		return nil, keySchema{}, err
+	}
+	p.index = opts.index
+	p.consistent = opts.consistent
+	if opts.segments > 1 {
+		if p.keyCondition != "" {
+			return nil, keySchema{}, fmt.Errorf("%w: 'segments' only applies to scans", domain.ErrInvalidRequest)
+		}
+		if q.PageSize > 0 {
+			return nil, keySchema{}, fmt.Errorf("%w: a parallel scan cannot be paginated", domain.ErrInvalidRequest)
+		}
+		p.segments = int32(opts.segments)
+	}
+	return p, keys, nil
+}
+
+// pages picks how a prepared query runs: GetItem or BatchGetItem when the
+// condition names whole primary keys and nothing else, a parallel Scan when
+// segments are requested, otherwise Query or Scan page by page.
+func (s *Source) pages(ctx context.Context, q domain.Query, p *plan, keys keySchema) pageSeq {
+	if p.index == "" && p.segments <= 1 {
+		if itemKeys, ok := fullKeys(q, keys); ok {
+			return func(yield func([]map[string]types.AttributeValue, error) bool) {
+				yield(s.getItems(ctx, q, p, itemKeys))
+			}
+		}
+	}
+	if p.segments > 1 {
+		return s.parallelScan(ctx, q.Target, p)
+	}
+	return sequential(func(startKey map[string]types.AttributeValue) ([]map[string]types.AttributeValue, map[string]types.AttributeValue, error) {
+		return s.read(ctx, q.Target, p, startKey, nil)
+	})
+}
+
+// sequential follows LastEvaluatedKey from page to page.
+func sequential(read pageReader) pageSeq {
+	return func(yield func([]map[string]types.AttributeValue, error) bool) {
+		var startKey map[string]types.AttributeValue
+		for {
+			items, lastKey, err := read(startKey)
+			if !yield(items, err) || err != nil || len(lastKey) == 0 {
+				return
+			}
+			startKey = lastKey
+		}
+	}
+}
+
+// parallelScan runs every segment in its own goroutine and yields pages as
+// they arrive, in no particular order. Stopping the iteration cancels the
+// remaining workers.
+func (s *Source) parallelScan(ctx context.Context, table string, p *plan) pageSeq {
+	return func(yield func([]map[string]types.AttributeValue, error) bool) {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		type result struct {
+			items []map[string]types.AttributeValue
+			err   error
+		}
+		results := make(chan result, p.segments)
+		var wg sync.WaitGroup
+		for segment := int32(0); segment < p.segments; segment++ {
+			wg.Add(1)
+			go func(worker plan) {
+				defer wg.Done()
+				for items, err := range sequential(func(startKey map[string]types.AttributeValue) ([]map[string]types.AttributeValue, map[string]types.AttributeValue, error) {
+					return s.read(ctx, table, &worker, startKey, nil)
+				}) {
+					select {
+					case results <- result{items, err}:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}(plan{
+				filter: p.filter, projection: p.projection, expr: p.expr,
+				consistent: p.consistent, index: p.index,
+				segment: segment, segments: p.segments,
+			})
+		}
+		go func() {
+			wg.Wait()
+			close(results)
+		}()
+
+		for r := range results {
+			if !yield(r.items, r.err) || r.err != nil {
+				return
+			}
+		}
+	}
+}
+
+// fullKeys returns the primary keys a query selects when its condition is
+// only equality ("eq" or "in") on the partition key and, for composite
+// keys, on the sort key. Sorted or offset queries take the normal path.
+func fullKeys(q domain.Query, keys keySchema) ([]map[string]any, bool) {
+	if q.Where == nil || len(q.Sort) > 0 || q.Offset > 0 || q.PageSize > 0 {
+		return nil, false
+	}
+
+	var partitions, sorts []any
+	for _, c := range flattenAnd(*q.Where) {
+		var values []any
+		switch c.Op {
+		case domain.OpEq:
+			values = []any{c.Value}
+		case domain.OpIn:
+			values = c.Value.([]any)
+		default:
+			return nil, false
+		}
+		switch {
+		case c.Field == keys.partition && partitions == nil:
+			partitions = values
+		case keys.sort != "" && c.Field == keys.sort && sorts == nil:
+			sorts = values
+		default:
+			return nil, false
+		}
+	}
+	if len(partitions) == 0 || (keys.sort != "" && len(sorts) == 0) {
+		return nil, false
+	}
+
+	var out []map[string]any
+	seen := make(map[string]bool)
+	for _, pv := range partitions {
+		if keys.sort == "" {
+			if id := fmt.Sprintf("%T:%v", pv, pv); !seen[id] {
+				seen[id] = true
+				out = append(out, map[string]any{keys.partition: pv})
+			}
+			continue
+		}
+		for _, sv := range sorts {
+			if id := fmt.Sprintf("%T:%v|%T:%v", pv, pv, sv, sv); !seen[id] {
+				seen[id] = true
+				out = append(out, map[string]any{keys.partition: pv, keys.sort: sv})
+			}
+		}
+	}
+	if len(out) > maxBatchGet {
+		return nil, false
+	}
+	return out, true
+}
+
+// getItems fetches items by primary key: GetItem for one key, otherwise
+// BatchGetItem, retrying unprocessed keys with backoff. Keys still
+// unprocessed after maxBatchGetAttempts calls fail the read with a
+// domain.ThrottleError.
+func (s *Source) getItems(ctx context.Context, q domain.Query, p *plan, itemKeys []map[string]any) ([]map[string]types.AttributeValue, error) {
+	keys := make([]map[string]types.AttributeValue, len(itemKeys))
+	for i, k := range itemKeys {
+		av, err := attributevalue.MarshalMap(k)
+		if err != nil {
+			return nil, fmt.Errorf("%w: cannot marshal key: %v", domain.ErrInvalidRequest, err)
+		}
+		keys[i] = av
+	}
+
+	// The plan's names include the key condition's, which DynamoDB rejects
+	// as unused here, so the projection gets its own placeholders.
+	var projection *string
+	var names map[string]string
+	if len(q.Select) > 0 {
+		expr := newExpression()
+		fields := make([]string, len(q.Select))
+		for i, field := range q.Select {
+			fields[i] = expr.name(field)
+		}
+		projection, names = aws.String(strings.Join(fields, ", ")), expr.names
+	}
+
+	if len(keys) == 1 {
+		out, err := s.client.GetItem(ctx, &sdynamodb.GetItemInput{
+			TableName:                aws.String(q.Target),
+			Key:                      keys[0],
+			ProjectionExpression:     projection,
+			ExpressionAttributeNames: names,
+			ConsistentRead:           consistent(p.consistent),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("dynamodb get item failed: %w", err)
+		}
+		if out.Item == nil {
+			return nil, nil
+		}
+		return []map[string]types.AttributeValue{out.Item}, nil
+	}
+
+	request := map[string]types.KeysAndAttributes{q.Target: {
+		Keys:                     keys,
+		ProjectionExpression:     projection,
+		ExpressionAttributeNames: names,
+		ConsistentRead:           consistent(p.consistent),
+	}}
+	var items []map[string]types.AttributeValue
+	for attempt := 1; ; attempt++ {
+		out, err := s.client.BatchGetItem(ctx, &sdynamodb.BatchGetItemInput{RequestItems: request})
+		if err != nil {
+			return nil, fmt.Errorf("dynamodb batch get failed: %w", err)
+		}
+		items = append(items, out.Responses[q.Target]...)
+		if len(out.UnprocessedKeys) == 0 {
+			return items, nil
+		}
+		request = out.UnprocessedKeys
+
+		backoff := min(50*time.Millisecond<<min(attempt-1, 5), time.Second)
+		if attempt == maxBatchGetAttempts {
+			return nil, &domain.ThrottleError{Target: q.Target, RetryAfter: backoff, Err: errUnprocessedKeys}
+		}
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
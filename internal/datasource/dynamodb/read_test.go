@@ -0,0 +1,269 @@
+package dynamodb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	sdynamodb "github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/thegodeveloper/data-gateway/internal/domain"
+)
+
+// fakeDynamo answers DynamoDB and DynamoDB Streams API calls. It gets the
+// operation name and the decoded request body, and returns the response
+// body or an apiError.
+type fakeDynamo func(op string, body map[string]any) any
+
+// apiError is the error response of a fake call, by its exception name.
+type apiError string
+
+func (f fakeDynamo) Do(req *http.Request) (*http.Response, error) {
+	var body map[string]any
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+	target := req.Header.Get("X-Amz-Target")
+	op := target[strings.LastIndex(target, ".")+1:]
+	status, res := http.StatusOK, f(op, body)
+	if name, ok := res.(apiError); ok {
+		res = map[string]any{"__type": "com.amazonaws.dynamodb.v20120810#" + string(name), "message": string(name)}
+	}
+	// A response with a "__type" is an error, and may carry more members.
+	if m, ok := res.(map[string]any); ok && m["__type"] != nil {
+		status = http.StatusBadRequest
+	}
+	out, err := json.Marshal(res)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Response{
+		StatusCode: status,
+		Header:     http.Header{"Content-Type": {"application/x-amz-json-1.0"}},
+		Body:       io.NopCloser(strings.NewReader(string(out))),
+		Request:    req,
+	}, nil
+}
+
+func newFakeSource(f fakeDynamo) *Source {
+	return NewSource(sdynamodb.New(sdynamodb.Options{
+		Region:       "us-east-1",
+		BaseEndpoint: aws.String("http://dynamodb.test"),
+		Credentials:  aws.AnonymousCredentials{},
+		HTTPClient:   f,
+		Retryer:      aws.NopRetryer{},
+	}))
+}
+
+func TestGetItemsBoundsUnprocessedRetries(t *testing.T) {
+	calls := 0
+	s := newFakeSource(func(op string, body map[string]any) any {
+		calls++
+		return map[string]any{
+			"Responses":       map[string]any{"sessions": []any{}},
+			"UnprocessedKeys": body["RequestItems"],
+		}
+	})
+
+	keys := []map[string]any{{"id": "a"}, {"id": "b"}}
+	_, err := s.getItems(context.Background(), domain.Query{Target: "sessions"}, &plan{}, keys)
+	var throttled *domain.ThrottleError
+	if !errors.As(err, &throttled) || throttled.RetryAfter <= 0 {
+		t.Fatalf("error = %v, want a ThrottleError with RetryAfter", err)
+	}
+	if calls != maxBatchGetAttempts {
+		t.Fatalf("BatchGetItem calls = %d, want %d", calls, maxBatchGetAttempts)
+	}
+}
+
+func TestGetItemsCollectsRetriedKeys(t *testing.T) {
+	calls := 0
+	s := newFakeSource(func(op string, body map[string]any) any {
+		calls++
+		if calls == 1 {
+			return map[string]any{
+				"Responses": map[string]any{"sessions": []any{map[string]any{"id": map[string]any{"S": "a"}}}},
+				"UnprocessedKeys": map[string]any{"sessions": map[string]any{
+					"Keys": []any{map[string]any{"id": map[string]any{"S": "b"}}},
+				}},
+			}
+		}
+		return map[string]any{
+			"Responses": map[string]any{"sessions": []any{map[string]any{"id": map[string]any{"S": "b"}}}},
+		}
+	})
+
+	keys := []map[string]any{{"id": "a"}, {"id": "b"}}
+	items, err := s.getItems(context.Background(), domain.Query{Target: "sessions"}, &plan{}, keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 || calls != 2 {
+		t.Fatalf("got %d items in %d calls, want 2 in 2", len(items), calls)
+	}
+}
+
+func TestFullKeys(t *testing.T) {
+	simple := keySchema{partition: "id"}
+	composite := keySchema{partition: "user", sort: "ts"}
+	eq := func(field string, v any) domain.Condition {
+		return domain.Condition{Field: field, Op: domain.OpEq, Value: v}
+	}
+	in := func(field string, v ...any) domain.Condition {
+		return domain.Condition{Field: field, Op: domain.OpIn, Value: v}
+	}
+	and := func(c ...domain.Condition) *domain.Condition { return &domain.Condition{And: c} }
+	many := make([]any, maxBatchGet+1)
+	for i := range many {
+		many[i] = i
+	}
+
+	tests := []struct {
+		name  string
+		keys  keySchema
+		query domain.Query
+		want  []map[string]any // nil when the query is not a key lookup
+	}{
+		{"partition", simple, domain.Query{Where: &domain.Condition{Field: "id", Op: domain.OpEq, Value: "a"}}, []map[string]any{{"id": "a"}}},
+		{"partitions", simple, domain.Query{Where: and(in("id", "a", "b", "a"))}, []map[string]any{{"id": "a"}, {"id": "b"}}},
+		{"same text, other type", simple, domain.Query{Where: and(in("id", "1", 1.0))}, []map[string]any{{"id": "1"}, {"id": 1.0}}},
+		{"composite", composite, domain.Query{Where: and(eq("user", "u"), in("ts", 1.0, 2.0))},
+			[]map[string]any{{"user": "u", "ts": 1.0}, {"user": "u", "ts": 2.0}}},
+		{"composite, sort key first", composite, domain.Query{Where: and(eq("ts", 1.0), eq("user", "u"))}, []map[string]any{{"user": "u", "ts": 1.0}}},
+		{"no condition", simple, domain.Query{}, nil},
+		{"partition only of a composite key", composite, domain.Query{Where: and(eq("user", "u"))}, nil},
+		{"other field", simple, domain.Query{Where: and(eq("id", "a"), eq("status", "open"))}, nil},
+		{"range", composite, domain.Query{Where: and(eq("user", "u"), domain.Condition{Field: "ts", Op: domain.OpGt, Value: 1.0})}, nil},
+		{"or", simple, domain.Query{Where: &domain.Condition{Or: []domain.Condition{eq("id", "a"), eq("id", "b")}}}, nil},
+		{"repeated field", simple, domain.Query{Where: and(eq("id", "a"), eq("id", "b"))}, nil},
+		{"sorted", simple, domain.Query{Where: and(eq("id", "a")), Sort: []domain.SortField{{Field: "id"}}}, nil},
+		{"offset", simple, domain.Query{Where: and(eq("id", "a")), Offset: 1}, nil},
+		{"paginated", simple, domain.Query{Where: and(eq("id", "a")), PageSize: 10}, nil},
+		{"too many keys", simple, domain.Query{Where: and(in("id", many...))}, nil},
+	}
+	for _, tt := range tests {
+		got, ok := fullKeys(tt.query, tt.keys)
+		if ok != (tt.want != nil) || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: fullKeys = %v, %v; want %v", tt.name, got, ok, tt.want)
+		}
+	}
+}
+
+// eventsTable describes a table keyed by user and ts, with a global index on
+// kind and a local index on user and score.
+func eventsTable() map[string]any {
+	key := func(attrs ...string) []any {
+		out := []any{map[string]any{"AttributeName": attrs[0], "KeyType": "HASH"}}
+		if len(attrs) > 1 {
+			out = append(out, map[string]any{"AttributeName": attrs[1], "KeyType": "RANGE"})
+		}
+		return out
+	}
+	return map[string]any{"Table": map[string]any{
+		"KeySchema":              key("user", "ts"),
+		"GlobalSecondaryIndexes": []any{map[string]any{"IndexName": "by_kind", "KeySchema": key("kind")}},
+		"LocalSecondaryIndexes":  []any{map[string]any{"IndexName": "by_score", "KeySchema": key("user", "score")}},
+	}}
+}
+
+func TestReadPicksTheOperation(t *testing.T) {
+	eq := func(field string, v any) domain.Condition {
+		return domain.Condition{Field: field, Op: domain.OpEq, Value: v}
+	}
+	and := func(c ...domain.Condition) *domain.Condition { return &domain.Condition{And: c} }
+
+	tests := []struct {
+		name   string
+		query  domain.Query
+		params map[string]any
+		want   []string // operation, then IndexName, ConsistentRead or Segment when set
+	}{
+		{"whole key", domain.Query{Where: and(eq("user", "u"), eq("ts", 1.0))}, nil, []string{"GetItem"}},
+		{"whole keys", domain.Query{Where: and(domain.Condition{Field: "user", Op: domain.OpIn, Value: []any{"u", "v"}}, eq("ts", 1.0))}, nil, []string{"BatchGetItem"}},
+		{"whole keys of a partition", domain.Query{Where: and(eq("user", "u"), domain.Condition{Field: "ts", Op: domain.OpIn, Value: []any{1.0, 2.0}})}, nil, []string{"BatchGetItem"}},
+		{"consistent whole key", domain.Query{Where: and(eq("user", "u"), eq("ts", 1.0))}, map[string]any{"consistent_read": true}, []string{"GetItem consistent"}},
+		{"partition", domain.Query{Where: and(eq("user", "u"))}, nil, []string{"Query"}},
+		{"whole key, sorted", domain.Query{Where: and(eq("user", "u"), eq("ts", 1.0)), Sort: []domain.SortField{{Field: "ts"}}}, nil, []string{"Query"}},
+		{"whole key on an index", domain.Query{Where: and(eq("user", "u"), eq("score", 1.0))}, map[string]any{"index": "by_score"}, []string{"Query by_score"}},
+		{"global index", domain.Query{Where: and(eq("kind", "click"))}, map[string]any{"index": "by_kind"}, []string{"Query by_kind"}},
+		{"consistent local index", domain.Query{Where: and(eq("user", "u"))}, map[string]any{"index": "by_score", "consistent_read": true}, []string{"Query by_score consistent"}},
+		{"no condition", domain.Query{}, nil, []string{"Scan"}},
+		{"other field", domain.Query{Where: and(eq("kind", "click"))}, nil, []string{"Scan"}},
+		{"one segment", domain.Query{}, map[string]any{"segments": 1.0}, []string{"Scan"}},
+		{"parallel", domain.Query{}, map[string]any{"segments": 3.0}, []string{"Scan segment 0/3", "Scan segment 1/3", "Scan segment 2/3"}},
+		{"parallel on an index", domain.Query{}, map[string]any{"segments": 2.0, "index": "by_kind"}, []string{"Scan by_kind segment 0/2", "Scan by_kind segment 1/2"}},
+	}
+	for _, tt := range tests {
+		var mu sync.Mutex
+		var calls []string
+		s := newFakeSource(func(op string, body map[string]any) any {
+			if op == "DescribeTable" {
+				return eventsTable()
+			}
+			if op == "BatchGetItem" {
+				body = body["RequestItems"].(map[string]any)["events"].(map[string]any)
+			}
+			call := op
+			if index, ok := body["IndexName"].(string); ok {
+				call += " " + index
+			}
+			if body["ConsistentRead"] == true {
+				call += " consistent"
+			}
+			if total, ok := body["TotalSegments"].(float64); ok {
+				call += fmt.Sprintf(" segment %v/%v", body["Segment"], total)
+			}
+			mu.Lock()
+			calls = append(calls, call)
+			mu.Unlock()
+			return map[string]any{"Items": []any{}, "Responses": map[string]any{"events": []any{}}}
+		})
+
+		q := tt.query
+		q.Target = "events"
+		if _, err := s.structured(context.Background(), q, tt.params); err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		slices.Sort(calls)
+		if !slices.Equal(calls, tt.want) {
+			t.Errorf("%s: calls = %v, want %v", tt.name, calls, tt.want)
+		}
+	}
+}
+
+func TestPrepareRejects(t *testing.T) {
+	partition := &domain.Condition{Field: "user", Op: domain.OpEq, Value: "u"}
+	tests := []struct {
+		name   string
+		query  domain.Query
+		params map[string]any
+	}{
+		{"empty index", domain.Query{}, map[string]any{"index": ""}},
+		{"unknown index", domain.Query{}, map[string]any{"index": "by_nothing"}},
+		{"consistent global index", domain.Query{}, map[string]any{"index": "by_kind", "consistent_read": true}},
+		{"consistent_read type", domain.Query{}, map[string]any{"consistent_read": "yes"}},
+		{"no segments", domain.Query{}, map[string]any{"segments": 0.0}},
+		{"too many segments", domain.Query{}, map[string]any{"segments": float64(maxSegments + 1)}},
+		{"fractional segments", domain.Query{}, map[string]any{"segments": 1.5}},
+		{"segments type", domain.Query{}, map[string]any{"segments": "2"}},
+		{"segments with a key condition", domain.Query{Where: partition}, map[string]any{"segments": 2.0}},
+		{"segments with pages", domain.Query{PageSize: 10}, map[string]any{"segments": 2.0}},
+	}
+	s := newFakeSource(func(op string, body map[string]any) any { return eventsTable() })
+	for _, tt := range tests {
+		q := tt.query
+		q.Target = "events"
+		if _, _, err := s.prepare(context.Background(), q, tt.params); !errors.Is(err, domain.ErrInvalidRequest) {
+			t.Errorf("%s: error = %v, want ErrInvalidRequest", tt.name, err)
+		}
+	}
+}
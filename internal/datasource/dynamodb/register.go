@@ -0,0 +1,64 @@
+// Package dynamodb
+// internal/datasource/dynamodb/register.go
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/thegodeveloper/data-gateway/internal/datasource"
+	"github.com/thegodeveloper/data-gateway/internal/domain"
+)
+
+func init() {
+	datasource.Register("dynamodb", newFromSettings)
+}
+
+// Settings configures a "dynamodb" data source instance.
+type Settings struct {
+	ClientOptions `mapstructure:",squash"`
+	// TablePrefix is put in front of every table name, e.g. "dev_".
+	TablePrefix string `mapstructure:"table_prefix"`
+	// Tables renames logical table names before the prefix is applied.
+	Tables map[string]string `mapstructure:"tables"`
+	// Budgets caps the capacity units per second spent on each table, keyed
+	// by physical table name.
+	Budgets map[string]Budget `mapstructure:"budgets"`
+	// Streams lists the tables whose changes watchers can subscribe to.
+	Streams Streams `mapstructure:"streams"`
+}
+
+func newFromSettings(ctx context.Context, raw map[string]any) (domain.DataSource, error) {
+	var settings Settings
+	if err := datasource.DecodeSettings(raw, &settings); err != nil {
+		return nil, err
+	}
+	if settings.Region == "" {
+		return nil, errors.New("missing 'region' setting")
+	}
+
+	for table, b := range settings.Budgets {
+		if b.Read < 0 || b.Write < 0 || b.Burst < 0 {
+			return nil, fmt.Errorf("invalid budget for table '%s': values cannot be negative", table)
+		}
+	}
+
+	client, err := NewClient(ctx, settings.ClientOptions)
+	if err != nil {
+		return nil, err
+	}
+	src := NewSource(client)
+	src.SetTables(settings.TablePrefix, settings.Tables)
+	src.SetBudgets(settings.Budgets)
+	if len(settings.Streams.Tables) > 0 {
+		streams, err := NewStreamsClient(ctx, settings.ClientOptions)
+		if err != nil {
+			return nil, err
+		}
+		if err := src.SetStreams(ctx, streams, settings.Streams); err != nil {
+			return nil, err
+		}
+	}
+	return src, nil
+}
@@ -0,0 +1,88 @@
+// Package dynamodb
+// internal/datasource/dynamodb/schema.go
+package dynamodb
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	sdynamodb "github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/thegodeveloper/data-gateway/internal/domain"
+)
+
+// tableSchema holds the key attributes of a table and of each of its
+// secondary indexes.
+type tableSchema struct {
+	keys    keySchema
+	indexes map[string]index
+}
+
+type index struct {
+	keys   keySchema
+	global bool
+}
+
+// keySchema returns the table's primary key attributes.
+func (s *Source) keySchema(ctx context.Context, table string) (keySchema, error) {
+	schema, err := s.schema(ctx, table)
+	if err != nil {
+		return keySchema{}, err
+	}
+	return schema.keys, nil
+}
+
+// indexSchema returns the key attributes of a global or local secondary
+// index of the table.
+func (s *Source) indexSchema(ctx context.Context, table, name string) (index, error) {
+	schema, err := s.schema(ctx, table)
+	if err != nil {
+		return index{}, err
+	}
+	idx, ok := schema.indexes[name]
+	if !ok {
+		return index{}, fmt.Errorf("%w: table '%s' has no index '%s'", domain.ErrInvalidRequest, table, name)
+	}
+	return idx, nil
+}
+
+// schema asks DynamoDB once per table and caches the answer.
+func (s *Source) schema(ctx context.Context, table string) (tableSchema, error) {
+	s.mu.RLock()
+	schema, ok := s.schemas[table]
+	s.mu.RUnlock()
+	if ok {
+		return schema, nil
+	}
+
+	out, err := s.client.DescribeTable(ctx, &sdynamodb.DescribeTableInput{TableName: aws.String(table)})
+	if err != nil {
+		return tableSchema{}, fmt.Errorf("failed to describe DynamoDB table '%s': %w", table, err)
+	}
+	schema = tableSchema{keys: keysOf(out.Table.KeySchema), indexes: make(map[string]index)}
+	for _, gsi := range out.Table.GlobalSecondaryIndexes {
+		schema.indexes[aws.ToString(gsi.IndexName)] = index{keys: keysOf(gsi.KeySchema), global: true}
+	}
+	for _, lsi := range out.Table.LocalSecondaryIndexes {
+		schema.indexes[aws.ToString(lsi.IndexName)] = index{keys: keysOf(lsi.KeySchema)}
+	}
+
+	s.mu.Lock()
+	s.schemas[table] = schema
+	s.mu.Unlock()
+	return schema, nil
+}
+
+func keysOf(elements []types.KeySchemaElement) keySchema {
+	var keys keySchema
+	for _, k := range elements {
+		switch k.KeyType {
+		case types.KeyTypeHash:
+			keys.partition = aws.ToString(k.AttributeName)
+		case types.KeyTypeRange:
+			keys.sort = aws.ToString(k.AttributeName)
+		}
+	}
+	return keys
+}
@@ -0,0 +1,122 @@
+// Package dynamodb
+// internal/datasource/dynamodb/streams.go
+package dynamodb
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	sdynamodb "github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodbstreams"
+	"github.com/thegodeveloper/data-gateway/internal/domain"
+)
+
+// Streams configures which tables a dynamodb instance reports to watchers.
+type Streams struct {
+	// Tables are the logical names of the tables whose streams are read.
+	// Each needs DynamoDB Streams enabled, with NEW_AND_OLD_IMAGES to
+	// report both images.
+	Tables []string `mapstructure:"tables"`
+	// PollInterval is how often a drained stream is read again (default 1s).
+	PollInterval time.Duration `mapstructure:"poll_interval"`
+	// Buffer is how many recent changes of each table are kept for resuming
+	// (default 10000).
+	Buffer int `mapstructure:"buffer"`
+	// CheckpointTable is a DynamoDB table, by its physical name, where the
+	// position read up to in each shard is saved, so that a restart picks
+	// up there instead of at the latest records. See tableCheckpoints for
+	// its key schema.
+	CheckpointTable string `mapstructure:"checkpoint_table"`
+}
+
+// SetStreams starts reading the stream of every configured table. A table
+// whose stream is not enabled is an error.
+func (s *Source) SetStreams(ctx context.Context, client *dynamodbstreams.Client, cfg Streams) error {
+	feeds := make(map[string]*streamFeed, len(cfg.Tables))
+	closeAll := func() {
+		for _, f := range feeds {
+			f.close()
+		}
+	}
+	var store checkpointStore
+	if cfg.CheckpointTable != "" {
+		store = &tableCheckpoints{client: s.client, table: cfg.CheckpointTable}
+	}
+	for _, table := range cfg.Tables {
+		physical := s.physical(nil, table)
+		out, err := s.client.DescribeTable(ctx, &sdynamodb.DescribeTableInput{TableName: aws.String(physical)})
+		if err != nil {
+			closeAll()
+			return fmt.Errorf("failed to describe DynamoDB table '%s': %w", physical, err)
+		}
+		spec := out.Table.StreamSpecification
+		if spec == nil || !aws.ToBool(spec.StreamEnabled) || out.Table.LatestStreamArn == nil {
+			closeAll()
+			return fmt.Errorf("DynamoDB table '%s' has no stream enabled", physical)
+		}
+		feed, err := newStreamFeed(ctx, client, table, aws.ToString(out.Table.LatestStreamArn), cfg, store)
+		if err != nil {
+			closeAll()
+			return err
+		}
+		feeds[table] = feed
+	}
+
+	s.mu.Lock()
+	old := s.feeds
+	s.feeds = feeds
+	s.mu.Unlock()
+	for _, f := range old {
+		f.close()
+	}
+	return nil
+}
+
+// Watch reports the inserts, updates and deletes of params.table, one of
+// the tables configured under streams. Key holds the item's key attributes,
+// Document the new image and Before the old one.
+//
+// Change IDs are positions in the gateway's buffer of the table's recent
+// changes; they do not survive a restart.
+func (s *Source) Watch(ctx context.Context, req domain.WatchRequest) (domain.ChangeStream, error) {
+	table, ok := req.Params["table"].(string)
+	if !ok || table == "" {
+		return nil, fmt.Errorf("%w: missing or invalid 'table' parameter", domain.ErrInvalidRequest)
+	}
+	columns, ok := req.Access.Table(table)
+	if !ok {
+		return nil, fmt.Errorf("%w: table '%s' is not exposed by this route", domain.ErrInvalidRequest, table)
+	}
+	s.mu.RLock()
+	feed, ok := s.feeds[table]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: table '%s' has no change feed", domain.ErrInvalidRequest, table)
+	}
+
+	index := feed.latest()
+	if req.ResumeAfter != "" {
+		var err error
+		if index, err = feed.parseID(req.ResumeAfter); err != nil {
+			return nil, err
+		}
+		if _, _, ok := feed.since(index); !ok {
+			return nil, fmt.Errorf("%w: resume position '%s' is no longer buffered; reload and watch from now", domain.ErrInvalidRequest, req.ResumeAfter)
+		}
+	}
+	return feed.subscribe(ctx, index, columns), nil
+}
+
+// Close stops reading the table streams.
+func (s *Source) Close(ctx context.Context) error {
+	s.mu.Lock()
+	feeds := s.feeds
+	s.feeds = nil
+	s.mu.Unlock()
+	for _, f := range feeds {
+		f.close()
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+// Package dynamodb
+// internal/datasource/dynamodb/tables.go
+package dynamodb
+
+import (
+	"fmt"
+
+	"github.com/thegodeveloper/data-gateway/internal/domain"
+)
+
+// tableNames maps the logical table names requests and routes use to the
+// physical tables of one environment.
+type tableNames struct {
+	prefix string
+	tables map[string]string
+}
+
+// SetTables sets the instance's table mapping: logical names found in tables
+// are renamed, and prefix (e.g. "dev_") is put in front of every name.
+func (s *Source) SetTables(prefix string, tables map[string]string) {
+	s.names = tableNames{prefix: prefix, tables: tables}
+}
+
+// physical resolves a logical table name. The mapping of the route the
+// request came through, from its configuration, wins over the instance's;
+// the instance prefix applies either way, so routes stay the same across
+// environments.
+func (s *Source) physical(route map[string]string, logical string) string {
+	name := logical
+	if mapped, ok := s.names.tables[logical]; ok {
+		name = mapped
+	}
+	if mapped, ok := route[logical]; ok {
+		name = mapped
+	}
+	return s.names.prefix + name
+}
+
+// checkTablesParam refuses a table mapping in request params. Only a
+// route's configuration maps table names, so callers cannot point a route
+// at another table.
+func checkTablesParam(params map[string]any) error {
+	if _, ok := params["tables"]; ok {
+		return fmt.Errorf("%w: 'tables' is not a request parameter; map table names in the route's 'tables'", domain.ErrInvalidRequest)
+	}
+	return nil
+}
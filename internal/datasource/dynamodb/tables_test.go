@@ -0,0 +1,59 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/thegodeveloper/data-gateway/internal/domain"
+)
+
+func TestPhysicalTableNames(t *testing.T) {
+	s := &Source{}
+	s.SetTables("dev_", map[string]string{"invoices": "billing_invoices"})
+	route := map[string]string{"invoices": "invoices_archive"}
+
+	tests := []struct {
+		route   map[string]string
+		logical string
+		want    string
+	}{
+		{nil, "orders", "dev_orders"},
+		{nil, "invoices", "dev_billing_invoices"},
+		{route, "invoices", "dev_invoices_archive"},
+		{route, "orders", "dev_orders"},
+	}
+	for _, tt := range tests {
+		if got := s.physical(tt.route, tt.logical); got != tt.want {
+			t.Errorf("physical(%v, %q) = %q, want %q", tt.route, tt.logical, got, tt.want)
+		}
+	}
+}
+
+func TestRequestParamsCannotRemapTables(t *testing.T) {
+	var scanned []string
+	s := newFakeSource(func(op string, body map[string]any) any {
+		scanned = append(scanned, body["TableName"].(string))
+		return map[string]any{"Items": []any{}}
+	})
+	s.SetTables("dev_", nil)
+
+	params := map[string]any{"table": "invoices", "tables": map[string]any{"invoices": "secrets"}}
+	if _, err := s.Query(context.Background(), domain.QueryRequest{Params: params}); !errors.Is(err, domain.ErrInvalidRequest) {
+		t.Fatalf("params.tables: error = %v, want ErrInvalidRequest", err)
+	}
+	mutation := domain.MutationRequest{Params: params, Mutation: domain.Mutation{Op: domain.MutationDelete, Target: "invoices", All: true}}
+	if _, err := s.Mutate(context.Background(), mutation); !errors.Is(err, domain.ErrInvalidRequest) {
+		t.Fatalf("params.tables in a mutation: error = %v, want ErrInvalidRequest", err)
+	}
+	if _, err := s.Query(context.Background(), domain.QueryRequest{Params: map[string]any{"table": "invoices"}}); err != nil {
+		t.Fatal(err)
+	}
+	route := domain.QueryRequest{Params: map[string]any{"table": "invoices"}, Tables: map[string]string{"invoices": "invoices_archive"}}
+	if _, err := s.Query(context.Background(), route); err != nil {
+		t.Fatal(err)
+	}
+	if len(scanned) != 2 || scanned[0] != "dev_invoices" || scanned[1] != "dev_invoices_archive" {
+		t.Fatalf("scanned %v, want [dev_invoices dev_invoices_archive]", scanned)
+	}
+}
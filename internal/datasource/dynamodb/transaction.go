@@ -0,0 +1,143 @@
+// Package dynamodb
+// internal/datasource/dynamodb/transaction.go
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	sdynamodb "github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/thegodeveloper/data-gateway/internal/domain"
+)
+
+// maxTransactItems is the TransactWriteItems limit on items per call.
+const maxTransactItems = 100
+
+// Transact runs every step in one TransactWriteItems call. Each document of
+// an insert or upsert is one item. Inside a transaction, updates and deletes
+// of a missing item fail the transaction rather than affecting nothing,
+// since DynamoDB cannot report per-item results.
+func (s *Source) Transact(ctx context.Context, req domain.TransactionRequest) (*domain.TransactionResult, error) {
+	if err := checkTablesParam(req.Params); err != nil {
+		return nil, err
+	}
+	var items []types.TransactWriteItem
+	var owners []int // step index of each item
+	res := &domain.TransactionResult{Steps: make([]*domain.MutationResult, len(req.Steps))}
+
+	for i, step := range req.Steps {
+		stepItems, err := s.transactItems(ctx, step, req.Access)
+		if err != nil {
+			return nil, &domain.StepError{Step: i, Op: step.Op, Err: err}
+		}
+		for range stepItems {
+			owners = append(owners, i)
+		}
+		items = append(items, stepItems...)
+		res.Steps[i] = &domain.MutationResult{Op: step.Op, Affected: int64(len(stepItems))}
+	}
+	if len(items) > maxTransactItems {
+		return nil, fmt.Errorf("%w: a dynamodb transaction writes at most %d items, got %d", domain.ErrInvalidRequest, maxTransactItems, len(items))
+	}
+
+	_, err := s.client.TransactWriteItems(ctx, &sdynamodb.TransactWriteItemsInput{TransactItems: items})
+	if err != nil {
+		return nil, transactError(err, req.Steps, owners)
+	}
+	return res, nil
+}
+
+func (s *Source) transactItems(ctx context.Context, mut domain.Mutation, access *domain.Access) ([]types.TransactWriteItem, error) {
+	if _, ok := access.Table(mut.Target); !ok {
+		return nil, fmt.Errorf("%w: table '%s' is not exposed by this route", domain.ErrInvalidRequest, mut.Target)
+	}
+	mut.Target = s.physical(nil, mut.Target)
+	if len(mut.Returning) > 0 {
+		return nil, fmt.Errorf("%w: dynamodb transactions do not support 'returning'", domain.ErrInvalidRequest)
+	}
+	keys, err := s.keySchema(ctx, mut.Target)
+	if err != nil {
+		return nil, err
+	}
+
+	switch mut.Op {
+	case domain.MutationInsert, domain.MutationUpsert:
+		items := make([]types.TransactWriteItem, len(mut.Documents))
+		for i, doc := range mut.Documents {
+			in, err := putInput(mut, keys, doc)
+			if err != nil {
+				return nil, err
+			}
+			items[i] = types.TransactWriteItem{Put: &types.Put{
+				TableName:                 in.TableName,
+				Item:                      in.Item,
+				ConditionExpression:       in.ConditionExpression,
+				ExpressionAttributeNames:  in.ExpressionAttributeNames,
+				ExpressionAttributeValues: in.ExpressionAttributeValues,
+			}}
+		}
+		return items, nil
+
+	case domain.MutationUpdate:
+		in, err := updateInput(mut, keys)
+		if err != nil {
+			return nil, err
+		}
+		return []types.TransactWriteItem{{Update: &types.Update{
+			TableName:                 in.TableName,
+			Key:                       in.Key,
+			UpdateExpression:          in.UpdateExpression,
+			ConditionExpression:       in.ConditionExpression,
+			ExpressionAttributeNames:  in.ExpressionAttributeNames,
+			ExpressionAttributeValues: in.ExpressionAttributeValues,
+		}}}, nil
+
+	case domain.MutationDelete:
+		in, err := deleteInput(mut, keys, true)
+		if err != nil {
+			return nil, err
+		}
+		return []types.TransactWriteItem{{Delete: &types.Delete{
+			TableName:                 in.TableName,
+			Key:                       in.Key,
+			ConditionExpression:       in.ConditionExpression,
+			ExpressionAttributeNames:  in.ExpressionAttributeNames,
+			ExpressionAttributeValues: in.ExpressionAttributeValues,
+		}}}, nil
+
+	default:
+		return nil, fmt.Errorf("%w: unknown mutation op '%s'", domain.ErrInvalidRequest, mut.Op)
+	}
+}
+
+// transactError maps a cancelled transaction back to the first step whose
+// item caused it.
+func transactError(err error, steps []domain.Mutation, owners []int) error {
+	var cancelled *types.TransactionCanceledException
+	if !errors.As(err, &cancelled) {
+		return fmt.Errorf("dynamodb transaction failed: %w", err)
+	}
+	for i, reason := range cancelled.CancellationReasons {
+		code := aws.ToString(reason.Code)
+		if code == "" || code == "None" || i >= len(owners) {
+			continue
+		}
+		step := owners[i]
+		var cause error
+		switch code {
+		case "ConditionalCheckFailed", "TransactionConflict":
+			cause = fmt.Errorf("%w: %s on table '%s'", domain.ErrConflict, code, steps[step].Target)
+		case "ValidationError":
+			cause = fmt.Errorf("%w: %s", domain.ErrInvalidRequest, aws.ToString(reason.Message))
+		case "ProvisionedThroughputExceeded", "ThrottlingError":
+			cause = &domain.ThrottleError{Target: steps[step].Target, Err: fmt.Errorf("%s %s", code, aws.ToString(reason.Message))}
+		default:
+			cause = fmt.Errorf("dynamodb transaction cancelled: %s %s", code, aws.ToString(reason.Message))
+		}
+		return &domain.StepError{Step: step, Op: steps[step].Op, Err: cause}
+	}
+	return fmt.Errorf("dynamodb transaction cancelled: %w", err)
+}
@@ -0,0 +1,117 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/thegodeveloper/data-gateway/internal/domain"
+)
+
+func TestTransactMapsCancellationsToSteps(t *testing.T) {
+	steps := []domain.Mutation{
+		{Op: domain.MutationInsert, Target: "orders", Documents: []map[string]any{{"id": "o1"}, {"id": "o2"}}},
+		{Op: domain.MutationUpdate, Target: "stock", Key: map[string]any{"id": "s1"}, Set: map[string]any{"count": 9}},
+		{Op: domain.MutationDelete, Target: "carts", Key: map[string]any{"id": "c1"}},
+	}
+	cancelled := func(codes ...string) map[string]any {
+		reasons := make([]any, len(codes))
+		for i, code := range codes {
+			reasons[i] = map[string]any{"Code": code, "Message": code}
+		}
+		return map[string]any{
+			"__type":              "com.amazonaws.dynamodb.v20120810#TransactionCanceledException",
+			"message":             "Transaction cancelled",
+			"CancellationReasons": reasons,
+		}
+	}
+
+	tests := []struct {
+		name     string
+		response any
+		step     int   // failing step, -1 for none
+		is       error // what the step error wraps
+	}{
+		{"commits", map[string]any{}, -1, nil},
+		{"condition failed", cancelled("None", "None", "ConditionalCheckFailed", "None"), 1, domain.ErrConflict},
+		{"conflict", cancelled("None", "TransactionConflict", "None", "None"), 0, domain.ErrConflict},
+		{"invalid item", cancelled("None", "None", "None", "ValidationError"), 2, domain.ErrInvalidRequest},
+	}
+	for _, tt := range tests {
+		var writes []any
+		s := newFakeSource(func(op string, body map[string]any) any {
+			switch op {
+			case "DescribeTable":
+				return map[string]any{"Table": map[string]any{"KeySchema": []any{map[string]any{"AttributeName": "id", "KeyType": "HASH"}}}}
+			case "TransactWriteItems":
+				writes = append(writes, body["TransactItems"])
+				return tt.response
+			}
+			t.Fatalf("unexpected %s call", op)
+			return nil
+		})
+
+		res, err := s.Transact(context.Background(), domain.TransactionRequest{Source: "dynamo", Steps: steps})
+		var stepErr *domain.StepError
+		switch {
+		case tt.step < 0 && (err != nil || res.Steps[0].Affected != 2 || res.Steps[1].Affected != 1):
+			t.Errorf("%s: Transact = %+v, %v", tt.name, res, err)
+		case tt.step >= 0 && (!errors.As(err, &stepErr) || stepErr.Step != tt.step || !errors.Is(err, tt.is)):
+			t.Errorf("%s: error = %v, want step %d to fail with %v", tt.name, err, tt.step, tt.is)
+		}
+		if len(writes) != 1 || len(writes[0].([]any)) != 4 {
+			t.Errorf("%s: wrote %v, want one call with 4 items", tt.name, writes)
+		}
+	}
+}
+
+func TestTransactThrottledStep(t *testing.T) {
+	s := newFakeSource(func(op string, body map[string]any) any {
+		if op == "DescribeTable" {
+			return map[string]any{"Table": map[string]any{"KeySchema": []any{map[string]any{"AttributeName": "id", "KeyType": "HASH"}}}}
+		}
+		return map[string]any{
+			"__type":              "com.amazonaws.dynamodb.v20120810#TransactionCanceledException",
+			"CancellationReasons": []any{map[string]any{"Code": "ThrottlingError"}},
+		}
+	})
+	_, err := s.Transact(context.Background(), domain.TransactionRequest{Steps: []domain.Mutation{
+		{Op: domain.MutationDelete, Target: "carts", Key: map[string]any{"id": "c1"}},
+	}})
+	var throttled *domain.ThrottleError
+	if !errors.As(err, &throttled) || throttled.Target != "carts" {
+		t.Fatalf("error = %v, want a ThrottleError for carts", err)
+	}
+}
+
+func TestTransactRejectsBeforeWriting(t *testing.T) {
+	docs := make([]map[string]any, maxTransactItems+1)
+	for i := range docs {
+		docs[i] = map[string]any{"id": i}
+	}
+	tests := []struct {
+		name  string
+		steps []domain.Mutation
+		step  int // failing step, -1 for the whole transaction
+	}{
+		{"too many items", []domain.Mutation{{Op: domain.MutationInsert, Target: "orders", Documents: docs}}, -1},
+		{"returning", []domain.Mutation{
+			{Op: domain.MutationDelete, Target: "carts", Key: map[string]any{"id": "c1"}},
+			{Op: domain.MutationDelete, Target: "carts", Key: map[string]any{"id": "c2"}, Returning: []string{"id"}},
+		}, 1},
+		{"unknown op", []domain.Mutation{{Op: "merge", Target: "carts"}}, 0},
+	}
+	for _, tt := range tests {
+		s := newFakeSource(func(op string, body map[string]any) any {
+			if op != "DescribeTable" {
+				t.Fatalf("%s: unexpected %s call", tt.name, op)
+			}
+			return map[string]any{"Table": map[string]any{"KeySchema": []any{map[string]any{"AttributeName": "id", "KeyType": "HASH"}}}}
+		})
+		_, err := s.Transact(context.Background(), domain.TransactionRequest{Steps: tt.steps})
+		var stepErr *domain.StepError
+		if !errors.Is(err, domain.ErrInvalidRequest) || errors.As(err, &stepErr) != (tt.step >= 0) || (tt.step >= 0 && stepErr.Step != tt.step) {
+			t.Errorf("%s: error = %v, want an invalid request at step %d", tt.name, err, tt.step)
+		}
+	}
+}
@@ -0,0 +1,419 @@
+// Package dynamodb
+// internal/datasource/dynamodb/translate.go
+package dynamodb
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	sdynamodb "github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/thegodeveloper/data-gateway/internal/domain"
+)
+
+var comparisons = map[domain.Operator]string{
+	domain.OpEq:  "=",
+	domain.OpNe:  "<>",
+	domain.OpGt:  ">",
+	domain.OpGte: ">=",
+	domain.OpLt:  "<",
+	domain.OpLte: "<=",
+}
+
+// keySchema names a table's primary key attributes.
+type keySchema struct {
+	partition string
+	sort      string
+}
+
+// plan is a structured query translated for DynamoDB. When keyCondition is
+// empty the query runs as a Scan.
+type plan struct {
+	keyCondition string
+	filter       string
+	projection   string
+	forward      *bool
+	expr         *expression
+
+	// Request options, see readOptions. segment is set per parallel Scan
+	// worker.
+	index      string
+	consistent bool
+	segment    int32
+	segments   int32
+}
+
+// expression collects the attribute name and value placeholders shared by
+// every expression of one request.
+type expression struct {
+	names  map[string]string
+	values map[string]types.AttributeValue
+	byName map[string]string
+}
+
+func newExpression() *expression {
+	return &expression{
+		names:  make(map[string]string),
+		values: make(map[string]types.AttributeValue),
+		byName: make(map[string]string),
+	}
+}
+
+// name returns a placeholder path for a possibly nested attribute, so
+// reserved words such as "status" never appear in an expression.
+func (e *expression) name(field string) string {
+	parts := strings.Split(field, ".")
+	for i, part := range parts {
+		placeholder, ok := e.byName[part]
+		if !ok {
+			placeholder = fmt.Sprintf("#n%d", len(e.byName))
+			e.byName[part] = placeholder
+			e.names[placeholder] = part
+		}
+		parts[i] = placeholder
+	}
+	return strings.Join(parts, ".")
+}
+
+func (e *expression) value(v any) (string, error) {
+	av, err := attributevalue.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("%w: cannot marshal value %v: %v", domain.ErrInvalidRequest, v, err)
+	}
+	placeholder := fmt.Sprintf(":v%d", len(e.values))
+	e.values[placeholder] = av
+	return placeholder, nil
+}
+
+// translate splits the top-level AND of q.Where into a key condition (an
+// equality on the partition key plus at most one sort key condition) and a
+// filter for everything else. Without a partition key equality the query
+// becomes a filtered Scan.
+func translate(q domain.Query, keys keySchema) (*plan, error) {
+	p := &plan{expr: newExpression()}
+
+	var conjuncts []domain.Condition
+	if q.Where != nil {
+		conjuncts = flattenAnd(*q.Where)
+	}
+
+	var partition, sortLower, sortUpper, sortOther *domain.Condition
+	var rest []domain.Condition
+	for i := range conjuncts {
+		c := &conjuncts[i]
+		switch {
+		case c.Field == keys.partition && c.Op == domain.OpEq && partition == nil:
+			partition = c
+		case keys.sort != "" && c.Field == keys.sort && (c.Op == domain.OpGt || c.Op == domain.OpGte) && sortLower == nil:
+			sortLower = c
+		case keys.sort != "" && c.Field == keys.sort && (c.Op == domain.OpLt || c.Op == domain.OpLte) && sortUpper == nil:
+			sortUpper = c
+		case keys.sort != "" && c.Field == keys.sort && sortOther == nil && isKeyOperator(*c):
+			sortOther = c
+		default:
+			rest = append(rest, *c)
+		}
+	}
+
+	if partition != nil {
+		cond, err := p.keyConditions(*partition, sortLower, sortUpper, sortOther)
+		if err != nil {
+			return nil, err
+		}
+		p.keyCondition = cond
+	} else {
+		for _, c := range []*domain.Condition{sortLower, sortUpper, sortOther} {
+			if c != nil {
+				rest = append(rest, *c)
+			}
+		}
+	}
+
+	if len(rest) > 0 {
+		parts := make([]string, len(rest))
+		for i, c := range rest {
+			if p.keyCondition != "" && (c.Field == keys.partition || c.Field == keys.sort) {
+				return nil, fmt.Errorf("%w: dynamodb cannot filter on key attribute '%s' beyond the key condition", domain.ErrInvalidRequest, c.Field)
+			}
+			expr, err := p.condition(c)
+			if err != nil {
+				return nil, err
+			}
+			parts[i] = expr
+		}
+		p.filter = strings.Join(parts, " AND ")
+	}
+
+	if len(q.Select) > 0 {
+		names := make([]string, len(q.Select))
+		for i, field := range q.Select {
+			names[i] = p.expr.name(field)
+		}
+		p.projection = strings.Join(names, ", ")
+	}
+
+	switch {
+	case len(q.Sort) == 0:
+	case len(q.Sort) == 1 && p.keyCondition != "" && q.Sort[0].Field == keys.sort:
+		p.forward = aws.Bool(!q.Sort[0].Desc)
+	default:
+		return nil, fmt.Errorf("%w: dynamodb can only sort a key query by its sort key", domain.ErrInvalidRequest)
+	}
+
+	return p, nil
+}
+
+// keyConditions renders the partition key equality and the sort key
+// condition. A lower and an upper bound on the sort key become BETWEEN when
+// both are inclusive; any sort key condition that cannot join the key
+// condition is an error, since DynamoDB rejects key attributes in filters.
+func (p *plan) keyConditions(partition domain.Condition, lower, upper, other *domain.Condition) (string, error) {
+	pk, err := p.comparison(partition)
+	if err != nil {
+		return "", err
+	}
+	parts := []string{pk}
+
+	var sortCond *domain.Condition
+	switch {
+	case lower != nil && upper != nil:
+		if other != nil || lower.Op != domain.OpGte || upper.Op != domain.OpLte {
+			return "", fmt.Errorf("%w: dynamodb supports one sort key condition or an inclusive range", domain.ErrInvalidRequest)
+		}
+		lo, err := p.expr.value(lower.Value)
+		if err != nil {
+			return "", err
+		}
+		hi, err := p.expr.value(upper.Value)
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, fmt.Sprintf("%s BETWEEN %s AND %s", p.expr.name(lower.Field), lo, hi))
+	case lower != nil:
+		sortCond = lower
+	case upper != nil:
+		sortCond = upper
+	}
+	if sortCond != nil && other != nil {
+		return "", fmt.Errorf("%w: dynamodb supports one sort key condition or an inclusive range", domain.ErrInvalidRequest)
+	}
+	if sortCond == nil {
+		sortCond = other
+	}
+	if sortCond != nil {
+		expr, err := p.condition(*sortCond)
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, expr)
+	}
+
+	return strings.Join(parts, " AND "), nil
+}
+
+func (p *plan) condition(c domain.Condition) (string, error) {
+	if c.And != nil || c.Or != nil {
+		children, joiner := c.And, " AND "
+		if c.Or != nil {
+			children, joiner = c.Or, " OR "
+		}
+		parts := make([]string, len(children))
+		for i, child := range children {
+			expr, err := p.condition(child)
+			if err != nil {
+				return "", err
+			}
+			parts[i] = expr
+		}
+		return "(" + strings.Join(parts, joiner) + ")", nil
+	}
+
+	switch c.Op {
+	case domain.OpExists:
+		if c.Value.(bool) {
+			return fmt.Sprintf("attribute_exists(%s)", p.expr.name(c.Field)), nil
+		}
+		return fmt.Sprintf("attribute_not_exists(%s)", p.expr.name(c.Field)), nil
+	case domain.OpIn:
+		values := c.Value.([]any)
+		if len(values) == 0 || len(values) > 100 {
+			return "", fmt.Errorf("%w: dynamodb 'in' needs between 1 and 100 values", domain.ErrInvalidRequest)
+		}
+		placeholders := make([]string, len(values))
+		for i, v := range values {
+			placeholder, err := p.expr.value(v)
+			if err != nil {
+				return "", err
+			}
+			placeholders[i] = placeholder
+		}
+		return fmt.Sprintf("%s IN (%s)", p.expr.name(c.Field), strings.Join(placeholders, ", ")), nil
+	case domain.OpLike:
+		pattern := c.Value.(string)
+		inner := strings.Trim(pattern, "%")
+		if strings.ContainsAny(inner, "%_\\") {
+			return "", fmt.Errorf("%w: dynamodb only supports 'like' patterns of the form 'abc', 'abc%%' or '%%abc%%'", domain.ErrInvalidRequest)
+		}
+		placeholder, err := p.expr.value(inner)
+		if err != nil {
+			return "", err
+		}
+		name := p.expr.name(c.Field)
+		switch {
+		case strings.HasPrefix(pattern, "%") && strings.HasSuffix(pattern, "%") && len(pattern) > 1:
+			return fmt.Sprintf("contains(%s, %s)", name, placeholder), nil
+		case strings.HasPrefix(pattern, "%"):
+			return "", fmt.Errorf("%w: dynamodb cannot match a 'like' suffix", domain.ErrInvalidRequest)
+		case strings.HasSuffix(pattern, "%"):
+			return fmt.Sprintf("begins_with(%s, %s)", name, placeholder), nil
+		default:
+			return fmt.Sprintf("%s = %s", name, placeholder), nil
+		}
+	default:
+		return p.comparison(c)
+	}
+}
+
+func (p *plan) comparison(c domain.Condition) (string, error) {
+	op, ok := comparisons[c.Op]
+	if !ok {
+		return "", fmt.Errorf("%w: operator '%s' is not supported by dynamodb", domain.ErrInvalidRequest, c.Op)
+	}
+	placeholder, err := p.expr.value(c.Value)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s %s %s", p.expr.name(c.Field), op, placeholder), nil
+}
+
+// isKeyOperator reports whether c can be a sort key condition on its own.
+func isKeyOperator(c domain.Condition) bool {
+	switch c.Op {
+	case domain.OpEq:
+		return true
+	case domain.OpLike:
+		pattern := c.Value.(string)
+		return strings.HasSuffix(pattern, "%") && !strings.ContainsAny(strings.TrimSuffix(pattern, "%"), "%_\\")
+	}
+	return false
+}
+
+func flattenAnd(c domain.Condition) []domain.Condition {
+	if c.And == nil {
+		return []domain.Condition{c}
+	}
+	var out []domain.Condition
+	for _, child := range c.And {
+		out = append(out, flattenAnd(child)...)
+	}
+	return out
+}
+
+// structured runs a translated query, following pages until limit+offset
+// items have been collected, since DynamoDB applies Limit before filtering.
+// With a page size it returns one domain.Page instead. q.Target is the
+// physical table name.
+func (s *Source) structured(ctx context.Context, q domain.Query, params map[string]any) (any, error) {
+	p, keys, err := s.prepare(ctx, q, params)
+	if err != nil {
+		return nil, err
+	}
+	if q.PageSize > 0 {
+		return s.page(ctx, q, p)
+	}
+
+	want := q.Offset + q.Limit
+	var items []map[string]types.AttributeValue
+	for page, err := range s.pages(ctx, q, p, keys) {
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, page...)
+		if q.Limit > 0 && len(items) >= want {
+			break
+		}
+	}
+
+	if q.Offset >= len(items) {
+		items = nil
+	} else {
+		items = items[q.Offset:]
+	}
+	if q.Limit > 0 && len(items) > q.Limit {
+		items = items[:q.Limit]
+	}
+	return unmarshalItems(items)
+}
+
+// read runs one Query or Scan request for a plan, starting after startKey
+// and evaluating at most limit items when limit is set.
+func (s *Source) read(ctx context.Context, table string, p *plan, startKey map[string]types.AttributeValue, limit *int32) ([]map[string]types.AttributeValue, map[string]types.AttributeValue, error) {
+	var (
+		names  map[string]string
+		values map[string]types.AttributeValue
+	)
+	if len(p.expr.names) > 0 {
+		names = p.expr.names
+	}
+	if len(p.expr.values) > 0 {
+		values = p.expr.values
+	}
+
+	if p.keyCondition != "" {
+		out, err := s.client.Query(ctx, &sdynamodb.QueryInput{
+			TableName:                 aws.String(table),
+			KeyConditionExpression:    aws.String(p.keyCondition),
+			FilterExpression:          optional(p.filter),
+			ProjectionExpression:      optional(p.projection),
+			ScanIndexForward:          p.forward,
+			IndexName:                 optional(p.index),
+			ConsistentRead:            consistent(p.consistent),
+			ExpressionAttributeNames:  names,
+			ExpressionAttributeValues: values,
+			ExclusiveStartKey:         startKey,
+			Limit:                     limit,
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("dynamodb query failed: %w", err)
+		}
+		return out.Items, out.LastEvaluatedKey, nil
+	}
+
+	input := &sdynamodb.ScanInput{
+		TableName:                 aws.String(table),
+		FilterExpression:          optional(p.filter),
+		ProjectionExpression:      optional(p.projection),
+		IndexName:                 optional(p.index),
+		ConsistentRead:            consistent(p.consistent),
+		ExpressionAttributeNames:  names,
+		ExpressionAttributeValues: values,
+		ExclusiveStartKey:         startKey,
+		Limit:                     limit,
+	}
+	if p.segments > 1 {
+		input.Segment = aws.Int32(p.segment)
+		input.TotalSegments = aws.Int32(p.segments)
+	}
+	out, err := s.client.Scan(ctx, input)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to scan DynamoDB table '%s': %w", table, err)
+	}
+	return out.Items, out.LastEvaluatedKey, nil
+}
+
+func optional(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return aws.String(s)
+}
+
+func consistent(b bool) *bool {
+	if !b {
+		return nil
+	}
+	return aws.Bool(true)
+}
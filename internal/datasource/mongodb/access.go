@@ -0,0 +1,111 @@
+// Package mongodb
+// internal/datasource/mongodb/access.go
+package mongodb
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/thegodeveloper/data-gateway/internal/domain"
+)
+
+// queryAccess checks a structured query against the route's allow-list and
+// returns the columns its results are restricted to, nil for all.
+func queryAccess(req domain.QueryRequest) (map[string]bool, error) {
+	columns, ok := req.Access.Table(req.Query.Target)
+	if !ok {
+		return nil, fmt.Errorf("%w: collection '%s' is not exposed by this route", domain.ErrInvalidRequest, req.Query.Target)
+	}
+	return columns, domain.CheckQuery(req.Query, columns, req.Query.Target)
+}
+
+// checkFindFields checks the fields a params find reads against columns;
+// nil columns allow everything. Fields of the filter, sort and projection
+// must be allowed. Top-level filter operators other than $and, $or, $nor and
+// $comment are refused, since $expr, $text and the like can read any field,
+// and so are projection values other than inclusion flags, which could copy
+// a hidden field into an allowed one.
+func checkFindFields(filter map[string]any, params map[string]any, columns map[string]bool, collection string) error {
+	if columns == nil {
+		return nil
+	}
+	if err := filterFields(filter, columns, collection, "filter"); err != nil {
+		return err
+	}
+
+	switch p := params["projection"].(type) {
+	case map[string]any:
+		for field, v := range p {
+			if err := allowed(field, columns, collection); err != nil {
+				return err
+			}
+			switch v.(type) {
+			case float64, bool:
+			default:
+				return fmt.Errorf("%w: projection of '%s' must be 0 or 1 on collection '%s', whose fields this route restricts", domain.ErrForbidden, field, collection)
+			}
+		}
+	case []any:
+		for _, field := range p {
+			if name, ok := field.(string); ok {
+				if err := allowed(name, columns, collection); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	var sorts []any
+	switch s := params["sort"].(type) {
+	case map[string]any:
+		sorts = []any{s}
+	case []any:
+		sorts = s
+	}
+	for _, s := range sorts {
+		keys, _ := s.(map[string]any)
+		for field := range keys {
+			if err := allowed(field, columns, collection); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func filterFields(filter map[string]any, columns map[string]bool, collection, at string) error {
+	for key, v := range filter {
+		switch key {
+		case "$and", "$or", "$nor":
+			clauses, ok := v.([]any)
+			if !ok {
+				return fmt.Errorf("%w: %s.%s must be an array", domain.ErrInvalidRequest, at, key)
+			}
+			for i, clause := range clauses {
+				sub, ok := clause.(map[string]any)
+				if !ok {
+					return fmt.Errorf("%w: %s.%s[%d] must be an object", domain.ErrInvalidRequest, at, key, i)
+				}
+				if err := filterFields(sub, columns, collection, fmt.Sprintf("%s.%s[%d]", at, key, i)); err != nil {
+					return err
+				}
+			}
+		case "$comment":
+		default:
+			if strings.HasPrefix(key, "$") {
+				return fmt.Errorf("%w: %s: operator '%s' cannot be used on collection '%s', whose fields this route restricts", domain.ErrForbidden, at, key, collection)
+			}
+			if err := allowed(key, columns, collection); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func allowed(field string, columns map[string]bool, collection string) error {
+	if !columns[domain.TopLevel(field)] {
+		return fmt.Errorf("%w: unknown field '%s' on '%s'", domain.ErrInvalidRequest, field, collection)
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package mongodb
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/thegodeveloper/data-gateway/internal/domain"
+)
+
+var restricted = &domain.Access{Tables: map[string][]string{
+	"customers": {"_id", "name", "address"},
+	"orders":    {"*"},
+}}
+
+func TestQueryAccess(t *testing.T) {
+	allowed := []domain.Query{
+		{Target: "customers", Select: []string{"name", "address.city"}},
+		{Target: "customers", Where: &domain.Condition{Field: "name", Op: domain.OpEq, Value: "Ann"}, Sort: []domain.SortField{{Field: "_id"}}},
+		{Target: "orders", Where: &domain.Condition{Field: "anything", Op: domain.OpExists, Value: true}},
+	}
+	for _, q := range allowed {
+		if _, err := queryAccess(domain.QueryRequest{Query: &q, Access: restricted}); err != nil {
+			t.Errorf("queryAccess(%+v): %v", q, err)
+		}
+	}
+
+	refused := map[string]domain.Query{
+		"collection": {Target: "secrets"},
+		"select":     {Target: "customers", Select: []string{"ssn"}},
+		"where":      {Target: "customers", Where: &domain.Condition{Or: []domain.Condition{{Field: "name", Op: domain.OpEq, Value: "Ann"}, {Field: "ssn", Op: domain.OpExists, Value: true}}}},
+		"sort":       {Target: "customers", Sort: []domain.SortField{{Field: "ssn.last4"}}},
+	}
+	for name, q := range refused {
+		if _, err := queryAccess(domain.QueryRequest{Query: &q, Access: restricted}); !errors.Is(err, domain.ErrInvalidRequest) {
+			t.Errorf("%s: error = %v, want ErrInvalidRequest", name, err)
+		}
+	}
+}
+
+func TestCheckFindFields(t *testing.T) {
+	columns, _ := restricted.Table("customers")
+	cases := []struct {
+		name   string
+		filter map[string]any
+		params map[string]any
+		want   error
+	}{
+		{"allowed", map[string]any{"name": "Ann", "$or": []any{map[string]any{"address.city": "Oslo"}}}, map[string]any{"projection": map[string]any{"name": 1.0}, "sort": []any{map[string]any{"_id": -1.0}}}, nil},
+		{"hidden field", map[string]any{"ssn": map[string]any{"$exists": true}}, nil, domain.ErrInvalidRequest},
+		{"nested hidden field", map[string]any{"$and": []any{map[string]any{"$nor": []any{map[string]any{"ssn": "1"}}}}}, nil, domain.ErrInvalidRequest},
+		{"expr", map[string]any{"$expr": map[string]any{"$eq": []any{"$ssn", "1"}}}, nil, domain.ErrForbidden},
+		{"text", map[string]any{"$text": map[string]any{"$search": "x"}}, nil, domain.ErrForbidden},
+		{"projection of hidden field", map[string]any{}, map[string]any{"projection": []any{"ssn"}}, domain.ErrInvalidRequest},
+		{"computed projection", map[string]any{}, map[string]any{"projection": map[string]any{"name": "$ssn"}}, domain.ErrForbidden},
+		{"sort on hidden field", map[string]any{}, map[string]any{"sort": map[string]any{"ssn": 1.0}}, domain.ErrInvalidRequest},
+	}
+	for _, c := range cases {
+		err := checkFindFields(c.filter, c.params, columns, "customers")
+		if (c.want == nil) != (err == nil) || (c.want != nil && !errors.Is(err, c.want)) {
+			t.Errorf("%s: error = %v, want %v", c.name, err, c.want)
+		}
+	}
+	if err := checkFindFields(map[string]any{"$expr": true}, nil, nil, "orders"); err != nil {
+		t.Errorf("unrestricted collection: %v", err)
+	}
+}
+
+func TestPipelineAccess(t *testing.T) {
+	if err := exposed(restricted, "orders", "collection"); err != nil {
+		t.Errorf("exposed(orders): %v", err)
+	}
+	if err := exposed(restricted, "customers", "collection"); !errors.Is(err, domain.ErrForbidden) {
+		t.Errorf("exposed(customers) error = %v, want ErrForbidden", err)
+	}
+	if err := exposed(restricted, "secrets", "collection"); !errors.Is(err, domain.ErrInvalidRequest) {
+		t.Errorf("exposed(secrets) error = %v, want ErrInvalidRequest", err)
+	}
+}
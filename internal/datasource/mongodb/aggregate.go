@@ -0,0 +1,392 @@
+// Package mongodb
+// internal/datasource/mongodb/aggregate.go
+package mongodb
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-viper/mapstructure/v2"
+	"github.com/thegodeveloper/data-gateway/internal/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// readStages are the aggregation stages every caller may use. None of them
+// writes, and the ones that read other collections are checked against the
+// route's allow-list.
+var readStages = []string{
+	"$addFields", "$bucket", "$bucketAuto", "$count", "$facet", "$graphLookup",
+	"$group", "$limit", "$lookup", "$match", "$project", "$redact",
+	"$replaceRoot", "$replaceWith", "$sample", "$set", "$setWindowFields",
+	"$skip", "$sort", "$sortByCount", "$unionWith", "$unset", "$unwind",
+}
+
+// javaScriptOperators run server-side JavaScript wherever they appear.
+var javaScriptOperators = map[string]bool{"$function": true, "$accumulator": true, "$where": true}
+
+// Aggregation configures which pipelines an instance accepts.
+type Aggregation struct {
+	// Stages are permitted on top of the read-only default set, e.g.
+	// ["$out", "$merge"].
+	Stages []string `mapstructure:"stages"`
+	// JavaScript permits $function, $accumulator and $where.
+	JavaScript bool `mapstructure:"javascript"`
+}
+
+// pipelinePolicy is the compiled form of Aggregation.
+type pipelinePolicy struct {
+	stages     map[string]bool
+	javaScript bool
+	// filters checks $match stages like params filters.
+	filters filterPolicy
+}
+
+func newPipelinePolicy(cfg Aggregation) (pipelinePolicy, error) {
+	p := pipelinePolicy{stages: make(map[string]bool), javaScript: cfg.JavaScript}
+	for _, stage := range readStages {
+		p.stages[stage] = true
+	}
+	for _, stage := range cfg.Stages {
+		if !strings.HasPrefix(stage, "$") {
+			return p, fmt.Errorf("invalid aggregation stage '%s': stage names start with '$'", stage)
+		}
+		p.stages[stage] = true
+	}
+	return p, nil
+}
+
+// SetAggregation replaces the stages and operators pipelines may use.
+func (m *MongoSource) SetAggregation(cfg Aggregation) error {
+	policy, err := newPipelinePolicy(cfg)
+	if err != nil {
+		return err
+	}
+	policy.filters = m.pipelines.filters
+	m.pipelines = policy
+	return nil
+}
+
+// aggregateArgs resolves the collection, pipeline and options of a params
+// request with a "pipeline":
+//
+//	pipeline        array of stages
+//	allow_disk_use  let stages spill to disk
+//	max_time_ms     server-side time limit
+//	batch_size      documents per cursor batch
+//	collation       {"locale": ..., "strength": ...}
+//	hint            index name, or [{"field": 1}, ...]
+func (m *MongoSource) aggregateArgs(req domain.QueryRequest) (*mongo.Collection, mongo.Pipeline, *options.AggregateOptions, error) {
+	collectionName, ok := req.Params["collection"].(string)
+	if !ok {
+		return nil, nil, nil, fmt.Errorf("%w: missing 'collection' parameter", domain.ErrInvalidRequest)
+	}
+	if err := exposed(req.Access, collectionName, "collection"); err != nil {
+		return nil, nil, nil, err
+	}
+
+	stages, ok := req.Params["pipeline"].([]any)
+	if !ok {
+		return nil, nil, nil, fmt.Errorf("%w: 'pipeline' must be an array of stages", domain.ErrInvalidRequest)
+	}
+	converted, err := fromEJSON(stages)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	pipeline, err := m.pipelines.check(converted.([]any), "pipeline", req.Access)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	opts, err := aggregateOptions(req.Params)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	coll, err := m.collection(req.Params, collectionName)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return coll, pipeline, opts, nil
+}
+
+// check validates every stage against the policy and converts the pipeline
+// to its bson form. path names the position for error messages.
+func (p pipelinePolicy) check(stages []any, path string, access *domain.Access) (mongo.Pipeline, error) {
+	pipeline := make(mongo.Pipeline, 0, len(stages))
+	for i, raw := range stages {
+		at := fmt.Sprintf("%s[%d]", path, i)
+		stage, ok := raw.(map[string]any)
+		if !ok || len(stage) != 1 {
+			return nil, fmt.Errorf("%w: %s must be an object with exactly one stage", domain.ErrInvalidRequest, at)
+		}
+		for name, body := range stage {
+			if !p.stages[name] {
+				return nil, fmt.Errorf("%w: %s: stage '%s' is not allowed", domain.ErrForbidden, at, name)
+			}
+			if err := p.checkOperators(body, at+"."+name); err != nil {
+				return nil, err
+			}
+			value, err := p.stageBody(name, body, at+"."+name, access)
+			if err != nil {
+				return nil, err
+			}
+			pipeline = append(pipeline, bson.D{{Key: name, Value: value}})
+		}
+	}
+	return pipeline, nil
+}
+
+// stageBody converts the stages whose bodies need more than a plain
+// document: sub-pipelines are checked in turn, key order is kept where it
+// matters, and collections read or written are checked against access.
+func (p pipelinePolicy) stageBody(name string, body any, at string, access *domain.Access) (any, error) {
+	switch name {
+	case "$limit", "$skip":
+		n, ok := body.(float64)
+		if !ok || n != float64(int64(n)) || n < 0 {
+			return nil, fmt.Errorf("%w: %s must be a non-negative integer", domain.ErrInvalidRequest, at)
+		}
+		return int64(n), nil
+
+	case "$sort":
+		return ordered(body, at)
+
+	case "$match":
+		return body, p.filters.check(body, at)
+
+	case "$facet":
+		facets, ok := body.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s must be an object of pipelines", domain.ErrInvalidRequest, at)
+		}
+		out := bson.M{}
+		for facet, raw := range facets {
+			stages, ok := raw.([]any)
+			if !ok {
+				return nil, fmt.Errorf("%w: %s.%s must be an array of stages", domain.ErrInvalidRequest, at, facet)
+			}
+			sub, err := p.check(stages, at+"."+facet, access)
+			if err != nil {
+				return nil, err
+			}
+			out[facet] = sub
+		}
+		return out, nil
+
+	case "$lookup", "$graphLookup":
+		spec, ok := body.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s must be an object", domain.ErrInvalidRequest, at)
+		}
+		from, ok := spec["from"].(string)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s.from must be a collection name", domain.ErrInvalidRequest, at)
+		}
+		if err := exposed(access, from, at); err != nil {
+			return nil, err
+		}
+		// A $lookup pipeline joins on its "let" variables, which only
+		// $expr can compare, so $expr is allowed in its $match stages.
+		lookup := p
+		lookup.filters = p.filters.with("$expr")
+		return lookup.subPipeline(spec, at, access)
+
+	case "$unionWith":
+		if coll, ok := body.(string); ok {
+			return coll, exposed(access, coll, at)
+		}
+		spec, ok := body.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s must be a collection name or an object", domain.ErrInvalidRequest, at)
+		}
+		coll, ok := spec["coll"].(string)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s.coll must be a collection name", domain.ErrInvalidRequest, at)
+		}
+		if err := exposed(access, coll, at); err != nil {
+			return nil, err
+		}
+		return p.subPipeline(spec, at, access)
+
+	case "$out":
+		return body, writable(access, body, at)
+
+	case "$merge":
+		if spec, ok := body.(map[string]any); ok {
+			return body, writable(access, spec["into"], at+".into")
+		}
+		return body, writable(access, body, at)
+
+	default:
+		return body, nil
+	}
+}
+
+// subPipeline checks the "pipeline" member of a $lookup or $unionWith.
+func (p pipelinePolicy) subPipeline(spec map[string]any, at string, access *domain.Access) (any, error) {
+	raw, ok := spec["pipeline"]
+	if !ok {
+		return spec, nil
+	}
+	stages, ok := raw.([]any)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s.pipeline must be an array of stages", domain.ErrInvalidRequest, at)
+	}
+	sub, err := p.check(stages, at+".pipeline", access)
+	if err != nil {
+		return nil, err
+	}
+	out := make(bson.M, len(spec))
+	for k, v := range spec {
+		out[k] = v
+	}
+	out["pipeline"] = sub
+	return out, nil
+}
+
+// checkOperators rejects JavaScript operators anywhere below a stage unless
+// the policy permits them.
+func (p pipelinePolicy) checkOperators(v any, at string) error {
+	if p.javaScript {
+		return nil
+	}
+	switch v := v.(type) {
+	case map[string]any:
+		for k, item := range v {
+			if javaScriptOperators[k] {
+				return fmt.Errorf("%w: %s: operator '%s' is not allowed", domain.ErrForbidden, at, k)
+			}
+			if err := p.checkOperators(item, at+"."+k); err != nil {
+				return err
+			}
+		}
+	case []any:
+		for i, item := range v {
+			if err := p.checkOperators(item, fmt.Sprintf("%s[%d]", at, i)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// writable checks the target of an $out or $merge, given as a collection
+// name or as {"db": ..., "coll": ...}. Pipelines write to the database they
+// read from, whose collections the route exposes, so a target naming a
+// database is refused even when it names the same one.
+func writable(access *domain.Access, target any, at string) error {
+	var coll string
+	switch v := target.(type) {
+	case string:
+		coll = v
+	case map[string]any:
+		if _, ok := v["db"]; ok {
+			return fmt.Errorf("%w: %s: writing to a named database is not allowed", domain.ErrForbidden, at)
+		}
+		coll, _ = v["coll"].(string)
+	}
+	if coll == "" {
+		return fmt.Errorf("%w: %s must be a collection name or {\"coll\": ...}", domain.ErrInvalidRequest, at)
+	}
+	return exposed(access, coll, at)
+}
+
+// exposed checks that a pipeline may read or write collection. Stages can
+// compute any field from any other, so a collection whose fields the route
+// restricts cannot take part in a pipeline at all.
+func exposed(access *domain.Access, collection, at string) error {
+	columns, ok := access.Table(collection)
+	if !ok {
+		return fmt.Errorf("%w: %s: collection '%s' is not exposed by this route", domain.ErrInvalidRequest, at, collection)
+	}
+	if columns != nil {
+		return fmt.Errorf("%w: %s: collection '%s' cannot be aggregated, since this route restricts its fields", domain.ErrForbidden, at, collection)
+	}
+	return nil
+}
+
+// ordered converts a key specification whose order matters, such as a sort
+// or an index hint. Decoded JSON objects do not keep their key order, so
+// more than one key has to be given as a list of single-key objects.
+func ordered(v any, at string) (bson.D, error) {
+	var items []any
+	switch v := v.(type) {
+	case map[string]any:
+		if len(v) > 1 {
+			return nil, fmt.Errorf("%w: %s: give several keys as a list of single-key objects, e.g. [{\"a\": 1}, {\"b\": -1}], so their order is kept", domain.ErrInvalidRequest, at)
+		}
+		items = []any{v}
+	case []any:
+		items = v
+	default:
+		return nil, fmt.Errorf("%w: %s must be an object or a list of single-key objects", domain.ErrInvalidRequest, at)
+	}
+
+	spec := make(bson.D, 0, len(items))
+	for _, item := range items {
+		key, ok := item.(map[string]any)
+		if !ok || len(key) != 1 {
+			return nil, fmt.Errorf("%w: %s must be a list of single-key objects", domain.ErrInvalidRequest, at)
+		}
+		for field, dir := range key {
+			if n, ok := dir.(float64); ok {
+				dir = int32(n)
+			}
+			spec = append(spec, bson.E{Key: field, Value: dir})
+		}
+	}
+	return spec, nil
+}
+
+func aggregateOptions(params map[string]any) (*options.AggregateOptions, error) {
+	opts := options.Aggregate()
+	if v, ok := params["allow_disk_use"]; ok {
+		b, ok := v.(bool)
+		if !ok {
+			return nil, fmt.Errorf("%w: 'allow_disk_use' must be a boolean", domain.ErrInvalidRequest)
+		}
+		opts.SetAllowDiskUse(b)
+	}
+	if d, ok, err := maxTime(params); err != nil {
+		return nil, err
+	} else if ok {
+		opts.SetMaxTime(d)
+	}
+	if n, ok, err := integer(params, "batch_size", 1); err != nil {
+		return nil, err
+	} else if ok {
+		opts.SetBatchSize(int32(min(n, 1<<31-1)))
+	}
+	if v, ok := params["collation"]; ok {
+		collation, err := decodeCollation(v)
+		if err != nil {
+			return nil, err
+		}
+		opts.SetCollation(collation)
+	}
+	if v, ok := params["hint"]; ok {
+		hint, err := indexHint(v)
+		if err != nil {
+			return nil, err
+		}
+		opts.SetHint(hint)
+	}
+	return opts, nil
+}
+
+// decodeCollation reads a collation document with MongoDB's field names.
+func decodeCollation(v any) (*options.Collation, error) {
+	var collation options.Collation
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{Result: &collation, ErrorUnused: true})
+	if err != nil {
+		return nil, err
+	}
+	if err := decoder.Decode(v); err != nil {
+		return nil, fmt.Errorf("%w: invalid 'collation': %v", domain.ErrInvalidRequest, err)
+	}
+	if collation.Locale == "" {
+		return nil, fmt.Errorf("%w: 'collation' needs a 'locale'", domain.ErrInvalidRequest)
+	}
+	return &collation, nil
+}
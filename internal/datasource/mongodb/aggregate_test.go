@@ -0,0 +1,115 @@
+package mongodb
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/thegodeveloper/data-gateway/internal/domain"
+)
+
+func testPipelines(t *testing.T) pipelinePolicy {
+	t.Helper()
+	p, err := newPipelinePolicy(Aggregation{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.filters, err = newFilterPolicy(Filters{}); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestPipelineLookup(t *testing.T) {
+	p := testPipelines(t)
+	ok := map[string][]any{
+		"local/foreign": {map[string]any{"$lookup": map[string]any{
+			"from": "customers", "localField": "customer_id", "foreignField": "_id", "as": "customer",
+		}}},
+		"let/pipeline with $expr": {map[string]any{"$lookup": map[string]any{
+			"from": "customers",
+			"let":  map[string]any{"id": "$customer_id"},
+			"pipeline": []any{
+				map[string]any{"$match": map[string]any{"$expr": map[string]any{
+					"$and": []any{
+						map[string]any{"$eq": []any{"$_id", "$$id"}},
+						map[string]any{"$gt": []any{map[string]any{"$add": []any{"$credit", 1.0}}, 0.0}},
+					},
+				}}},
+			},
+			"as": "customer",
+		}}},
+	}
+	for name, stages := range ok {
+		if _, err := p.check(stages, "pipeline", nil); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+
+	rejected := map[string]struct {
+		stages []any
+		want   error
+	}{
+		"non-string from": {[]any{map[string]any{"$lookup": map[string]any{
+			"from": map[string]any{"db": "admin", "coll": "system.users"}, "localField": "a", "foreignField": "b", "as": "c",
+		}}}, domain.ErrInvalidRequest},
+		"missing from": {[]any{map[string]any{"$graphLookup": map[string]any{
+			"startWith": "$a", "connectFromField": "a", "connectToField": "b", "as": "c",
+		}}}, domain.ErrInvalidRequest},
+		"non-string coll": {[]any{map[string]any{"$unionWith": map[string]any{"coll": 1.0}}}, domain.ErrInvalidRequest},
+		"$expr outside a lookup": {[]any{map[string]any{"$match": map[string]any{
+			"$expr": map[string]any{"$eq": []any{"$a", "$b"}},
+		}}}, domain.ErrInvalidRequest},
+		"$expr in a $unionWith pipeline": {[]any{map[string]any{"$unionWith": map[string]any{
+			"coll":     "customers",
+			"pipeline": []any{map[string]any{"$match": map[string]any{"$expr": true}}},
+		}}}, domain.ErrInvalidRequest},
+		"$where in a lookup": {[]any{map[string]any{"$lookup": map[string]any{
+			"from":     "customers",
+			"pipeline": []any{map[string]any{"$match": map[string]any{"$where": "sleep(100)"}}},
+			"as":       "c",
+		}}}, domain.ErrForbidden},
+		"$function in a lookup $expr": {[]any{map[string]any{"$lookup": map[string]any{
+			"from": "customers",
+			"pipeline": []any{map[string]any{"$match": map[string]any{"$expr": map[string]any{
+				"$function": map[string]any{"body": "function() { return true }", "args": []any{}, "lang": "js"},
+			}}}},
+			"as": "c",
+		}}}, domain.ErrForbidden},
+	}
+	for name, c := range rejected {
+		if _, err := p.check(c.stages, "pipeline", nil); !errors.Is(err, c.want) {
+			t.Errorf("%s: error = %v, want %v", name, err, c.want)
+		}
+	}
+}
+
+func TestPipelineOutputTargets(t *testing.T) {
+	p := testPipelines(t)
+	p.stages["$out"], p.stages["$merge"] = true, true
+	access := &domain.Access{Tables: map[string][]string{"orders": {"*"}, "reports": {"*"}}}
+
+	tests := []struct {
+		name  string
+		stage map[string]any
+		want  error // nil when allowed
+	}{
+		{"$out name", map[string]any{"$out": "reports"}, nil},
+		{"$out object", map[string]any{"$out": map[string]any{"coll": "reports"}}, nil},
+		{"$merge name", map[string]any{"$merge": "reports"}, nil},
+		{"$merge into name", map[string]any{"$merge": map[string]any{"into": "reports", "on": "_id"}}, nil},
+		{"$merge into object", map[string]any{"$merge": map[string]any{"into": map[string]any{"coll": "reports"}}}, nil},
+		{"$out to another database", map[string]any{"$out": map[string]any{"db": "other", "coll": "reports"}}, domain.ErrForbidden},
+		{"$merge into another database", map[string]any{"$merge": map[string]any{"into": map[string]any{"db": "other", "coll": "x"}}}, domain.ErrForbidden},
+		{"$merge into a named database", map[string]any{"$merge": map[string]any{"into": map[string]any{"db": "shop", "coll": "reports"}}}, domain.ErrForbidden},
+		{"$out to an unexposed collection", map[string]any{"$out": "secrets"}, domain.ErrInvalidRequest},
+		{"$merge into an unexposed collection", map[string]any{"$merge": map[string]any{"into": map[string]any{"coll": "secrets"}}}, domain.ErrInvalidRequest},
+		{"$out without a collection", map[string]any{"$out": map[string]any{"timeseries": map[string]any{}}}, domain.ErrInvalidRequest},
+		{"$merge without into", map[string]any{"$merge": map[string]any{"on": "_id"}}, domain.ErrInvalidRequest},
+	}
+	for _, tt := range tests {
+		_, err := p.check([]any{tt.stage}, "pipeline", access)
+		if tt.want == nil && err != nil || tt.want != nil && !errors.Is(err, tt.want) {
+			t.Errorf("%s: error = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+// Package mongodb
+// internal/datasource/mongodb/describe.go
+package mongodb
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/thegodeveloper/data-gateway/internal/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Sample sizes of Describe.
+const (
+	defaultSample = 100
+	maxSample     = 1000
+)
+
+// Describe infers the top-level fields of a collection from a random sample
+// of its documents. A field whose values differ in type is reported as
+// JSON, except that integers widen to bigint and numbers to float. Fields
+// missing from the sample are not reported.
+func (m *MongoSource) Describe(ctx context.Context, req domain.DescribeRequest) (*domain.TableSchema, error) {
+	size := req.Sample
+	if size <= 0 {
+		size = defaultSample
+	}
+	if size > maxSample {
+		return nil, fmt.Errorf("%w: sample of %d exceeds the maximum of %d", domain.ErrInvalidRequest, size, maxSample)
+	}
+	coll, err := m.collection(req.Params, req.Target)
+	if err != nil {
+		return nil, err
+	}
+	cursor, err := coll.Aggregate(ctx, mongo.Pipeline{{{Key: "$sample", Value: bson.D{{Key: "size", Value: size}}}}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to sample collection '%s': %w", req.Target, err)
+	}
+	docs, err := collect(ctx, cursor)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sample collection '%s': %w", req.Target, err)
+	}
+
+	types := make(map[string]domain.FieldType)
+	for _, doc := range docs {
+		for name, v := range doc {
+			typ, ok := bsonType(v)
+			if !ok {
+				continue
+			}
+			if seen, ok := types[name]; ok {
+				typ = widen(seen, typ)
+			}
+			types[name] = typ
+		}
+	}
+	names := make([]string, 0, len(types))
+	for name := range types {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	schema := &domain.TableSchema{Fields: make([]domain.Field, 0, len(names)), Key: []string{"_id"}}
+	for _, name := range names {
+		schema.Fields = append(schema.Fields, domain.Field{Name: name, Type: types[name]})
+	}
+	return schema, nil
+}
+
+// bsonType is the type a decoded value has once output; ok is false for
+// null.
+func bsonType(v any) (domain.FieldType, bool) {
+	switch v.(type) {
+	case nil, primitive.Null, primitive.Undefined:
+		return "", false
+	case string, primitive.ObjectID, primitive.Symbol:
+		return domain.FieldString, true
+	case int32:
+		return domain.FieldInt, true
+	case int64:
+		return domain.FieldBigInt, true
+	case float64:
+		return domain.FieldFloat, true
+	case primitive.Decimal128:
+		return domain.FieldDecimal, true
+	case bool:
+		return domain.FieldBool, true
+	case primitive.DateTime:
+		return domain.FieldTime, true
+	}
+	return domain.FieldJSON, true
+}
+
+func widen(a, b domain.FieldType) domain.FieldType {
+	if a == b {
+		return a
+	}
+	rank := map[domain.FieldType]int{domain.FieldInt: 1, domain.FieldBigInt: 2, domain.FieldFloat: 3}
+	if rank[a] > 0 && rank[b] > 0 {
+		if rank[a] > rank[b] {
+			return a
+		}
+		return b
+	}
+	return domain.FieldJSON
+}
@@ -0,0 +1,223 @@
+// Package mongodb
+// internal/datasource/mongodb/ejson.go
+package mongodb
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/thegodeveloper/data-gateway/internal/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ejsonTypes are the keys of Extended JSON v2 type wrappers. Query
+// operators such as $regex or $type are deliberately absent: they keep their
+// query meaning.
+var ejsonTypes = map[string]bool{
+	"$oid": true, "$date": true, "$numberDecimal": true, "$numberLong": true,
+	"$numberInt": true, "$numberDouble": true, "$binary": true, "$uuid": true,
+	"$regularExpression": true, "$timestamp": true, "$minKey": true,
+	"$maxKey": true, "$symbol": true, "$code": true, "$dbPointer": true,
+	"$undefined": true,
+}
+
+// fromEJSON replaces the Extended JSON type wrappers in a decoded request
+// value, canonical or relaxed, with the bson values they stand for, e.g.
+// {"$oid": "..."} with an ObjectID and {"$date": "..."} with a DateTime.
+// Other objects and arrays keep their shape.
+func fromEJSON(v any) (any, error) {
+	switch v := v.(type) {
+	case map[string]any:
+		if isEJSONWrapper(v) {
+			return ejsonValue(v)
+		}
+		out := make(map[string]any, len(v))
+		for k, item := range v {
+			converted, err := fromEJSON(item)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = converted
+		}
+		return out, nil
+	case []any:
+		out := make([]any, len(v))
+		for i, item := range v {
+			converted, err := fromEJSON(item)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = converted
+		}
+		return out, nil
+	default:
+		return v, nil
+	}
+}
+
+// documentFromEJSON is fromEJSON for a whole document.
+func documentFromEJSON(doc map[string]any) (map[string]any, error) {
+	if doc == nil {
+		return nil, nil
+	}
+	v, err := fromEJSON(doc)
+	if err != nil {
+		return nil, err
+	}
+	return v.(map[string]any), nil
+}
+
+// mutationFromEJSON converts the documents and update values of a write.
+// Conditions are converted with the rest of a filter by condition.
+func mutationFromEJSON(mut domain.Mutation) (domain.Mutation, error) {
+	var err error
+	if mut.Documents != nil {
+		docs := make([]map[string]any, len(mut.Documents))
+		for i, doc := range mut.Documents {
+			if docs[i], err = documentFromEJSON(doc); err != nil {
+				return mut, err
+			}
+		}
+		mut.Documents = docs
+	}
+	if mut.Set, err = documentFromEJSON(mut.Set); err != nil {
+		return mut, err
+	}
+	if mut.Inc, err = documentFromEJSON(mut.Inc); err != nil {
+		return mut, err
+	}
+	return mut, nil
+}
+
+func isEJSONWrapper(v map[string]any) bool {
+	if len(v) == 0 || len(v) > 2 {
+		return false
+	}
+	for k := range v {
+		if ejsonTypes[k] {
+			return true
+		}
+	}
+	return false
+}
+
+func ejsonValue(wrapper map[string]any) (any, error) {
+	data, err := json.Marshal(map[string]any{"v": wrapper})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", domain.ErrInvalidRequest, err)
+	}
+	var holder struct {
+		V any `bson:"v"`
+	}
+	if err := bson.UnmarshalExtJSON(data, false, &holder); err != nil {
+		return nil, fmt.Errorf("%w: invalid extended JSON value: %v", domain.ErrInvalidRequest, err)
+	}
+	return holder.V, nil
+}
+
+// Output modes for result documents.
+const (
+	// plainOutput is ordinary JSON: ObjectIDs as hex strings, dates as
+	// RFC 3339 strings, decimals as strings and binary data as base64.
+	plainOutput = ""
+	// relaxedOutput is relaxed Extended JSON v2.
+	relaxedOutput = "relaxed"
+	// canonicalOutput is canonical Extended JSON v2, which keeps every type.
+	canonicalOutput = "canonical"
+)
+
+// SetOutput selects how result documents are encoded: "" or "plain",
+// "relaxed" or "canonical".
+func (m *MongoSource) SetOutput(mode string) error {
+	switch mode {
+	case plainOutput, "plain":
+		m.outputMode = plainOutput
+	case relaxedOutput, canonicalOutput:
+		m.outputMode = mode
+	default:
+		return fmt.Errorf("invalid output mode '%s': use plain, relaxed or canonical", mode)
+	}
+	return nil
+}
+
+// output converts a decoded document for the response in the instance's
+// output mode.
+func (m *MongoSource) output(doc map[string]any) (map[string]any, error) {
+	if m.outputMode == plainOutput {
+		return plain(doc).(map[string]any), nil
+	}
+	data, err := bson.MarshalExtJSON(doc, m.outputMode == canonicalOutput, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode result: %w", err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	// Numbers stay json.Number so 64-bit integers keep every digit.
+	dec.UseNumber()
+	var out map[string]any
+	if err := dec.Decode(&out); err != nil {
+		return nil, fmt.Errorf("failed to encode result: %w", err)
+	}
+	return out, nil
+}
+
+// outputAll applies output to every document in place.
+func (m *MongoSource) outputAll(docs []map[string]any) error {
+	for i, doc := range docs {
+		out, err := m.output(doc)
+		if err != nil {
+			return err
+		}
+		docs[i] = out
+	}
+	return nil
+}
+
+// plain converts bson values to their plain JSON form.
+func plain(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, item := range v {
+			v[k] = plain(item)
+		}
+		return v
+	case primitive.D:
+		out := make(map[string]any, len(v))
+		for _, e := range v {
+			out[e.Key] = plain(e.Value)
+		}
+		return out
+	case primitive.A:
+		for i, item := range v {
+			v[i] = plain(item)
+		}
+		return []any(v)
+	case primitive.ObjectID:
+		return v.Hex()
+	case primitive.DateTime:
+		return v.Time().UTC().Format(time.RFC3339Nano)
+	case primitive.Decimal128:
+		return v.String()
+	case primitive.Binary:
+		return base64.StdEncoding.EncodeToString(v.Data)
+	case primitive.Timestamp:
+		return map[string]any{"t": v.T, "i": v.I}
+	case primitive.Regex:
+		return "/" + v.Pattern + "/" + v.Options
+	case primitive.JavaScript:
+		return string(v)
+	case primitive.Symbol:
+		return string(v)
+	case primitive.CodeWithScope:
+		return string(v.Code)
+	case primitive.DBPointer:
+		return map[string]any{"$ref": v.DB, "$id": v.Pointer.Hex()}
+	case primitive.MinKey, primitive.MaxKey, primitive.Undefined, primitive.Null:
+		return nil
+	default:
+		return v
+	}
+}
@@ -0,0 +1,174 @@
+package mongodb
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/thegodeveloper/data-gateway/internal/domain"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestFromEJSON(t *testing.T) {
+	id, _ := primitive.ObjectIDFromHex("65f1a2b3c4d5e6f708192a3b")
+	at := primitive.NewDateTimeFromTime(time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC))
+	price, _ := primitive.ParseDecimal128("19.99")
+	tests := []struct {
+		name string
+		in   any
+		want any
+	}{
+		{"object id", map[string]any{"$oid": "65f1a2b3c4d5e6f708192a3b"}, id},
+		{"relaxed date", map[string]any{"$date": "2024-05-01T12:00:00Z"}, at},
+		{"canonical date", map[string]any{"$date": map[string]any{"$numberLong": "1714564800000"}}, at},
+		{"decimal", map[string]any{"$numberDecimal": "19.99"}, price},
+		{"long", map[string]any{"$numberLong": "9007199254740993"}, int64(9007199254740993)},
+		{"int", map[string]any{"$numberInt": "7"}, int32(7)},
+		{"double", map[string]any{"$numberDouble": "1.5"}, 1.5},
+		{"binary", map[string]any{"$binary": map[string]any{"base64": "3q0=", "subType": "00"}}, primitive.Binary{Data: []byte{0xde, 0xad}}},
+		{"regular expression", map[string]any{"$regularExpression": map[string]any{"pattern": "^a", "options": "i"}}, primitive.Regex{Pattern: "^a", Options: "i"}},
+		{"timestamp", map[string]any{"$timestamp": map[string]any{"t": 1.0, "i": 2.0}}, primitive.Timestamp{T: 1, I: 2}},
+		{"nested", map[string]any{"order": map[string]any{"_id": map[string]any{"$oid": "65f1a2b3c4d5e6f708192a3b"}, "at": []any{map[string]any{"$date": "2024-05-01T12:00:00Z"}}}},
+			map[string]any{"order": map[string]any{"_id": id, "at": []any{at}}}},
+		{"query operators kept", map[string]any{"$regex": "^a", "$options": "i"}, map[string]any{"$regex": "^a", "$options": "i"}},
+		{"wider objects kept", map[string]any{"$oid": "x", "a": 1.0, "b": 2.0}, map[string]any{"$oid": "x", "a": 1.0, "b": 2.0}},
+		{"plain values kept", []any{"a", 1.0, true, nil}, []any{"a", 1.0, true, nil}},
+	}
+	for _, tt := range tests {
+		got, err := fromEJSON(tt.in)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: fromEJSON = %#v, want %#v", tt.name, got, tt.want)
+		}
+	}
+
+	invalid := []any{
+		map[string]any{"$oid": "not hex"},
+		map[string]any{"$date": "yesterday"},
+		map[string]any{"$numberLong": 7.0},
+		map[string]any{"a": []any{map[string]any{"$numberDecimal": "x"}}},
+	}
+	for _, in := range invalid {
+		if got, err := fromEJSON(in); !errors.Is(err, domain.ErrInvalidRequest) {
+			t.Errorf("fromEJSON(%v) = %#v, %v; want ErrInvalidRequest", in, got, err)
+		}
+	}
+}
+
+// TestEJSONRoundTrip sends result documents out in each Extended JSON mode
+// and back in as request values, as a client echoing them would.
+func TestEJSONRoundTrip(t *testing.T) {
+	id, _ := primitive.ObjectIDFromHex("65f1a2b3c4d5e6f708192a3b")
+	at := primitive.NewDateTimeFromTime(time.Date(2024, 5, 1, 12, 0, 0, 123000000, time.UTC))
+	price, _ := primitive.ParseDecimal128("19.99")
+	// Relaxed Extended JSON writes numbers as plain JSON numbers, so only
+	// the types it wraps survive it.
+	relaxed := map[string]any{
+		"_id":   id,
+		"at":    at,
+		"price": price,
+		"blob":  primitive.Binary{Subtype: 4, Data: []byte("0123456789abcdef")},
+		"tags":  []any{"a", map[string]any{"seen": at}},
+		"name":  "Ann",
+	}
+	canonical := map[string]any{
+		"_id":      id,
+		"at":       at,
+		"price":    price,
+		"count":    int32(3),
+		"big":      int64(9007199254740993),
+		"ratio":    0.5,
+		"ts":       primitive.Timestamp{T: 1714564800, I: 1},
+		"pattern":  primitive.Regex{Pattern: "^a", Options: "i"},
+		"nested":   map[string]any{"_id": id, "n": int64(1)},
+		"archived": false,
+	}
+	tests := []struct {
+		mode string
+		doc  map[string]any
+	}{
+		{relaxedOutput, relaxed},
+		{canonicalOutput, canonical},
+	}
+	for _, tt := range tests {
+		m := &MongoSource{}
+		if err := m.SetOutput(tt.mode); err != nil {
+			t.Fatal(err)
+		}
+		out, err := m.output(tt.doc)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.mode, err)
+		}
+		body, err := json.Marshal(out)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.mode, err)
+		}
+		var echoed map[string]any
+		if err := json.Unmarshal(body, &echoed); err != nil {
+			t.Fatalf("%s: %v", tt.mode, err)
+		}
+		back, err := documentFromEJSON(echoed)
+		if err != nil {
+			t.Fatalf("%s: %s: %v", tt.mode, body, err)
+		}
+		if !reflect.DeepEqual(back, tt.doc) {
+			t.Errorf("%s: %s came back as %#v, want %#v", tt.mode, body, back, tt.doc)
+		}
+	}
+}
+
+func TestPlainOutput(t *testing.T) {
+	id, _ := primitive.ObjectIDFromHex("65f1a2b3c4d5e6f708192a3b")
+	price, _ := primitive.ParseDecimal128("19.99")
+	doc := map[string]any{
+		"_id":    id,
+		"at":     primitive.NewDateTimeFromTime(time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)),
+		"price":  price,
+		"blob":   primitive.Binary{Data: []byte{0xde, 0xad}},
+		"ts":     primitive.Timestamp{T: 1, I: 2},
+		"re":     primitive.Regex{Pattern: "^a", Options: "i"},
+		"nested": primitive.D{{Key: "ref", Value: id}},
+		"list":   primitive.A{id, primitive.Null{}},
+		"n":      int64(7),
+	}
+	want := map[string]any{
+		"_id":    "65f1a2b3c4d5e6f708192a3b",
+		"at":     "2024-05-01T12:00:00Z",
+		"price":  "19.99",
+		"blob":   "3q0=",
+		"ts":     map[string]any{"t": uint32(1), "i": uint32(2)},
+		"re":     "/^a/i",
+		"nested": map[string]any{"ref": "65f1a2b3c4d5e6f708192a3b"},
+		"list":   []any{"65f1a2b3c4d5e6f708192a3b", nil},
+		"n":      int64(7),
+	}
+	got, err := (&MongoSource{}).output(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("output = %#v, want %#v", got, want)
+	}
+}
+
+func TestMutationFromEJSON(t *testing.T) {
+	id, _ := primitive.ObjectIDFromHex("65f1a2b3c4d5e6f708192a3b")
+	oid := map[string]any{"$oid": "65f1a2b3c4d5e6f708192a3b"}
+	mut, err := mutationFromEJSON(domain.Mutation{
+		Op:        domain.MutationUpsert,
+		Documents: []map[string]any{{"_id": oid}},
+		Set:       map[string]any{"owner": oid},
+		Inc:       map[string]any{"n": map[string]any{"$numberLong": "2"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mut.Documents[0]["_id"] != id || mut.Set["owner"] != id || mut.Inc["n"] != int64(2) {
+		t.Fatalf("mutation = %+v", mut)
+	}
+}
@@ -0,0 +1,253 @@
+// Package mongodb
+// internal/datasource/mongodb/filter.go
+package mongodb
+
+import (
+	"fmt"
+	"regexp/syntax"
+	"sort"
+	"strings"
+
+	"github.com/thegodeveloper/data-gateway/internal/domain"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// queryOperators are the filter operators every caller may use. $where,
+// $expr and $jsonSchema are left out: the first runs JavaScript and the
+// others evaluate per document, so none of them can use an index.
+var queryOperators = []string{
+	"$eq", "$ne", "$gt", "$gte", "$lt", "$lte", "$in", "$nin",
+	"$and", "$or", "$nor", "$not",
+	"$exists", "$type", "$regex", "$options",
+	"$all", "$elemMatch", "$size", "$mod",
+	"$bitsAllClear", "$bitsAllSet", "$bitsAnyClear", "$bitsAnySet",
+	"$geoWithin", "$geoIntersects", "$near", "$nearSphere", "$geometry",
+	"$maxDistance", "$minDistance", "$box", "$center", "$centerSphere", "$polygon",
+	"$text", "$search", "$language", "$caseSensitive", "$diacriticSensitive",
+	"$comment",
+}
+
+// Filters bounds the filters requests may send. Zero values take the
+// defaults.
+type Filters struct {
+	// Operators are permitted on top of the default query operators, e.g.
+	// ["$expr"].
+	Operators []string `mapstructure:"operators"`
+	// MaxDepth bounds how deeply objects and arrays nest. Defaults to 10.
+	MaxDepth int `mapstructure:"max_depth"`
+	// MaxIn bounds the values of one $in, $nin or $all. Defaults to 1000.
+	MaxIn int `mapstructure:"max_in"`
+	// MaxRegex bounds the length of a pattern. Defaults to 256.
+	MaxRegex int `mapstructure:"max_regex"`
+	// UnanchoredRegex permits patterns that do not start with '^', which
+	// cannot use an index and scan the whole collection.
+	UnanchoredRegex bool `mapstructure:"unanchored_regex"`
+}
+
+// filterPolicy is the compiled form of Filters.
+type filterPolicy struct {
+	operators  map[string]bool
+	maxDepth   int
+	maxIn      int
+	maxRegex   int
+	unanchored bool
+}
+
+func newFilterPolicy(cfg Filters) (filterPolicy, error) {
+	if cfg.MaxDepth < 0 || cfg.MaxIn < 0 || cfg.MaxRegex < 0 {
+		return filterPolicy{}, fmt.Errorf("invalid filter limits: values cannot be negative")
+	}
+	f := filterPolicy{
+		operators:  make(map[string]bool),
+		maxDepth:   cfg.MaxDepth,
+		maxIn:      cfg.MaxIn,
+		maxRegex:   cfg.MaxRegex,
+		unanchored: cfg.UnanchoredRegex,
+	}
+	if f.maxDepth == 0 {
+		f.maxDepth = 10
+	}
+	if f.maxIn == 0 {
+		f.maxIn = 1000
+	}
+	if f.maxRegex == 0 {
+		f.maxRegex = 256
+	}
+	for _, op := range queryOperators {
+		f.operators[op] = true
+	}
+	for _, op := range cfg.Operators {
+		if !strings.HasPrefix(op, "$") {
+			return f, fmt.Errorf("invalid filter operator '%s': operator names start with '$'", op)
+		}
+		f.operators[op] = true
+	}
+	return f, nil
+}
+
+// SetFilters replaces the operators and bounds filters are checked against.
+// They apply to params filters and to $match stages.
+func (m *MongoSource) SetFilters(cfg Filters) error {
+	policy, err := newFilterPolicy(cfg)
+	if err != nil {
+		return err
+	}
+	m.pipelines.filters = policy
+	return nil
+}
+
+// with returns a copy of the policy that also permits op.
+func (f filterPolicy) with(op string) filterPolicy {
+	operators := make(map[string]bool, len(f.operators)+1)
+	for k, v := range f.operators {
+		operators[k] = v
+	}
+	operators[op] = true
+	f.operators = operators
+	return f
+}
+
+// check walks a filter and rejects the first operator, list, pattern or
+// nesting level outside the policy, naming its path, e.g.
+// "filter.$or[1].name.$regex".
+func (f filterPolicy) check(v any, at string) error {
+	return f.walk(v, at, 0)
+}
+
+func (f filterPolicy) walk(v any, at string, depth int) error {
+	switch v := v.(type) {
+	case primitive.M:
+		// Filters translated from structured queries.
+		return f.walk(map[string]any(v), at, depth)
+	case primitive.A:
+		return f.walk([]any(v), at, depth)
+	case map[string]any:
+		if depth >= f.maxDepth {
+			return fmt.Errorf("%w: %s: filter nests deeper than %d levels", domain.ErrInvalidRequest, at, f.maxDepth)
+		}
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			path := at + "." + k
+			if strings.HasPrefix(k, "$") {
+				if err := f.operator(k, v[k], path); err != nil {
+					return err
+				}
+			}
+			if k == "$expr" {
+				// Aggregation expressions have operators of their own
+				// ($add, $dateDiff, ...); only their nesting is bounded.
+				if err := f.nesting(v[k], path, depth+1); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := f.walk(v[k], path, depth+1); err != nil {
+				return err
+			}
+		}
+	case []any:
+		if depth >= f.maxDepth {
+			return fmt.Errorf("%w: %s: filter nests deeper than %d levels", domain.ErrInvalidRequest, at, f.maxDepth)
+		}
+		for i, item := range v {
+			if err := f.walk(item, fmt.Sprintf("%s[%d]", at, i), depth+1); err != nil {
+				return err
+			}
+		}
+	case primitive.Regex:
+		return f.regex(v.Pattern, at)
+	}
+	return nil
+}
+
+// nesting bounds how deeply v nests without checking its keys.
+func (f filterPolicy) nesting(v any, at string, depth int) error {
+	var items []any
+	switch v := v.(type) {
+	case map[string]any:
+		for _, item := range v {
+			items = append(items, item)
+		}
+	case []any:
+		items = v
+	default:
+		return nil
+	}
+	if depth >= f.maxDepth {
+		return fmt.Errorf("%w: %s: filter nests deeper than %d levels", domain.ErrInvalidRequest, at, f.maxDepth)
+	}
+	for _, item := range items {
+		if err := f.nesting(item, at, depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// operator checks one operator and the arguments its bounds apply to.
+func (f filterPolicy) operator(op string, arg any, at string) error {
+	if !f.operators[op] {
+		return fmt.Errorf("%w: %s: operator '%s' is not allowed", domain.ErrInvalidRequest, at, op)
+	}
+	switch op {
+	case "$in", "$nin", "$all":
+		if a, ok := arg.(primitive.A); ok {
+			arg = []any(a)
+		}
+		if list, ok := arg.([]any); ok && len(list) > f.maxIn {
+			return fmt.Errorf("%w: %s: %d values exceed the maximum of %d", domain.ErrInvalidRequest, at, len(list), f.maxIn)
+		}
+	case "$regex":
+		if pattern, ok := arg.(string); ok {
+			return f.regex(pattern, at)
+		}
+	}
+	return nil
+}
+
+// regex bounds a pattern's length, requires a '^' anchor unless the policy
+// permits scans, and rejects nested quantifiers such as (a+)+, which
+// backtrack exponentially.
+func (f filterPolicy) regex(pattern, at string) error {
+	if len(pattern) > f.maxRegex {
+		return fmt.Errorf("%w: %s: pattern is longer than %d characters", domain.ErrInvalidRequest, at, f.maxRegex)
+	}
+	if !f.unanchored && (!strings.HasPrefix(pattern, "^") || strings.HasPrefix(pattern, "^.*") || strings.HasPrefix(pattern, "^.+")) {
+		return fmt.Errorf("%w: %s: pattern must start with '^' and a literal prefix so it can use an index", domain.ErrInvalidRequest, at)
+	}
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return fmt.Errorf("%w: %s: pattern cannot be checked: %v", domain.ErrInvalidRequest, at, err)
+	}
+	if nestedRepeat(re, false) {
+		return fmt.Errorf("%w: %s: pattern repeats a group that itself repeats", domain.ErrInvalidRequest, at)
+	}
+	return nil
+}
+
+func nestedRepeat(re *syntax.Regexp, inRepeat bool) bool {
+	switch re.Op {
+	case syntax.OpStar, syntax.OpPlus:
+		if inRepeat {
+			return true
+		}
+		inRepeat = true
+	case syntax.OpRepeat:
+		if re.Max != 1 {
+			if inRepeat {
+				return true
+			}
+			inRepeat = true
+		}
+	}
+	for _, sub := range re.Sub {
+		if nestedRepeat(sub, inRepeat) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,54 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/thegodeveloper/data-gateway/internal/domain"
+)
+
+func TestStructuredQueriesAreBounded(t *testing.T) {
+	m := NewMongoSource(nil)
+	if err := m.SetFilters(Filters{MaxIn: 3, MaxRegex: 16}); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string]*domain.Condition{
+		"$in over max_in":     {Field: "status", Op: domain.OpIn, Value: []any{"a", "b", "c", "d"}},
+		"nested $in":          {Or: []domain.Condition{{Field: "a", Op: domain.OpEq, Value: 1.0}, {Field: "status", Op: domain.OpIn, Value: []any{1.0, 2.0, 3.0, 4.0}}}},
+		"like over max_regex": {Field: "name", Op: domain.OpLike, Value: strings.Repeat("a", 20)},
+		"unanchored like":     {Field: "name", Op: domain.OpLike, Value: "%a%b%c%"},
+	}
+	for name, where := range cases {
+		q := domain.Query{Target: "orders", Where: where}
+		if _, _, _, _, err := m.findArgs(domain.QueryRequest{Query: &q}); !errors.Is(err, domain.ErrInvalidRequest) {
+			t.Errorf("%s: find error = %v, want ErrInvalidRequest", name, err)
+		}
+		q.PageSize = 10
+		if _, err := m.page(context.Background(), nil, q, nil); !errors.Is(err, domain.ErrInvalidRequest) {
+			t.Errorf("%s: page error = %v, want ErrInvalidRequest", name, err)
+		}
+	}
+}
+
+func TestFilterPolicyRegex(t *testing.T) {
+	f, err := newFilterPolicy(Filters{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for pattern, ok := range map[string]bool{
+		"^abc":     true,
+		"^ab.*c$":  true,
+		"abc":      false,
+		"^.*abc":   false,
+		"^(a+)+$":  false,
+		"^a{2,}b+": true,
+	} {
+		err := f.check(map[string]any{"name": map[string]any{"$regex": pattern}}, "filter")
+		if (err == nil) != ok {
+			t.Errorf("pattern %q: check = %v, want ok %v", pattern, err, ok)
+		}
+	}
+}
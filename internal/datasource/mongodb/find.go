@@ -0,0 +1,192 @@
+// Package mongodb
+// internal/datasource/mongodb/find.go
+package mongodb
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/thegodeveloper/data-gateway/internal/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readconcern"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+)
+
+// Limit bounds how many documents a find returns from one collection. Zero
+// values leave the bound off.
+type Limit struct {
+	// Default applies when a request sets no limit.
+	Default int64 `mapstructure:"default"`
+	// Max is the largest limit a request may ask for.
+	Max int64 `mapstructure:"max"`
+}
+
+// anyCollection keys the limit of collections without one of their own.
+const anyCollection = "*"
+
+// SetLimits replaces the per-collection find limits.
+func (m *MongoSource) SetLimits(limits map[string]Limit) error {
+	for coll, l := range limits {
+		if l.Default < 0 || l.Max < 0 {
+			return fmt.Errorf("invalid limit for collection '%s': values cannot be negative", coll)
+		}
+		if l.Max > 0 && l.Default > l.Max {
+			return fmt.Errorf("invalid limit for collection '%s': default %d exceeds max %d", coll, l.Default, l.Max)
+		}
+	}
+	m.limits = limits
+	return nil
+}
+
+// limit returns the limit a find on coll runs with. Without a requested
+// limit that is the default, or the maximum when only that is configured.
+func (m *MongoSource) limit(coll string, requested int64) (int64, error) {
+	l, ok := m.limits[coll]
+	if !ok {
+		l = m.limits[anyCollection]
+	}
+	if requested == 0 {
+		if l.Default > 0 {
+			return l.Default, nil
+		}
+		return l.Max, nil
+	}
+	if l.Max > 0 && requested > l.Max {
+		return 0, fmt.Errorf("%w: limit %d exceeds the maximum of %d for collection '%s'", domain.ErrInvalidRequest, requested, l.Max, coll)
+	}
+	return requested, nil
+}
+
+// readConcerns are the levels read_concern accepts.
+var readConcerns = map[string]bool{
+	"local": true, "available": true, "majority": true, "linearizable": true, "snapshot": true,
+}
+
+// collection returns the named collection of the request's database with
+// the read_preference and read_concern params applied, e.g.
+// "secondaryPreferred" for reporting routes.
+func (m *MongoSource) collection(params map[string]any, name string) (*mongo.Collection, error) {
+	dbName, err := m.databaseName(params)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := options.Collection()
+	if v, ok := params["read_preference"]; ok {
+		s, _ := v.(string)
+		mode, err := readpref.ModeFromString(s)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid 'read_preference' %v", domain.ErrInvalidRequest, v)
+		}
+		rp, err := readpref.New(mode)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid 'read_preference': %v", domain.ErrInvalidRequest, err)
+		}
+		opts.SetReadPreference(rp)
+	}
+	if v, ok := params["read_concern"]; ok {
+		level, _ := v.(string)
+		if !readConcerns[level] {
+			return nil, fmt.Errorf("%w: invalid 'read_concern' %v", domain.ErrInvalidRequest, v)
+		}
+		opts.SetReadConcern(&readconcern.ReadConcern{Level: level})
+	}
+	return m.client.Database(dbName).Collection(name, opts), nil
+}
+
+// findOptions reads the find params of a filter request:
+//
+//	projection   {"field": 1, ...} or ["field", ...]
+//	sort         {"field": -1} or [{"field": -1}, {"other": 1}]
+//	skip, limit  non-negative integers
+//
+// plus the cursor params that structured queries take as well.
+func findOptions(params map[string]any) (*options.FindOptions, error) {
+	opts := options.Find()
+	if v, ok := params["projection"]; ok {
+		switch p := v.(type) {
+		case map[string]any:
+			opts.SetProjection(bson.M(p))
+		case []any:
+			projection := bson.D{}
+			for _, field := range p {
+				name, ok := field.(string)
+				if !ok {
+					return nil, fmt.Errorf("%w: 'projection' must list field names", domain.ErrInvalidRequest)
+				}
+				projection = append(projection, bson.E{Key: name, Value: 1})
+			}
+			opts.SetProjection(projection)
+		default:
+			return nil, fmt.Errorf("%w: 'projection' must be an object or a list of field names", domain.ErrInvalidRequest)
+		}
+	}
+	if v, ok := params["sort"]; ok {
+		sort, err := ordered(v, "sort")
+		if err != nil {
+			return nil, err
+		}
+		opts.SetSort(sort)
+	}
+	if n, ok, err := integer(params, "skip", 0); err != nil {
+		return nil, err
+	} else if ok {
+		opts.SetSkip(n)
+	}
+	if n, ok, err := integer(params, "limit", 0); err != nil {
+		return nil, err
+	} else if ok {
+		opts.SetLimit(n)
+	}
+	return opts, cursorOptions(params, opts)
+}
+
+// cursorOptions applies batch_size, hint and max_time_ms to a find.
+func cursorOptions(params map[string]any, opts *options.FindOptions) error {
+	if n, ok, err := integer(params, "batch_size", 1); err != nil {
+		return err
+	} else if ok {
+		opts.SetBatchSize(int32(min(n, 1<<31-1)))
+	}
+	if v, ok := params["hint"]; ok {
+		hint, err := indexHint(v)
+		if err != nil {
+			return err
+		}
+		opts.SetHint(hint)
+	}
+	if d, ok, err := maxTime(params); err != nil {
+		return err
+	} else if ok {
+		opts.SetMaxTime(d)
+	}
+	return nil
+}
+
+// indexHint is an index name or an ordered key specification.
+func indexHint(v any) (any, error) {
+	if name, ok := v.(string); ok {
+		return name, nil
+	}
+	return ordered(v, "hint")
+}
+
+func maxTime(params map[string]any) (time.Duration, bool, error) {
+	ms, ok, err := integer(params, "max_time_ms", 1)
+	return time.Duration(ms) * time.Millisecond, ok, err
+}
+
+// integer reads a whole-number param of at least least.
+func integer(params map[string]any, key string, least int64) (int64, bool, error) {
+	v, ok := params[key]
+	if !ok {
+		return 0, false, nil
+	}
+	n, ok := v.(float64)
+	if !ok || n != float64(int64(n)) || int64(n) < least {
+		return 0, false, fmt.Errorf("%w: '%s' must be an integer of at least %d", domain.ErrInvalidRequest, key, least)
+	}
+	return int64(n), true, nil
+}
@@ -4,41 +4,260 @@ package mongodb
 
 import (
 	"context"
-	"errors"
+	"fmt"
+
 	"github.com/thegodeveloper/data-gateway/internal/domain"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type MongoSource struct {
 	client *mongo.Client
+	// database is used when a request does not name one.
+	database string
+	// pipelines decides which aggregation stages and filter operators
+	// requests may use.
+	pipelines pipelinePolicy
+	// limits bound finds per collection.
+	limits map[string]Limit
+	// outputMode encodes result documents: plain JSON or Extended JSON.
+	outputMode string
 }
 
 func NewMongoSource(client *mongo.Client) *MongoSource {
-	return &MongoSource{client: client}
+	pipelines, _ := newPipelinePolicy(Aggregation{})
+	pipelines.filters, _ = newFilterPolicy(Filters{})
+	return &MongoSource{client: client, pipelines: pipelines}
+}
+
+// Close disconnects the underlying client.
+func (m *MongoSource) Close(ctx context.Context) error {
+	return m.client.Disconnect(ctx)
+}
+
+// NewClient connects to the deployment at uri and checks it is reachable.
+func NewClient(ctx context.Context, uri string) (*mongo.Client, error) {
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to MongoDB: %w", err)
+	}
+	if err := client.Ping(ctx, nil); err != nil {
+		client.Disconnect(ctx)
+		return nil, fmt.Errorf("failed to ping MongoDB: %w", err)
+	}
+	return client, nil
 }
 
+// Query runs a structured query, an aggregation when params.pipeline is set,
+// or a find of params.filter.
 func (m *MongoSource) Query(ctx context.Context, req domain.QueryRequest) (any, error) {
-	dbName, ok := req.Params["database"].(string)
-	if !ok {
-		return nil, errors.New("missing 'database' parameter")
+	if isAggregate(req) {
+		coll, pipeline, opts, err := m.aggregateArgs(req)
+		if err != nil {
+			return nil, err
+		}
+		cursor, err := coll.Aggregate(ctx, pipeline, opts)
+		if err != nil {
+			return nil, err
+		}
+		docs, err := collect(ctx, cursor)
+		if err != nil {
+			return nil, err
+		}
+		return docs, m.outputAll(docs)
+	}
+	if req.Query != nil && req.Query.PageSize > 0 {
+		columns, err := queryAccess(req)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := m.limit(req.Query.Target, int64(req.Query.PageSize)); err != nil {
+			return nil, err
+		}
+		coll, err := m.collection(req.Params, req.Query.Target)
+		if err != nil {
+			return nil, err
+		}
+		page, err := m.page(ctx, coll, *req.Query, req.Params)
+		if err != nil {
+			return nil, err
+		}
+		for _, doc := range page.Items {
+			domain.Restrict(doc, columns)
+		}
+		return page, nil
 	}
-	collectionName, ok := req.Params["collection"].(string)
-	if !ok {
-		return nil, errors.New("missing 'collection' parameter")
+
+	coll, filter, opts, columns, err := m.findArgs(req)
+	if err != nil {
+		return nil, err
 	}
+	docs, err := m.find(ctx, coll, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	for _, doc := range docs {
+		domain.Restrict(doc, columns)
+	}
+	return docs, m.outputAll(docs)
+}
 
-	filterRaw, ok := req.Params["filter"].(map[string]interface{})
-	if !ok {
-		return nil, errors.New("missing or invalid 'filter' parameter")
+// Stream runs the same finds and aggregations as Query, decoding one
+// document per step. Stopping the iteration, or cancelling ctx, closes the
+// cursor.
+func (m *MongoSource) Stream(ctx context.Context, req domain.QueryRequest) (domain.RowStream, error) {
+	if isAggregate(req) {
+		coll, pipeline, opts, err := m.aggregateArgs(req)
+		if err != nil {
+			return nil, err
+		}
+		return m.streamCursor(ctx, nil, func() (*mongo.Cursor, error) {
+			return coll.Aggregate(ctx, pipeline, opts)
+		}), nil
 	}
-	filter := bson.M(filterRaw)
 
-	coll := m.client.Database(dbName).Collection(collectionName)
-	cursor, err := coll.Find(ctx, filter)
+	coll, filter, opts, columns, err := m.findArgs(req)
 	if err != nil {
 		return nil, err
 	}
+	return m.streamCursor(ctx, columns, func() (*mongo.Cursor, error) {
+		return coll.Find(ctx, filter, opts)
+	}), nil
+}
+
+// isAggregate reports whether a params request asks for an aggregation.
+func isAggregate(req domain.QueryRequest) bool {
+	_, ok := req.Params["pipeline"]
+	return ok && req.Query == nil
+}
+
+// streamCursor opens the cursor on the first step, so an abandoned stream
+// never holds one. Fields outside columns are dropped from every document.
+func (m *MongoSource) streamCursor(ctx context.Context, columns map[string]bool, open func() (*mongo.Cursor, error)) domain.RowStream {
+	return func(yield func(map[string]any, error) bool) {
+		cursor, err := open()
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		defer cursor.Close(context.WithoutCancel(ctx))
+		for cursor.Next(ctx) {
+			var doc map[string]interface{}
+			if err := cursor.Decode(&doc); err != nil {
+				yield(nil, err)
+				return
+			}
+			domain.Restrict(doc, columns)
+			doc, err := m.output(doc)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			if !yield(doc, nil) {
+				return
+			}
+		}
+		if err := cursor.Err(); err != nil {
+			yield(nil, err)
+		}
+	}
+}
+
+// findArgs resolves the collection, filter and options of a structured or
+// params request, and applies the collection's limits. It checks the fields
+// the request uses against the route's allow-list and returns the columns
+// results are restricted to, nil for all.
+func (m *MongoSource) findArgs(req domain.QueryRequest) (*mongo.Collection, bson.M, *options.FindOptions, map[string]bool, error) {
+	var (
+		collectionName string
+		filter         bson.M
+		opts           *options.FindOptions
+		columns        map[string]bool
+		err            error
+	)
+	if req.Query != nil {
+		collectionName = req.Query.Target
+		if columns, err = queryAccess(req); err != nil {
+			return nil, nil, nil, nil, err
+		}
+		if filter, opts, err = translate(*req.Query); err != nil {
+			return nil, nil, nil, nil, err
+		}
+		if err := m.pipelines.filters.check(filter, "where"); err != nil {
+			return nil, nil, nil, nil, err
+		}
+		if err := cursorOptions(req.Params, opts); err != nil {
+			return nil, nil, nil, nil, err
+		}
+	} else {
+		var ok bool
+		if collectionName, ok = req.Params["collection"].(string); !ok {
+			return nil, nil, nil, nil, fmt.Errorf("%w: missing 'collection' parameter", domain.ErrInvalidRequest)
+		}
+		if columns, ok = req.Access.Table(collectionName); !ok {
+			return nil, nil, nil, nil, fmt.Errorf("%w: collection '%s' is not exposed by this route", domain.ErrInvalidRequest, collectionName)
+		}
+		filterRaw, ok := req.Params["filter"].(map[string]interface{})
+		if !ok {
+			return nil, nil, nil, nil, fmt.Errorf("%w: missing or invalid 'filter' parameter", domain.ErrInvalidRequest)
+		}
+		converted, err := documentFromEJSON(filterRaw)
+		if err != nil {
+			return nil, nil, nil, nil, err
+		}
+		if err := m.pipelines.filters.check(converted, "filter"); err != nil {
+			return nil, nil, nil, nil, err
+		}
+		if err := checkFindFields(converted, req.Params, columns, collectionName); err != nil {
+			return nil, nil, nil, nil, err
+		}
+		filter = bson.M(converted)
+		if opts, err = findOptions(req.Params); err != nil {
+			return nil, nil, nil, nil, err
+		}
+	}
+
+	var requested int64
+	if opts.Limit != nil {
+		requested = *opts.Limit
+	}
+	limit, err := m.limit(collectionName, requested)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	if limit > 0 {
+		opts.SetLimit(limit)
+	}
+
+	coll, err := m.collection(req.Params, collectionName)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	return coll, filter, opts, columns, nil
+}
+
+// databaseName returns params.database, or the instance default.
+func (m *MongoSource) databaseName(params map[string]any) (string, error) {
+	if dbName, ok := params["database"].(string); ok {
+		return dbName, nil
+	}
+	if m.database == "" {
+		return "", fmt.Errorf("%w: missing 'database' parameter", domain.ErrInvalidRequest)
+	}
+	return m.database, nil
+}
+
+func (m *MongoSource) find(ctx context.Context, coll *mongo.Collection, filter bson.M, opts ...*options.FindOptions) ([]map[string]interface{}, error) {
+	cursor, err := coll.Find(ctx, filter, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return collect(ctx, cursor)
+}
+
+// collect decodes every document of cursor and closes it.
+func collect(ctx context.Context, cursor *mongo.Cursor) ([]map[string]interface{}, error) {
 	defer cursor.Close(ctx)
 
 	var results []map[string]interface{}
@@ -0,0 +1,182 @@
+// Package mongodb
+// internal/datasource/mongodb/mutate.go
+package mongodb
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/thegodeveloper/data-gateway/internal/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Mutate runs a write as InsertMany, UpdateMany, DeleteMany or, for
+// upserts, a bulk of upserting UpdateOne calls keyed by on_conflict.
+func (m *MongoSource) Mutate(ctx context.Context, req domain.MutationRequest) (*domain.MutationResult, error) {
+	dbName, err := m.databaseName(req.Params)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := req.Access.Table(req.Mutation.Target); !ok {
+		return nil, fmt.Errorf("%w: collection '%s' is not exposed by this route", domain.ErrInvalidRequest, req.Mutation.Target)
+	}
+	res, err := mutate(ctx, m.client.Database(dbName).Collection(req.Mutation.Target), req.Mutation)
+	if err != nil {
+		return nil, err
+	}
+	return res, m.outputAll(res.Rows)
+}
+
+func mutate(ctx context.Context, coll *mongo.Collection, mut domain.Mutation) (*domain.MutationResult, error) {
+	if len(mut.Returning) > 0 {
+		return nil, fmt.Errorf("%w: mongodb writes do not support 'returning'", domain.ErrInvalidRequest)
+	}
+	mut, err := mutationFromEJSON(mut)
+	if err != nil {
+		return nil, err
+	}
+	res := &domain.MutationResult{Op: mut.Op}
+
+	switch mut.Op {
+	case domain.MutationInsert:
+		if mut.Condition != nil {
+			return nil, fmt.Errorf("%w: mongodb inserts do not take a 'condition'", domain.ErrInvalidRequest)
+		}
+		docs := make([]any, len(mut.Documents))
+		for i, doc := range mut.Documents {
+			docs[i] = bson.M(doc)
+		}
+		r, err := coll.InsertMany(ctx, docs)
+		if err != nil {
+			return nil, writeError(err)
+		}
+		res.Affected = int64(len(r.InsertedIDs))
+		for _, id := range r.InsertedIDs {
+			res.Rows = append(res.Rows, map[string]any{"_id": id})
+		}
+
+	case domain.MutationUpsert:
+		models, err := upserts(mut)
+		if err != nil {
+			return nil, err
+		}
+		r, err := coll.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(true))
+		if err != nil {
+			return nil, writeError(err)
+		}
+		res.Affected = r.MatchedCount + r.UpsertedCount
+		indexes := make([]int64, 0, len(r.UpsertedIDs))
+		for i := range r.UpsertedIDs {
+			indexes = append(indexes, i)
+		}
+		sort.Slice(indexes, func(a, b int) bool { return indexes[a] < indexes[b] })
+		for _, i := range indexes {
+			res.Rows = append(res.Rows, map[string]any{"_id": r.UpsertedIDs[i]})
+		}
+
+	case domain.MutationUpdate:
+		filter, err := mutationFilter(mut)
+		if err != nil {
+			return nil, err
+		}
+		update := bson.M{}
+		if len(mut.Set) > 0 {
+			update["$set"] = bson.M(mut.Set)
+		}
+		if len(mut.Inc) > 0 {
+			update["$inc"] = bson.M(mut.Inc)
+		}
+		if len(mut.Unset) > 0 {
+			unset := bson.M{}
+			for _, field := range mut.Unset {
+				unset[field] = ""
+			}
+			update["$unset"] = unset
+		}
+		r, err := coll.UpdateMany(ctx, filter, update)
+		if err != nil {
+			return nil, writeError(err)
+		}
+		res.Affected = r.MatchedCount
+
+	case domain.MutationDelete:
+		filter, err := mutationFilter(mut)
+		if err != nil {
+			return nil, err
+		}
+		r, err := coll.DeleteMany(ctx, filter)
+		if err != nil {
+			return nil, writeError(err)
+		}
+		res.Affected = r.DeletedCount
+
+	default:
+		return nil, fmt.Errorf("%w: unknown mutation op '%s'", domain.ErrInvalidRequest, mut.Op)
+	}
+	return res, nil
+}
+
+func mutationFilter(mut domain.Mutation) (bson.M, error) {
+	c := mut.Filter()
+	if c == nil {
+		return bson.M{}, nil
+	}
+	return condition(*c)
+}
+
+// upserts builds one upserting UpdateOne per document, matching the
+// on_conflict fields with $eq so document values are never read as
+// operators. A document that fails Condition on an existing match falls
+// through to an insert and surfaces as a duplicate key conflict.
+func upserts(mut domain.Mutation) ([]mongo.WriteModel, error) {
+	if len(mut.OnConflict) == 0 {
+		return nil, fmt.Errorf("%w: mongodb upsert needs 'on_conflict' fields", domain.ErrInvalidRequest)
+	}
+	var cond bson.M
+	if mut.Condition != nil {
+		var err error
+		if cond, err = condition(*mut.Condition); err != nil {
+			return nil, err
+		}
+	}
+
+	models := make([]mongo.WriteModel, len(mut.Documents))
+	for i, doc := range mut.Documents {
+		filter := bson.M{}
+		keys := bson.M{}
+		set := bson.M{}
+		for field, v := range doc {
+			set[field] = v
+		}
+		for _, field := range mut.OnConflict {
+			v, ok := doc[field]
+			if !ok {
+				return nil, fmt.Errorf("%w: document %d is missing on_conflict field '%s'", domain.ErrInvalidRequest, i, field)
+			}
+			filter[field] = bson.M{"$eq": v}
+			keys[field] = v
+			delete(set, field)
+		}
+		if cond != nil {
+			filter = bson.M{"$and": bson.A{filter, cond}}
+		}
+
+		update := bson.M{"$set": set}
+		if len(set) == 0 {
+			// Nothing to change on a match; an update needs some operator.
+			update = bson.M{"$setOnInsert": keys}
+		}
+		models[i] = mongo.NewUpdateOneModel().SetFilter(filter).SetUpdate(update).SetUpsert(true)
+	}
+	return models, nil
+}
+
+func writeError(err error) error {
+	if mongo.IsDuplicateKeyError(err) {
+		return fmt.Errorf("%w: %v", domain.ErrConflict, err)
+	}
+	return err
+}
@@ -0,0 +1,160 @@
+// Package mongodb
+// internal/datasource/mongodb/paginate.go
+package mongodb
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/thegodeveloper/data-gateway/internal/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// cursor is a page position: the last document's values of the sort fields
+// followed by _id. It is BSON so ObjectIDs and dates keep their types.
+type cursor struct {
+	Values []any `bson:"v"`
+}
+
+// page reads one page of a structured query sorted by the requested fields
+// and then _id, seeking past the previous page's last document.
+func (m *MongoSource) page(ctx context.Context, coll *mongo.Collection, q domain.Query, params map[string]any) (*domain.Page, error) {
+	filter, opts, err := translate(q)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.pipelines.filters.check(filter, "where"); err != nil {
+		return nil, err
+	}
+	if err := cursorOptions(params, opts); err != nil {
+		return nil, err
+	}
+
+	order := append([]domain.SortField(nil), q.Sort...)
+	hasID := false
+	for _, s := range order {
+		hasID = hasID || s.Field == "_id"
+	}
+	if !hasID {
+		order = append(order, domain.SortField{Field: "_id"})
+	}
+
+	sort := bson.D{}
+	for _, s := range order {
+		dir := 1
+		if s.Desc {
+			dir = -1
+		}
+		sort = append(sort, bson.E{Key: s.Field, Value: dir})
+	}
+	opts.SetSort(sort).SetLimit(int64(q.PageSize) + 1)
+
+	// Sort fields the caller did not select are read for the cursor and
+	// dropped from the results. _id is always returned.
+	var hidden []string
+	if len(q.Select) > 0 {
+		selected := make(map[string]bool, len(q.Select))
+		projection := bson.D{}
+		for _, field := range q.Select {
+			selected[field] = true
+			projection = append(projection, bson.E{Key: field, Value: 1})
+		}
+		for _, s := range order {
+			if !selected[s.Field] && s.Field != "_id" {
+				hidden = append(hidden, s.Field)
+				projection = append(projection, bson.E{Key: s.Field, Value: 1})
+			}
+		}
+		opts.SetProjection(projection)
+	}
+
+	if q.Cursor != nil {
+		var after cursor
+		if err := bson.Unmarshal(q.Cursor, &after); err != nil || len(after.Values) != len(order) {
+			return nil, fmt.Errorf("%w: 'page_token' does not match this query", domain.ErrInvalidRequest)
+		}
+		filter = bson.M{"$and": bson.A{filter, seek(order, after.Values)}}
+	}
+
+	docs, err := m.find(ctx, coll, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &domain.Page{Items: docs}
+	if len(docs) > q.PageSize {
+		page.Items = docs[:q.PageSize]
+		last := page.Items[q.PageSize-1]
+		next := cursor{Values: make([]any, len(order))}
+		for i, s := range order {
+			v, ok := lookup(last, s.Field)
+			if !ok || v == nil {
+				return nil, fmt.Errorf("%w: field '%s' is missing or null; paginated sort fields must be set on every document", domain.ErrInvalidRequest, s.Field)
+			}
+			next.Values[i] = v
+		}
+		if page.Next, err = bson.Marshal(next); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, doc := range page.Items {
+		for _, field := range hidden {
+			unset(doc, field)
+		}
+	}
+	if page.Items == nil {
+		page.Items = []map[string]any{}
+	}
+	return page, m.outputAll(page.Items)
+}
+
+// seek matches documents after the given sort values:
+// {$or: [{a: {$gt: v1}}, {a: {$eq: v1}, b: {$gt: v2}}, ...]}, with $lt for
+// descending fields.
+func seek(order []domain.SortField, after []any) bson.M {
+	clauses := make(bson.A, len(order))
+	for i, s := range order {
+		clause := bson.M{}
+		for j := 0; j < i; j++ {
+			clause[order[j].Field] = bson.M{"$eq": after[j]}
+		}
+		op := "$gt"
+		if s.Desc {
+			op = "$lt"
+		}
+		clause[s.Field] = bson.M{op: after[i]}
+		clauses[i] = clause
+	}
+	return bson.M{"$or": clauses}
+}
+
+// lookup reads a possibly dotted field from a decoded document.
+func lookup(doc map[string]any, field string) (any, bool) {
+	var cur any = doc
+	for _, part := range strings.Split(field, ".") {
+		m, ok := cur.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if cur, ok = m[part]; !ok {
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+// unset removes a possibly dotted field from a decoded document.
+func unset(doc map[string]any, field string) {
+	parts := strings.Split(field, ".")
+	for _, part := range parts[:len(parts)-1] {
+		next, ok := doc[part].(map[string]any)
+		if !ok {
+			return
+		}
+		doc = next
+	}
+	delete(doc, parts[len(parts)-1])
+}
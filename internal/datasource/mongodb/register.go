@@ -0,0 +1,65 @@
+// Package mongodb
+// internal/datasource/mongodb/register.go
+package mongodb
+
+import (
+	"context"
+	"errors"
+
+	"github.com/thegodeveloper/data-gateway/internal/datasource"
+	"github.com/thegodeveloper/data-gateway/internal/domain"
+)
+
+func init() {
+	datasource.Register("mongodb", newFromSettings)
+}
+
+// Settings configures a "mongodb" data source instance.
+type Settings struct {
+	URI string `mapstructure:"uri"`
+	// Database is the default for requests that do not name one.
+	Database string `mapstructure:"database"`
+	// Aggregation permits stages and operators beyond the read-only default.
+	Aggregation Aggregation `mapstructure:"aggregation"`
+	// Filters bounds the operators, nesting, lists and patterns of filters.
+	Filters Filters `mapstructure:"filters"`
+	// Limits bound finds per collection; "*" applies to the others.
+	Limits map[string]Limit `mapstructure:"limits"`
+	// Output encodes results as "plain" JSON (the default), or as "relaxed"
+	// or "canonical" Extended JSON.
+	Output string `mapstructure:"output"`
+}
+
+func newFromSettings(ctx context.Context, raw map[string]any) (domain.DataSource, error) {
+	var settings Settings
+	if err := datasource.DecodeSettings(raw, &settings); err != nil {
+		return nil, err
+	}
+	if settings.URI == "" {
+		return nil, errors.New("missing 'uri' setting")
+	}
+
+	client, err := NewClient(ctx, settings.URI)
+	if err != nil {
+		return nil, err
+	}
+	src := NewMongoSource(client)
+	src.database = settings.Database
+	if err := src.SetAggregation(settings.Aggregation); err != nil {
+		client.Disconnect(ctx)
+		return nil, err
+	}
+	if err := src.SetFilters(settings.Filters); err != nil {
+		client.Disconnect(ctx)
+		return nil, err
+	}
+	if err := src.SetLimits(settings.Limits); err != nil {
+		client.Disconnect(ctx)
+		return nil, err
+	}
+	if err := src.SetOutput(settings.Output); err != nil {
+		client.Disconnect(ctx)
+		return nil, err
+	}
+	return src, nil
+}
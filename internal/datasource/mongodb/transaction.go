@@ -0,0 +1,56 @@
+// Package mongodb
+// internal/datasource/mongodb/transaction.go
+package mongodb
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/thegodeveloper/data-gateway/internal/domain"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Transact runs every step in one session transaction, which needs a
+// replica set or sharded cluster. The driver retries transient transaction
+// errors; any other failure aborts it and is reported as a domain.StepError.
+func (m *MongoSource) Transact(ctx context.Context, req domain.TransactionRequest) (*domain.TransactionResult, error) {
+	dbName, err := m.databaseName(req.Params)
+	if err != nil {
+		return nil, err
+	}
+	db := m.client.Database(dbName)
+	for i, step := range req.Steps {
+		if _, ok := req.Access.Table(step.Target); !ok {
+			err := fmt.Errorf("%w: collection '%s' is not exposed by this route", domain.ErrInvalidRequest, step.Target)
+			return nil, &domain.StepError{Step: i, Op: step.Op, Err: err}
+		}
+	}
+
+	session, err := m.client.StartSession()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start session: %w", err)
+	}
+	defer session.EndSession(ctx)
+
+	out, err := session.WithTransaction(ctx, func(sc mongo.SessionContext) (any, error) {
+		res := &domain.TransactionResult{Steps: make([]*domain.MutationResult, len(req.Steps))}
+		for i, step := range req.Steps {
+			r, err := mutate(sc, db.Collection(step.Target), step)
+			if err != nil {
+				return nil, &domain.StepError{Step: i, Op: step.Op, Err: err}
+			}
+			res.Steps[i] = r
+		}
+		return res, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	res := out.(*domain.TransactionResult)
+	for _, step := range res.Steps {
+		if err := m.outputAll(step.Rows); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,72 @@
+package mongodb
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/thegodeveloper/data-gateway/internal/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestTransactAbortsOnAFailingStep(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	steps := []domain.Mutation{
+		{Op: domain.MutationInsert, Target: "orders", Documents: []map[string]any{{"_id": "o1", "total": 10}}},
+		{Op: domain.MutationUpdate, Target: "stock", Key: map[string]any{"_id": "s1"}, Set: map[string]any{"count": 9}},
+	}
+	duplicate := bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 0}, {Key: "writeErrors", Value: bson.A{
+		bson.D{{Key: "index", Value: 0}, {Key: "code", Value: 11000}, {Key: "errmsg", Value: "duplicate key"}},
+	}}}
+	tests := []struct {
+		name      string
+		responses []bson.D
+		step      int // failing step, -1 for none
+		commands  []string
+	}{
+		{
+			"commits",
+			[]bson.D{mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}), mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}), mtest.CreateSuccessResponse()},
+			-1,
+			[]string{"insert", "update", "commitTransaction"},
+		},
+		{
+			"aborts",
+			[]bson.D{mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}), duplicate, mtest.CreateSuccessResponse()},
+			1,
+			[]string{"insert", "update", "abortTransaction"},
+		},
+	}
+	for _, tt := range tests {
+		mt.Run(tt.name, func(mt *mtest.T) {
+			mt.AddMockResponses(tt.responses...)
+			m := NewMongoSource(mt.Client)
+			m.database = "shop"
+
+			res, err := m.Transact(mt.Context(), domain.TransactionRequest{Source: "mongo", Steps: steps})
+			var stepErr *domain.StepError
+			switch {
+			case tt.step < 0 && (err != nil || len(res.Steps) != 2):
+				mt.Fatalf("Transact = %+v, %v", res, err)
+			case tt.step >= 0 && (!errors.As(err, &stepErr) || stepErr.Step != tt.step):
+				mt.Fatalf("error = %v, want step %d to fail", err, tt.step)
+			}
+
+			var commands []string
+			var txn string
+			for e := mt.GetStartedEvent(); e != nil; e = mt.GetStartedEvent() {
+				commands = append(commands, e.CommandName)
+				number := e.Command.Lookup("txnNumber")
+				if txn == "" {
+					txn = number.String()
+				} else if number.String() != txn {
+					mt.Errorf("%s ran in transaction %s, want %s", e.CommandName, number, txn)
+				}
+			}
+			if !slices.Equal(commands, tt.commands) {
+				mt.Fatalf("commands = %v, want %v", commands, tt.commands)
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+// Package mongodb
+// internal/datasource/mongodb/translate.go
+package mongodb
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/thegodeveloper/data-gateway/internal/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var comparisons = map[domain.Operator]string{
+	domain.OpEq:     "$eq",
+	domain.OpNe:     "$ne",
+	domain.OpGt:     "$gt",
+	domain.OpGte:    "$gte",
+	domain.OpLt:     "$lt",
+	domain.OpLte:    "$lte",
+	domain.OpIn:     "$in",
+	domain.OpExists: "$exists",
+}
+
+// translate renders a structured query as a bson filter plus find options.
+func translate(q domain.Query) (bson.M, *options.FindOptions, error) {
+	filter := bson.M{}
+	if q.Where != nil {
+		var err error
+		if filter, err = condition(*q.Where); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	opts := options.Find()
+	if len(q.Select) > 0 {
+		projection := bson.D{}
+		for _, field := range q.Select {
+			projection = append(projection, bson.E{Key: field, Value: 1})
+		}
+		opts.SetProjection(projection)
+	}
+	if len(q.Sort) > 0 {
+		sort := bson.D{}
+		for _, s := range q.Sort {
+			dir := 1
+			if s.Desc {
+				dir = -1
+			}
+			sort = append(sort, bson.E{Key: s.Field, Value: dir})
+		}
+		opts.SetSort(sort)
+	}
+	if q.Limit > 0 {
+		opts.SetLimit(int64(q.Limit))
+	}
+	if q.Offset > 0 {
+		opts.SetSkip(int64(q.Offset))
+	}
+
+	return filter, opts, nil
+}
+
+func condition(c domain.Condition) (bson.M, error) {
+	if c.And != nil || c.Or != nil {
+		children, op := c.And, "$and"
+		if c.Or != nil {
+			children, op = c.Or, "$or"
+		}
+		clauses := make(bson.A, 0, len(children))
+		for _, child := range children {
+			clause, err := condition(child)
+			if err != nil {
+				return nil, err
+			}
+			clauses = append(clauses, clause)
+		}
+		return bson.M{op: clauses}, nil
+	}
+
+	if c.Op == domain.OpLike {
+		return bson.M{c.Field: bson.M{"$regex": likeToRegex(c.Value.(string))}}, nil
+	}
+	op, ok := comparisons[c.Op]
+	if !ok {
+		return nil, fmt.Errorf("%w: operator '%s' is not supported by mongodb", domain.ErrInvalidRequest, c.Op)
+	}
+	value, err := fromEJSON(c.Value)
+	if err != nil {
+		return nil, err
+	}
+	return bson.M{c.Field: bson.M{op: value}}, nil
+}
+
+// likeToRegex converts an SQL LIKE pattern to an anchored regular expression:
+// % matches any run of characters, _ matches one, and a backslash escapes
+// the next character.
+func likeToRegex(pattern string) string {
+	var b strings.Builder
+	b.WriteByte('^')
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '%':
+			b.WriteString(".*")
+		case '_':
+			b.WriteByte('.')
+		case '\\':
+			if i+1 < len(pattern) {
+				i++
+				b.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+			}
+		default:
+			b.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+	b.WriteByte('$')
+	return b.String()
+}
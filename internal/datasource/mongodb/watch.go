@@ -0,0 +1,197 @@
+// Package mongodb
+// internal/datasource/mongodb/watch.go
+package mongodb
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"github.com/thegodeveloper/data-gateway/internal/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// changeStages are the stages a change stream pipeline may use.
+var changeStages = map[string]bool{
+	"$match": true, "$project": true, "$addFields": true, "$set": true,
+	"$unset": true, "$replaceRoot": true, "$replaceWith": true, "$redact": true,
+}
+
+// fullDocumentModes are the values full_document accepts, and
+// beforeChangeModes those of full_document_before_change.
+var (
+	fullDocumentModes = map[string]bool{"default": true, "updateLookup": true, "whenAvailable": true, "required": true}
+	beforeChangeModes = map[string]bool{"off": true, "whenAvailable": true, "required": true}
+)
+
+// changeEvent is the part of a change stream event a Change reports.
+type changeEvent struct {
+	ID            bson.Raw `bson:"_id"`
+	OperationType string   `bson:"operationType"`
+	NS            struct {
+		Coll string `bson:"coll"`
+	} `bson:"ns"`
+	DocumentKey              map[string]any `bson:"documentKey"`
+	FullDocument             map[string]any `bson:"fullDocument"`
+	FullDocumentBeforeChange map[string]any `bson:"fullDocumentBeforeChange"`
+	UpdateDescription        struct {
+		UpdatedFields map[string]any `bson:"updatedFields"`
+		RemovedFields []string       `bson:"removedFields"`
+	} `bson:"updateDescription"`
+	ClusterTime primitive.Timestamp `bson:"clusterTime"`
+	WallTime    primitive.DateTime  `bson:"wallTime"`
+}
+
+// Watch opens a change stream on params.collection, which needs a replica
+// set or sharded cluster:
+//
+//	pipeline                     stages filtering or reshaping the events,
+//	                             e.g. [{"$match": {"operationType": "insert"}}]
+//	full_document                default, updateLookup, whenAvailable or required
+//	full_document_before_change  off, whenAvailable or required
+//
+// Change IDs carry the event's resume token, and a request resuming after
+// one starts after that event, even if it invalidated the stream.
+func (m *MongoSource) Watch(ctx context.Context, req domain.WatchRequest) (domain.ChangeStream, error) {
+	collectionName, ok := req.Params["collection"].(string)
+	if !ok {
+		return nil, fmt.Errorf("%w: missing 'collection' parameter", domain.ErrInvalidRequest)
+	}
+	columns, ok := req.Access.Table(collectionName)
+	if !ok {
+		return nil, fmt.Errorf("%w: collection '%s' is not exposed by this route", domain.ErrInvalidRequest, collectionName)
+	}
+
+	pipeline := mongo.Pipeline{}
+	if raw, ok := req.Params["pipeline"]; ok {
+		if err := exposed(req.Access, collectionName, "pipeline"); err != nil {
+			return nil, err
+		}
+		stages, ok := raw.([]any)
+		if !ok {
+			return nil, fmt.Errorf("%w: 'pipeline' must be an array of stages", domain.ErrInvalidRequest)
+		}
+		for i, stage := range stages {
+			body, _ := stage.(map[string]any)
+			for name := range body {
+				if !changeStages[name] {
+					return nil, fmt.Errorf("%w: pipeline[%d]: stage '%s' cannot be used in a change stream", domain.ErrInvalidRequest, i, name)
+				}
+			}
+		}
+		converted, err := fromEJSON(stages)
+		if err != nil {
+			return nil, err
+		}
+		if pipeline, err = m.pipelines.check(converted.([]any), "pipeline", req.Access); err != nil {
+			return nil, err
+		}
+	}
+
+	opts, err := changeStreamOptions(req)
+	if err != nil {
+		return nil, err
+	}
+	coll, err := m.collection(req.Params, collectionName)
+	if err != nil {
+		return nil, err
+	}
+	stream, err := coll.Watch(ctx, pipeline, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(yield func(domain.Change, error) bool) {
+		defer stream.Close(context.WithoutCancel(ctx))
+		for stream.Next(ctx) {
+			change, err := m.change(stream)
+			if !yield(change.Expose(columns), err) || err != nil {
+				return
+			}
+		}
+		if err := stream.Err(); err != nil && ctx.Err() == nil {
+			yield(domain.Change{}, err)
+		}
+	}, nil
+}
+
+func changeStreamOptions(req domain.WatchRequest) (*options.ChangeStreamOptions, error) {
+	opts := options.ChangeStream()
+	if v, ok := req.Params["full_document"]; ok {
+		mode, _ := v.(string)
+		if !fullDocumentModes[mode] {
+			return nil, fmt.Errorf("%w: invalid 'full_document' %v", domain.ErrInvalidRequest, v)
+		}
+		opts.SetFullDocument(options.FullDocument(mode))
+	}
+	if v, ok := req.Params["full_document_before_change"]; ok {
+		mode, _ := v.(string)
+		if !beforeChangeModes[mode] {
+			return nil, fmt.Errorf("%w: invalid 'full_document_before_change' %v", domain.ErrInvalidRequest, v)
+		}
+		opts.SetFullDocumentBeforeChange(options.FullDocument(mode))
+	}
+	if n, ok, err := integer(req.Params, "batch_size", 1); err != nil {
+		return nil, err
+	} else if ok {
+		opts.SetBatchSize(int32(min(n, 1<<31-1)))
+	}
+	if req.ResumeAfter != "" {
+		token, err := base64.RawURLEncoding.DecodeString(req.ResumeAfter)
+		if err == nil {
+			err = bson.Raw(token).Validate()
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid resume position '%s'", domain.ErrInvalidRequest, req.ResumeAfter)
+		}
+		opts.SetStartAfter(bson.Raw(token))
+	}
+	return opts, nil
+}
+
+// change converts the current event of stream.
+func (m *MongoSource) change(stream *mongo.ChangeStream) (domain.Change, error) {
+	var event changeEvent
+	if err := stream.Decode(&event); err != nil {
+		return domain.Change{}, err
+	}
+	if event.ID == nil {
+		return domain.Change{}, fmt.Errorf("change stream events must keep their _id to be resumable")
+	}
+
+	change := domain.Change{
+		ID:      base64.RawURLEncoding.EncodeToString(event.ID),
+		Op:      domain.ChangeOp(event.OperationType),
+		Target:  event.NS.Coll,
+		Removed: event.UpdateDescription.RemovedFields,
+	}
+	switch {
+	case event.WallTime != 0:
+		change.Time = event.WallTime.Time().UTC()
+	case event.ClusterTime.T != 0:
+		change.Time = time.Unix(int64(event.ClusterTime.T), 0).UTC()
+	}
+
+	var err error
+	for _, doc := range []struct {
+		from map[string]any
+		to   *map[string]any
+	}{
+		{event.DocumentKey, &change.Key},
+		{event.FullDocument, &change.Document},
+		{event.FullDocumentBeforeChange, &change.Before},
+		{event.UpdateDescription.UpdatedFields, &change.Updated},
+	} {
+		if doc.from == nil {
+			continue
+		}
+		if *doc.to, err = m.output(doc.from); err != nil {
+			return domain.Change{}, err
+		}
+	}
+	return change, nil
+}
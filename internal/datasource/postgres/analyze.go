@@ -0,0 +1,147 @@
+// Package postgres
+// internal/datasource/postgres/analyze.go
+package postgres
+
+import (
+	"strings"
+
+	pg "github.com/pganalyze/pg_query_go/v6"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// analysis describes one SQL statement as far as the raw SQL policy cares:
+// what kind of statement it is, what it writes, and which relations and
+// functions it references.
+type analysis struct {
+	// kind is the statement's verb: "select" (including VALUES, TABLE and
+	// WITH ... SELECT), "insert", "update", "delete", "merge", "explain",
+	// "show", "copy", or the leading keyword of anything else ("create",
+	// "drop", "set", "do", ...). SELECT ... INTO is reported as "create".
+	kind string
+	// writes lists data-modifying statements nested in CTEs or EXPLAIN.
+	writes    []string
+	tables    []table
+	functions []string
+	// program is set for COPY ... TO/FROM PROGRAM.
+	program bool
+}
+
+// analyzeSQL parses query with Postgres' own grammar (libpg_query) and
+// analyzes each of its statements. Names in the tree are already decoded:
+// case-folded, unquoted and with U&"..." escapes applied.
+func analyzeSQL(query string) ([]analysis, error) {
+	tree, err := pg.Parse(query)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]analysis, 0, len(tree.Stmts))
+	for _, raw := range tree.Stmts {
+		if raw.Stmt == nil {
+			continue
+		}
+		a := analysis{kind: kindOf(raw, query)}
+		a.walk(raw.Stmt.ProtoReflect(), nil, true)
+		out = append(out, a)
+	}
+	return out, nil
+}
+
+// kindOf classifies a statement by its node type or, for the statements the
+// policy has no name of its own for, by its leading keyword.
+func kindOf(raw *pg.RawStmt, query string) string {
+	switch n := raw.Stmt.Node.(type) {
+	case *pg.Node_SelectStmt:
+		if n.SelectStmt.IntoClause != nil {
+			return "create"
+		}
+		return "select"
+	case *pg.Node_InsertStmt:
+		return "insert"
+	case *pg.Node_UpdateStmt:
+		return "update"
+	case *pg.Node_DeleteStmt:
+		return "delete"
+	case *pg.Node_MergeStmt:
+		return "merge"
+	case *pg.Node_ExplainStmt:
+		return "explain"
+	case *pg.Node_VariableShowStmt:
+		return "show"
+	case *pg.Node_CopyStmt:
+		return "copy"
+	}
+
+	text := query[raw.StmtLocation:]
+	if raw.StmtLen > 0 {
+		text = text[:raw.StmtLen]
+	}
+	for _, t := range lex(text) {
+		if t.kind == tokWord {
+			return t.text
+		}
+	}
+	return ""
+}
+
+// walk visits every node below msg. ctes holds the WITH query names in
+// scope, which unqualified relation names refer to instead of tables. top
+// is set for the statement itself, which is not a nested write.
+func (a *analysis) walk(msg protoreflect.Message, ctes []string, top bool) {
+	switch n := msg.Interface().(type) {
+	case *pg.RangeVar:
+		if n.Schemaname == "" && n.Catalogname == "" && contains(ctes, n.Relname) {
+			return
+		}
+		a.tables = append(a.tables, table{schema: n.Schemaname, name: n.Relname})
+		return
+	case *pg.FuncCall:
+		if len(n.Funcname) > 0 {
+			a.functions = append(a.functions, n.Funcname[len(n.Funcname)-1].GetString_().GetSval())
+		}
+	case *pg.InsertStmt, *pg.UpdateStmt, *pg.DeleteStmt, *pg.MergeStmt:
+		if !top {
+			a.writes = append(a.writes, strings.ToLower(strings.TrimSuffix(string(msg.Descriptor().Name()), "Stmt")))
+		}
+	case *pg.CopyStmt:
+		a.program = a.program || n.IsProgram
+	}
+
+	// A WITH list is in scope in the rest of its statement. Each query of
+	// the list sees the ones before it, or all of them if RECURSIVE.
+	with := msg.Descriptor().Fields().ByName("with_clause")
+	if with != nil && msg.Has(with) {
+		clause := msg.Get(with).Message().Interface().(*pg.WithClause)
+		scope := ctes
+		var names []string
+		for _, node := range clause.Ctes {
+			names = append(names, node.GetCommonTableExpr().GetCtename())
+		}
+		for i, node := range clause.Ctes {
+			visible := names[:i]
+			if clause.Recursive {
+				visible = names
+			}
+			if q := node.GetCommonTableExpr().GetCtequery(); q != nil {
+				a.walk(q.ProtoReflect(), append(scope[:len(scope):len(scope)], visible...), false)
+			}
+		}
+		ctes = append(scope[:len(scope):len(scope)], names...)
+	}
+
+	// Node only wraps the statement itself.
+	_, wrapper := msg.Interface().(*pg.Node)
+	msg.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		if fd == with || fd.Kind() != protoreflect.MessageKind {
+			return true
+		}
+		if fd.IsList() {
+			list := v.List()
+			for i := range list.Len() {
+				a.walk(list.Get(i).Message(), ctes, false)
+			}
+			return true
+		}
+		a.walk(v.Message(), ctes, top && wrapper)
+		return true
+	})
+}
@@ -0,0 +1,211 @@
+// Package postgres
+// internal/datasource/postgres/binds.go
+package postgres
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/thegodeveloper/data-gateway/internal/domain"
+)
+
+// bindArgs binds params.args to query: an object fills :name placeholders,
+// an array fills $1..$n. hints, shaped like args, holds optional type hints
+// for coerce.
+func bindArgs(query string, args, hints any) (string, []any, error) {
+	switch a := args.(type) {
+	case nil:
+		return query, nil, nil
+	case map[string]any:
+		h, ok := hints.(map[string]any)
+		if !ok && hints != nil {
+			return "", nil, fmt.Errorf("%w: 'types' must be an object when 'args' is", domain.ErrInvalidRequest)
+		}
+		if highestParam(query) > 0 {
+			return "", nil, fmt.Errorf("%w: use :name binds with an 'args' object, or pass 'args' as an array for $n", domain.ErrInvalidRequest)
+		}
+		return bindNamed(query, a, h)
+	case []any:
+		h, ok := hints.([]any)
+		if !ok && hints != nil {
+			return "", nil, fmt.Errorf("%w: 'types' must be an array when 'args' is", domain.ErrInvalidRequest)
+		}
+		if n := highestParam(query); n != len(a) {
+			return "", nil, fmt.Errorf("%w: query uses %d positional bind(s) but %d arg(s) were given", domain.ErrInvalidRequest, n, len(a))
+		}
+		values := make([]any, len(a))
+		for i, value := range a {
+			hint, _ := index(h, i).(string)
+			v, err := coerce(value, hint)
+			if err != nil {
+				return "", nil, fmt.Errorf("%w: bind $%d: %v", domain.ErrInvalidRequest, i+1, err)
+			}
+			values[i] = v
+		}
+		return query, values, nil
+	default:
+		return "", nil, fmt.Errorf("%w: 'args' must be an object or an array", domain.ErrInvalidRequest)
+	}
+}
+
+func index(list []any, i int) any {
+	if i < len(list) {
+		return list[i]
+	}
+	return nil
+}
+
+// highestParam returns the largest $n referenced by query.
+func highestParam(query string) int {
+	highest := 0
+	for _, t := range lex(query) {
+		if t.kind != tokParam {
+			continue
+		}
+		if n, err := strconv.Atoi(t.text[1:]); err == nil && n > highest {
+			highest = n
+		}
+	}
+	return highest
+}
+
+// bindNamed rewrites :name placeholders in query to $n and returns the
+// matching positional arguments, coerced with the hint for their name. A
+// name used more than once maps to the same $n. Text inside string literals,
+// quoted identifiers, dollar-quoted bodies and comments is copied verbatim,
+// and :: casts are left alone.
+func bindNamed(query string, args, hints map[string]any) (string, []any, error) {
+	var (
+		out       strings.Builder
+		values    []any
+		positions = make(map[string]int)
+	)
+
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == '\'' || c == '"':
+			end := skipQuoted(query, i)
+			out.WriteString(query[i:end])
+			i = end
+		case c == '-' && strings.HasPrefix(query[i:], "--"):
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				end = len(query) - i
+			}
+			out.WriteString(query[i : i+end])
+			i += end
+		case c == '/' && strings.HasPrefix(query[i:], "/*"):
+			end := skipBlockComment(query, i)
+			out.WriteString(query[i:end])
+			i = end
+		case c == '$' && dollarTag(query[i:]) != "":
+			tag := dollarTag(query[i:])
+			end := strings.Index(query[i+len(tag):], tag)
+			if end < 0 {
+				end = len(query)
+			} else {
+				end = i + len(tag) + end + len(tag)
+			}
+			out.WriteString(query[i:end])
+			i = end
+		case c == ':' && strings.HasPrefix(query[i:], "::"):
+			out.WriteString("::")
+			i += 2
+		case c == ':' && i+1 < len(query) && isIdentStart(query[i+1]):
+			j := i + 1
+			for j < len(query) && isIdentPart(query[j]) {
+				j++
+			}
+			name := query[i+1 : j]
+			pos, ok := positions[name]
+			if !ok {
+				value, found := args[name]
+				if !found {
+					return "", nil, fmt.Errorf("%w: missing value for bind ':%s'", domain.ErrInvalidRequest, name)
+				}
+				hint, _ := hints[name].(string)
+				value, err := coerce(value, hint)
+				if err != nil {
+					return "", nil, fmt.Errorf("%w: bind ':%s': %v", domain.ErrInvalidRequest, name, err)
+				}
+				values = append(values, value)
+				pos = len(values)
+				positions[name] = pos
+			}
+			fmt.Fprintf(&out, "$%d", pos)
+			i = j
+		default:
+			out.WriteByte(c)
+			i++
+		}
+	}
+
+	return out.String(), values, nil
+}
+
+// skipQuoted returns the index just past the quoted token starting at i. A
+// doubled quote is an escaped quote; E'...' strings also honour backslashes.
+func skipQuoted(s string, i int) int {
+	quote := s[i]
+	backslash := quote == '\'' && i > 0 && (s[i-1] == 'E' || s[i-1] == 'e') &&
+		(i < 2 || !isIdentPart(s[i-2]))
+	for j := i + 1; j < len(s); j++ {
+		switch {
+		case backslash && s[j] == '\\':
+			j++
+		case s[j] == quote:
+			if j+1 < len(s) && s[j+1] == quote {
+				j++
+				continue
+			}
+			return j + 1
+		}
+	}
+	return len(s)
+}
+
+// skipBlockComment returns the index just past the (possibly nested) block
+// comment starting at i.
+func skipBlockComment(s string, i int) int {
+	depth := 0
+	for j := i; j < len(s)-1; j++ {
+		switch {
+		case s[j] == '/' && s[j+1] == '*':
+			depth++
+			j++
+		case s[j] == '*' && s[j+1] == '/':
+			depth--
+			j++
+			if depth == 0 {
+				return j + 1
+			}
+		}
+	}
+	return len(s)
+}
+
+// dollarTag returns the opening tag ($$ or $name$) of a dollar-quoted string
+// at the start of s, or "" if s does not start one ($1 is a parameter).
+func dollarTag(s string) string {
+	for j := 1; j < len(s); j++ {
+		switch {
+		case s[j] == '$':
+			return s[:j+1]
+		case j == 1 && !isIdentStart(s[j]):
+			return ""
+		case !isIdentPart(s[j]):
+			return ""
+		}
+	}
+	return ""
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isIdentPart(c byte) bool {
+	return isIdentStart(c) || (c >= '0' && c <= '9')
+}
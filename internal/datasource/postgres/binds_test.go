@@ -0,0 +1,78 @@
+package postgres
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/thegodeveloper/data-gateway/internal/domain"
+)
+
+func TestBindArgs(t *testing.T) {
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name   string
+		query  string
+		args   any
+		hints  any
+		sql    string
+		values []any
+	}{
+		{"no args", "SELECT 1", nil, nil, "SELECT 1", nil},
+		{"positional", "SELECT * FROM orders WHERE id = $1 AND total > $2", []any{7.0, 9.5}, nil,
+			"SELECT * FROM orders WHERE id = $1 AND total > $2", []any{int64(7), 9.5}},
+		{"positional hints", "SELECT * FROM orders WHERE ref = $1 AND placed_at = $2", []any{"2024-05-01T12:00:00Z", "2024-05-01T12:00:00Z"}, []any{"text"},
+			"SELECT * FROM orders WHERE ref = $1 AND placed_at = $2", []any{"2024-05-01T12:00:00Z", at}},
+		{"named", "SELECT * FROM orders WHERE customer_id = :customer AND status = :status", map[string]any{"customer": 3.0, "status": "open"}, nil,
+			"SELECT * FROM orders WHERE customer_id = $1 AND status = $2", []any{int64(3), "open"}},
+		{"named reused", "SELECT * FROM t WHERE a = :v OR b = :v", map[string]any{"v": "x"}, nil,
+			"SELECT * FROM t WHERE a = $1 OR b = $1", []any{"x"}},
+		{"named hints", "SELECT * FROM t WHERE total = :total", map[string]any{"total": 12.5}, map[string]any{"total": "numeric"},
+			"SELECT * FROM t WHERE total = $1", []any{"12.5"}},
+		{"casts kept", "SELECT :v::text, x::int FROM t", map[string]any{"v": "a"}, nil,
+			"SELECT $1::text, x::int FROM t", []any{"a"}},
+		{"quoted text kept", `SELECT ':a', ":a", E'\':a', $$ :a $$, $f$ :a $f$ FROM t WHERE x = :a`, map[string]any{"a": "v"}, nil,
+			`SELECT ':a', ":a", E'\':a', $$ :a $$, $f$ :a $f$ FROM t WHERE x = $1`, []any{"v"}},
+		{"comments kept", "SELECT 1 -- :a\n/* :a /* :a */ */ WHERE x = :a", map[string]any{"a": "v"}, nil,
+			"SELECT 1 -- :a\n/* :a /* :a */ */ WHERE x = $1", []any{"v"}},
+		{"unused args ignored", "SELECT :a", map[string]any{"a": true, "b": 1.0}, nil, "SELECT $1", []any{true}},
+		{"array arg", "SELECT * FROM t WHERE id = ANY(:ids)", map[string]any{"ids": []any{1.0, 2.0}}, nil,
+			"SELECT * FROM t WHERE id = ANY($1)", []any{pq.GenericArray{A: []any{int64(1), int64(2)}}}},
+	}
+	for _, c := range cases {
+		sql, values, err := bindArgs(c.query, c.args, c.hints)
+		if err != nil {
+			t.Errorf("%s: %v", c.name, err)
+			continue
+		}
+		if sql != c.sql || !reflect.DeepEqual(values, c.values) {
+			t.Errorf("%s: bindArgs = %q %#v\nwant %q %#v", c.name, sql, values, c.sql, c.values)
+		}
+	}
+}
+
+func TestBindArgsRejects(t *testing.T) {
+	cases := []struct {
+		name  string
+		query string
+		args  any
+		hints any
+	}{
+		{"scalar args", "SELECT $1", "x", nil},
+		{"too few positional", "SELECT $1, $2", []any{1.0}, nil},
+		{"too many positional", "SELECT $1", []any{1.0, 2.0}, nil},
+		{"positional in named", "SELECT $1, :a", map[string]any{"a": 1.0}, nil},
+		{"missing name", "SELECT :a, :b", map[string]any{"a": 1.0}, nil},
+		{"hints shape", "SELECT :a", map[string]any{"a": 1.0}, []any{"int8"}},
+		{"hints shape positional", "SELECT $1", []any{1.0}, map[string]any{"a": "int8"}},
+		{"bad hinted value", "SELECT :a", map[string]any{"a": "seven"}, map[string]any{"a": "int8"}},
+		{"unknown hint", "SELECT $1", []any{1.0}, []any{"money"}},
+	}
+	for _, c := range cases {
+		if _, _, err := bindArgs(c.query, c.args, c.hints); !errors.Is(err, domain.ErrInvalidRequest) {
+			t.Errorf("%s: error = %v, want ErrInvalidRequest", c.name, err)
+		}
+	}
+}
@@ -0,0 +1,264 @@
+// Package postgres
+// internal/datasource/postgres/catalog.go
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/thegodeveloper/data-gateway/internal/domain"
+)
+
+const (
+	// catalogTTL bounds how long a table's column list is trusted, so DDL
+	// shows up without a restart.
+	catalogTTL = 5 * time.Minute
+	// missingTTL is shorter so a newly created table appears quickly, while
+	// repeated lookups of bogus names still do not hit the catalog every time.
+	missingTTL = 30 * time.Second
+	// maxMissing caps remembered unknown names, so a caller cycling through
+	// bogus table names cannot grow the cache without bound.
+	maxMissing = 1024
+)
+
+// identifier accepts only plain, unquoted-style names. Anything else is
+// rejected before it gets near SQL or the catalog.
+var identifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_$]{0,62}$`)
+
+// table is a validated, possibly schema-qualified table name.
+type table struct {
+	schema string // empty means current_schema()
+	name   string
+}
+
+func (t table) String() string {
+	if t.schema == "" {
+		return t.name
+	}
+	return t.schema + "." + t.name
+}
+
+// quoted renders the name with identifier escaping for use in SQL.
+func (t table) quoted() string {
+	if t.schema == "" {
+		return pq.QuoteIdentifier(t.name)
+	}
+	return pq.QuoteIdentifier(t.schema) + "." + pq.QuoteIdentifier(t.name)
+}
+
+// parseTable validates "table" or "schema.table".
+func parseTable(name string) (table, error) {
+	parts := strings.Split(name, ".")
+	if len(parts) > 2 {
+		return table{}, fmt.Errorf("%w: invalid table name '%s'", domain.ErrInvalidRequest, name)
+	}
+	for _, part := range parts {
+		if !identifier.MatchString(part) {
+			return table{}, fmt.Errorf("%w: invalid table name '%s'", domain.ErrInvalidRequest, name)
+		}
+	}
+	if len(parts) == 2 {
+		return table{schema: parts[0], name: parts[1]}, nil
+	}
+	return table{name: parts[0]}, nil
+}
+
+type catalogEntry struct {
+	columns map[string]bool
+	order   []string
+	types   map[string]string // column -> udt_name, e.g. "int4", "_text"
+	key     []string          // primary key columns, in key order
+	loaded  time.Time
+}
+
+// catalog caches column lists read from information_schema. Tables the
+// gateway's role cannot see are reported as unknown.
+type catalog struct {
+	db *sql.DB
+
+	mu      sync.Mutex
+	entries map[table]catalogEntry
+	missing int
+}
+
+func newCatalog(db *sql.DB) *catalog {
+	return &catalog{db: db, entries: make(map[table]catalogEntry)}
+}
+
+// columns returns the table's columns in ordinal order, or an
+// ErrInvalidRequest error if the table does not exist.
+func (c *catalog) columns(ctx context.Context, t table) (catalogEntry, error) {
+	c.mu.Lock()
+	entry, ok := c.entries[t]
+	c.mu.Unlock()
+
+	ttl := catalogTTL
+	if ok && entry.columns == nil {
+		ttl = missingTTL
+	}
+	if !ok || time.Since(entry.loaded) > ttl {
+		var err error
+		if entry, err = c.load(ctx, t); err != nil {
+			return catalogEntry{}, err
+		}
+		c.store(t, entry)
+	}
+
+	if entry.columns == nil {
+		return catalogEntry{}, fmt.Errorf("%w: unknown table '%s'", domain.ErrInvalidRequest, t)
+	}
+	return entry, nil
+}
+
+func (c *catalog) store(t table, entry catalogEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	prev, had := c.entries[t]
+	wasMissing := had && prev.columns == nil
+	isMissing := entry.columns == nil
+	switch {
+	case isMissing && !wasMissing:
+		if c.missing >= maxMissing {
+			return
+		}
+		c.missing++
+	case !isMissing && wasMissing:
+		c.missing--
+	}
+	c.entries[t] = entry
+}
+
+func (c *catalog) load(ctx context.Context, t table) (catalogEntry, error) {
+	rows, err := c.db.QueryContext(ctx, `
+		SELECT column_name, udt_name
+		FROM information_schema.columns
+		WHERE table_schema = COALESCE(NULLIF($1, ''), current_schema()) AND table_name = $2
+		ORDER BY ordinal_position`, t.schema, t.name)
+	if err != nil {
+		return catalogEntry{}, fmt.Errorf("failed to read catalog for '%s': %w", t, err)
+	}
+	defer rows.Close()
+
+	entry := catalogEntry{loaded: time.Now()}
+	for rows.Next() {
+		var name, udt string
+		if err := rows.Scan(&name, &udt); err != nil {
+			return catalogEntry{}, fmt.Errorf("failed to read catalog for '%s': %w", t, err)
+		}
+		if entry.columns == nil {
+			entry.columns = make(map[string]bool)
+			entry.types = make(map[string]string)
+		}
+		entry.columns[name] = true
+		entry.types[name] = udt
+		entry.order = append(entry.order, name)
+	}
+	if err := rows.Err(); err != nil {
+		return catalogEntry{}, fmt.Errorf("failed to read catalog for '%s': %w", t, err)
+	}
+	if entry.columns == nil {
+		return entry, nil
+	}
+
+	if entry.key, err = c.primaryKey(ctx, t); err != nil {
+		return catalogEntry{}, err
+	}
+	return entry, nil
+}
+
+func (c *catalog) primaryKey(ctx context.Context, t table) ([]string, error) {
+	rows, err := c.db.QueryContext(ctx, `
+		SELECT kcu.column_name
+		FROM information_schema.table_constraints tc
+		JOIN information_schema.key_column_usage kcu
+			ON kcu.constraint_schema = tc.constraint_schema AND kcu.constraint_name = tc.constraint_name
+		WHERE tc.constraint_type = 'PRIMARY KEY'
+			AND tc.table_schema = COALESCE(NULLIF($1, ''), current_schema()) AND tc.table_name = $2
+		ORDER BY kcu.ordinal_position`, t.schema, t.name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read primary key of '%s': %w", t, err)
+	}
+	defer rows.Close()
+
+	var key []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to read primary key of '%s': %w", t, err)
+		}
+		key = append(key, name)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read primary key of '%s': %w", t, err)
+	}
+	return key, nil
+}
+
+// exposed resolves a table against the catalog and the request's allow-list.
+// It returns the columns the caller may see and filter on; the allow-list
+// can only narrow what the catalog reports.
+type exposed struct {
+	table   table
+	columns map[string]bool
+	order   []string
+	types   map[string]string
+	key     []string
+	all     bool
+}
+
+func (p *PostgresSource) expose(ctx context.Context, name string, access *domain.Access) (*exposed, error) {
+	t, err := parseTable(name)
+	if err != nil {
+		return nil, err
+	}
+	allowed, ok := access.Table(name)
+	if !ok {
+		return nil, fmt.Errorf("%w: table '%s' is not exposed by this route", domain.ErrInvalidRequest, name)
+	}
+
+	entry, err := p.catalog.columns(ctx, t)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &exposed{table: t, columns: make(map[string]bool, len(entry.order)), types: entry.types, key: entry.key}
+	for _, col := range entry.order {
+		if allowed == nil || allowed[col] {
+			e.columns[col] = true
+			e.order = append(e.order, col)
+		}
+	}
+	if len(e.order) == 0 {
+		return nil, fmt.Errorf("%w: no columns of table '%s' are exposed by this route", domain.ErrInvalidRequest, name)
+	}
+	e.all = len(e.order) == len(entry.order)
+	return e, nil
+}
+
+// column checks that name is a column the caller may use.
+func (e *exposed) column(name string) error {
+	if !e.columns[name] {
+		return fmt.Errorf("%w: unknown column '%s' on table '%s'", domain.ErrInvalidRequest, name, e.table)
+	}
+	return nil
+}
+
+// selectList is "*" when every column is exposed, otherwise the quoted list
+// of exposed columns.
+func (e *exposed) selectList() string {
+	if e.all {
+		return "*"
+	}
+	quoted := make([]string, len(e.order))
+	for i, col := range e.order {
+		quoted[i] = pq.QuoteIdentifier(col)
+	}
+	return strings.Join(quoted, ", ")
+}
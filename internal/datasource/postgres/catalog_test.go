@@ -0,0 +1,102 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/thegodeveloper/data-gateway/internal/domain"
+)
+
+// testSource is a source whose catalog already knows public.users and
+// public.orders, so expose never reaches a database.
+func testSource() *PostgresSource {
+	c := newCatalog(nil)
+	for _, t := range []table{{name: "users"}, {schema: "public", name: "users"}} {
+		c.entries[t] = catalogEntry{
+			columns: map[string]bool{"id": true, "email": true, "password_hash": true},
+			order:   []string{"id", "email", "password_hash"},
+			types:   map[string]string{"id": "int4", "email": "text", "password_hash": "text"},
+			key:     []string{"id"},
+			loaded:  time.Now(),
+		}
+	}
+	c.entries[table{name: "orders"}] = catalogEntry{
+		columns: map[string]bool{"id": true, "total": true},
+		order:   []string{"id", "total"},
+		types:   map[string]string{"id": "int4", "total": "numeric"},
+		loaded:  time.Now(),
+	}
+	return &PostgresSource{catalog: c}
+}
+
+func TestParseTable(t *testing.T) {
+	valid := map[string]table{
+		"users":        {name: "users"},
+		"public.users": {schema: "public", name: "users"},
+		"_audit$2024":  {name: "_audit$2024"},
+	}
+	for name, want := range valid {
+		got, err := parseTable(name)
+		if err != nil {
+			t.Errorf("parseTable(%q): %v", name, err)
+		} else if got != want {
+			t.Errorf("parseTable(%q) = %+v, want %+v", name, got, want)
+		}
+	}
+
+	for _, name := range []string{
+		"",
+		"users;DROP TABLE users",
+		"users; --",
+		"1=1 OR id",
+		"users WHERE 1=1",
+		`"users"`,
+		`public."users"`,
+		"users\x00",
+		"üsers",
+		"users​",
+		"a.b.c",
+		".users",
+		"public.",
+		"1users",
+		"pg_catalog.pg_authid/*",
+		"users) UNION SELECT * FROM secrets --",
+	} {
+		if _, err := parseTable(name); !errors.Is(err, domain.ErrInvalidRequest) {
+			t.Errorf("parseTable(%q) error = %v, want ErrInvalidRequest", name, err)
+		}
+	}
+}
+
+func TestExposeAllowList(t *testing.T) {
+	p := testSource()
+	ctx := context.Background()
+	access := &domain.Access{Tables: map[string][]string{"users": {"id", "email"}}}
+
+	e, err := p.expose(ctx, "users", access)
+	if err != nil {
+		t.Fatalf("expose: %v", err)
+	}
+	if e.columns["password_hash"] || !e.columns["email"] {
+		t.Fatalf("exposed columns = %v, want id and email", e.columns)
+	}
+	if got := e.selectList(); got != `"id", "email"` {
+		t.Fatalf("selectList = %s", got)
+	}
+
+	for _, name := range []string{"orders", "public.users", "users;DROP TABLE users"} {
+		if _, err := p.expose(ctx, name, access); !errors.Is(err, domain.ErrInvalidRequest) {
+			t.Errorf("expose(%q) error = %v, want ErrInvalidRequest", name, err)
+		}
+	}
+
+	none := &domain.Access{Tables: map[string][]string{"users": {"ghost"}}}
+	if _, err := p.expose(ctx, "users", none); !errors.Is(err, domain.ErrInvalidRequest) {
+		t.Errorf("expose with no known columns error = %v, want ErrInvalidRequest", err)
+	}
+	if e, err := p.expose(ctx, "public.users", nil); err != nil || !e.all {
+		t.Errorf("expose(public.users) = %v, %v; want every column", e, err)
+	}
+}
@@ -0,0 +1,202 @@
+// Package postgres
+// internal/datasource/postgres/changes.go
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/thegodeveloper/data-gateway/internal/domain"
+)
+
+// notifyOp is the kind of change a NOTIFY delivers.
+const notifyOp domain.ChangeOp = "notify"
+
+// Changes configures what a postgres instance reports to watchers.
+type Changes struct {
+	// Channels are the NOTIFY channels callers may listen on.
+	Channels []string `mapstructure:"channels"`
+	// Slot is a logical replication slot using the wal2json output plugin.
+	// Row changes of Tables are read from it; empty disables them.
+	Slot string `mapstructure:"slot"`
+	// CreateSlot creates Slot at startup when it does not exist.
+	CreateSlot bool `mapstructure:"create_slot"`
+	// Tables are the "table" or "schema.table" names whose row changes are
+	// reported. A name without a schema matches the table in any schema.
+	Tables []string `mapstructure:"tables"`
+	// PollInterval is how often the slot is read once it is drained
+	// (default 1s).
+	PollInterval time.Duration `mapstructure:"poll_interval"`
+	// Buffer is how many recent row changes are kept for resuming
+	// (default 10000).
+	Buffer int `mapstructure:"buffer"`
+}
+
+// SetChanges configures the NOTIFY channels and the row change feed, which
+// starts reading its slot right away.
+func (p *PostgresSource) SetChanges(ctx context.Context, cfg Changes) error {
+	channels := make(map[string]bool, len(cfg.Channels))
+	for _, channel := range cfg.Channels {
+		if !identifier.MatchString(channel) {
+			return fmt.Errorf("invalid notification channel '%s'", channel)
+		}
+		channels[channel] = true
+	}
+
+	var feed *rowFeed
+	if cfg.Slot != "" {
+		if !identifier.MatchString(cfg.Slot) {
+			return fmt.Errorf("invalid replication slot name '%s'", cfg.Slot)
+		}
+		if len(cfg.Tables) == 0 {
+			return fmt.Errorf("replication slot '%s' needs 'tables' to report", cfg.Slot)
+		}
+		var err error
+		if feed, err = newRowFeed(ctx, p.db, cfg); err != nil {
+			return err
+		}
+	} else if len(cfg.Tables) > 0 {
+		return errors.New("change feed 'tables' need a replication 'slot'")
+	}
+
+	if p.feed != nil {
+		p.feed.close()
+	}
+	p.channels = channels
+	p.feed = feed
+	return nil
+}
+
+// Watch reports changes in one of two ways:
+//
+//	channel  a NOTIFY channel to listen on; each notification is a "notify"
+//	         change whose document is the payload
+//	tables   tables whose row changes to report, by default every table of
+//	         the feed that the route exposes
+//
+// Row change IDs are "LSN:index" positions. Notifications are not stored by
+// PostgreSQL, so they cannot be resumed.
+func (p *PostgresSource) Watch(ctx context.Context, req domain.WatchRequest) (domain.ChangeStream, error) {
+	if raw, ok := req.Params["channel"]; ok {
+		channel, _ := raw.(string)
+		if !p.channels[channel] {
+			return nil, fmt.Errorf("%w: channel '%v' is not open to listeners", domain.ErrInvalidRequest, raw)
+		}
+		if req.ResumeAfter != "" {
+			return nil, fmt.Errorf("%w: notifications cannot be resumed", domain.ErrInvalidRequest)
+		}
+		return p.listen(ctx, channel)
+	}
+
+	if p.feed == nil {
+		return nil, fmt.Errorf("%w: this source has no row change feed", domain.ErrInvalidRequest)
+	}
+	tables, err := p.watchedTables(req)
+	if err != nil {
+		return nil, err
+	}
+	pos := p.feed.latest()
+	if req.ResumeAfter != "" {
+		if pos, err = parseWalPosition(req.ResumeAfter); err != nil {
+			return nil, err
+		}
+		if _, _, ok := p.feed.since(pos); !ok {
+			return nil, fmt.Errorf("%w: resume position '%s' is no longer buffered; reload and watch from now", domain.ErrInvalidRequest, req.ResumeAfter)
+		}
+	}
+	return p.feed.subscribe(ctx, pos, tables), nil
+}
+
+// watchedTables maps each requested table to the columns the route exposes.
+func (p *PostgresSource) watchedTables(req domain.WatchRequest) (map[string]map[string]bool, error) {
+	var names []string
+	if raw, ok := req.Params["tables"]; ok {
+		list, ok := raw.([]any)
+		if !ok {
+			return nil, fmt.Errorf("%w: 'tables' must be a list of table names", domain.ErrInvalidRequest)
+		}
+		for _, item := range list {
+			name, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("%w: 'tables' must be a list of table names", domain.ErrInvalidRequest)
+			}
+			names = append(names, name)
+		}
+	}
+
+	tables := make(map[string]map[string]bool)
+	configured := make(map[string]bool, len(p.feed.tables))
+	for _, name := range p.feed.tables {
+		configured[name] = true
+	}
+	if names == nil {
+		for name := range configured {
+			if columns, ok := req.Access.Table(name); ok {
+				tables[name] = columns
+			}
+		}
+		if len(tables) == 0 {
+			return nil, fmt.Errorf("%w: no table of the change feed is exposed by this route", domain.ErrInvalidRequest)
+		}
+		return tables, nil
+	}
+	for _, name := range names {
+		if !configured[name] {
+			return nil, fmt.Errorf("%w: table '%s' is not in the change feed", domain.ErrInvalidRequest, name)
+		}
+		columns, ok := req.Access.Table(name)
+		if !ok {
+			return nil, fmt.Errorf("%w: table '%s' is not exposed by this route", domain.ErrInvalidRequest, name)
+		}
+		tables[name] = columns
+	}
+	return tables, nil
+}
+
+// listen opens a dedicated connection listening on channel. A payload that
+// is a JSON object becomes the change's document; any other payload is
+// reported as {"payload": "..."}.
+func (p *PostgresSource) listen(ctx context.Context, channel string) (domain.ChangeStream, error) {
+	listener := pq.NewListener(p.connStr, time.Second, time.Minute, nil)
+	// Listen waits for the connection, retrying for as long as it takes.
+	listening := make(chan error, 1)
+	go func() { listening <- listener.Listen(channel) }()
+	select {
+	case err := <-listening:
+		if err != nil {
+			listener.Close()
+			return nil, fmt.Errorf("failed to listen on channel '%s': %w", channel, err)
+		}
+	case <-ctx.Done():
+		listener.Close()
+		return nil, ctx.Err()
+	}
+
+	return func(yield func(domain.Change, error) bool) {
+		defer listener.Close()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case n := <-listener.Notify:
+				if n == nil {
+					// The listener reconnected; whatever was sent meanwhile is lost.
+					yield(domain.Change{}, errors.New("connection to PostgreSQL was lost; notifications may have been missed"))
+					return
+				}
+				var doc map[string]any
+				if json.Unmarshal([]byte(n.Extra), &doc) != nil {
+					doc = map[string]any{"payload": n.Extra}
+				}
+				change := domain.Change{Op: notifyOp, Target: n.Channel, Document: doc, Time: time.Now().UTC()}
+				if !yield(change, nil) {
+					return
+				}
+			}
+		}
+	}, nil
+}
@@ -0,0 +1,164 @@
+// Package postgres
+// internal/datasource/postgres/coerce.go
+package postgres
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// errUnknownHint is returned for type hints coerce has no rule for.
+var errUnknownHint = errors.New("unsupported type hint")
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// maxExactFloat is the largest integer a float64 holds exactly.
+const maxExactFloat = 1 << 53
+
+// coerce converts a JSON-decoded bind value into a value lib/pq can send.
+// Without a type hint, integral numbers become int64, RFC 3339 strings
+// become timestamps, UUIDs are canonicalised, objects are sent as JSON text
+// and arrays as Postgres arrays. A hint ("text", "int8", "numeric",
+// "timestamptz", "uuid", "jsonb", "text[]", ...) replaces the guessing, e.g.
+// to keep a timestamp-looking string as text.
+func coerce(value any, hint string) (any, error) {
+	hint = strings.ToLower(strings.TrimSpace(hint))
+	if elem, ok := strings.CutSuffix(hint, "[]"); ok {
+		return coerceArray(value, elem)
+	}
+	if value == nil {
+		return nil, nil
+	}
+
+	switch hint {
+	case "":
+		return guess(value)
+	case "text", "varchar", "char", "bpchar", "name", "citext":
+		if s, ok := value.(string); ok {
+			return s, nil
+		}
+	case "int", "int2", "int4", "int8", "integer", "smallint", "bigint":
+		switch v := value.(type) {
+		case string:
+			return strconv.ParseInt(v, 10, 64)
+		case float64:
+			if v == math.Trunc(v) && math.Abs(v) <= maxExactFloat {
+				return int64(v), nil
+			}
+		case int, int64:
+			return v, nil
+		}
+	case "float4", "float8", "real", "double precision":
+		switch v := value.(type) {
+		case string:
+			return strconv.ParseFloat(v, 64)
+		case float64, int, int64:
+			return v, nil
+		}
+	case "numeric", "decimal":
+		// Sent as text so Postgres parses the exact decimal.
+		switch v := value.(type) {
+		case string:
+			if _, err := strconv.ParseFloat(v, 64); err == nil {
+				return v, nil
+			}
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64), nil
+		case int, int64:
+			return fmt.Sprint(v), nil
+		}
+	case "bool", "boolean":
+		switch v := value.(type) {
+		case string:
+			return strconv.ParseBool(v)
+		case bool:
+			return v, nil
+		}
+	case "timestamp", "timestamptz":
+		if s, ok := value.(string); ok {
+			return time.Parse(time.RFC3339Nano, s)
+		}
+	case "date":
+		if s, ok := value.(string); ok {
+			if _, err := time.Parse(time.DateOnly, s); err == nil {
+				return s, nil
+			}
+		}
+	case "uuid":
+		if s, ok := value.(string); ok && uuidPattern.MatchString(s) {
+			return strings.ToLower(s), nil
+		}
+	case "json", "jsonb":
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		return string(data), nil
+	default:
+		return nil, fmt.Errorf("%w '%s'", errUnknownHint, hint)
+	}
+	return nil, fmt.Errorf("%v is not a valid %s", value, hint)
+}
+
+func guess(value any) (any, error) {
+	switch v := value.(type) {
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) <= maxExactFloat {
+			return int64(v), nil
+		}
+		return v, nil
+	case string:
+		if uuidPattern.MatchString(v) {
+			return strings.ToLower(v), nil
+		}
+		if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
+			return t, nil
+		}
+		return v, nil
+	case map[string]any:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		return string(data), nil
+	case []any:
+		return coerceArray(v, "")
+	default:
+		return v, nil
+	}
+}
+
+// coerceArray sends a JSON array as a one-dimensional Postgres array literal;
+// the element type comes from the query's context, as with any parameter.
+func coerceArray(value any, elem string) (any, error) {
+	if value == nil {
+		return nil, nil
+	}
+	items, ok := value.([]any)
+	if !ok {
+		return nil, fmt.Errorf("%v is not an array", value)
+	}
+	out := make([]any, len(items))
+	for i, item := range items {
+		if _, nested := item.([]any); nested {
+			return nil, fmt.Errorf("nested arrays are not supported")
+		}
+		if _, object := item.(map[string]any); object && elem == "" {
+			return nil, fmt.Errorf("array elements must be scalars")
+		}
+		v, err := coerce(item, elem)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = v
+	}
+	return pq.GenericArray{A: out}, nil
+}
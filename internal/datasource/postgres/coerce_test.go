@@ -0,0 +1,88 @@
+package postgres
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+func TestCoerce(t *testing.T) {
+	at := time.Date(2024, 5, 1, 12, 0, 0, 500, time.UTC)
+	cases := []struct {
+		value any
+		hint  string
+		want  any
+	}{
+		{nil, "", nil},
+		{nil, "int8", nil},
+		{7.0, "", int64(7)},
+		{7.5, "", 7.5},
+		{1e300, "", 1e300},
+		{"2024-05-01T12:00:00.0000005Z", "", at},
+		{"A0EEBC99-9C0B-4EF8-BB6D-6BB9BD380A11", "", "a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11"},
+		{"hello", "", "hello"},
+		{true, "", true},
+		{map[string]any{"a": 1.0}, "", `{"a":1}`},
+		{[]any{"a", 2.0}, "", pq.GenericArray{A: []any{"a", int64(2)}}},
+		{"2024-05-01T12:00:00Z", "text", "2024-05-01T12:00:00Z"},
+		{"42", "int8", int64(42)},
+		{42.0, " INTEGER ", int64(42)},
+		{"1.5", "float8", 1.5},
+		{3.0, "double precision", 3.0},
+		{"12.30", "numeric", "12.30"},
+		{0.1, "numeric", "0.1"},
+		{"true", "bool", true},
+		{false, "boolean", false},
+		{"2024-05-01T12:00:00.0000005Z", "timestamptz", at},
+		{"2024-05-01", "date", "2024-05-01"},
+		{"A0EEBC99-9C0B-4EF8-BB6D-6BB9BD380A11", "uuid", "a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11"},
+		{[]any{1.0, "x"}, "jsonb", `[1,"x"]`},
+		{"x", "json", `"x"`},
+		{[]any{"1", "2"}, "int8[]", pq.GenericArray{A: []any{int64(1), int64(2)}}},
+		{[]any{"2024-05-01T12:00:00Z"}, "text[]", pq.GenericArray{A: []any{"2024-05-01T12:00:00Z"}}},
+		{nil, "text[]", nil},
+	}
+	for _, c := range cases {
+		got, err := coerce(c.value, c.hint)
+		if err != nil {
+			t.Errorf("coerce(%#v, %q): %v", c.value, c.hint, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("coerce(%#v, %q) = %#v, want %#v", c.value, c.hint, got, c.want)
+		}
+	}
+}
+
+func TestCoerceRejects(t *testing.T) {
+	cases := []struct {
+		value any
+		hint  string
+	}{
+		{7.0, "text"},
+		{7.5, "int8"},
+		{1e300, "bigint"},
+		{"seven", "int4"},
+		{true, "float8"},
+		{"1,5", "numeric"},
+		{"yes please", "bool"},
+		{"2024-05-01", "timestamptz"},
+		{"05/01/2024", "date"},
+		{"not-a-uuid", "uuid"},
+		{"a", "text[]"},
+		{[]any{[]any{1.0}}, "int8[]"},
+		{[]any{map[string]any{}}, ""},
+		{[]any{"x"}, "int8[]"},
+	}
+	for _, c := range cases {
+		if got, err := coerce(c.value, c.hint); err == nil {
+			t.Errorf("coerce(%#v, %q) = %#v, want an error", c.value, c.hint, got)
+		}
+	}
+	if _, err := coerce("x", "money"); !errors.Is(err, errUnknownHint) {
+		t.Errorf("coerce with an unknown hint: error = %v, want errUnknownHint", err)
+	}
+}
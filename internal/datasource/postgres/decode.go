@@ -0,0 +1,336 @@
+// Package postgres
+// internal/datasource/postgres/decode.go
+package postgres
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Binary values are returned as {"$base64": "..."} so consumers can tell
+// them apart from text.
+const binaryMarker = "$base64"
+
+// rowDecoder turns rows into JSON-ready maps using each column's database
+// type, rather than emitting whatever the driver scanned.
+type rowDecoder struct {
+	// numericNumbers emits numeric columns as JSON numbers with their exact
+	// digits instead of as strings.
+	numericNumbers bool
+}
+
+// decodeFunc converts the value lib/pq scanned for one column.
+type decodeFunc func(v any) (any, error)
+
+// decode reads every row into a column-name keyed map.
+func (d rowDecoder) decode(rows *sql.Rows) ([]map[string]interface{}, error) {
+	var results []map[string]interface{}
+	err := d.each(rows, func(row map[string]interface{}) bool {
+		results = append(results, row)
+		return true
+	})
+	return results, err
+}
+
+// each decodes rows one at a time, stopping early when fn returns false.
+func (d rowDecoder) each(rows *sql.Rows, fn func(map[string]interface{}) bool) error {
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return err
+	}
+	columns := make([]string, len(types))
+	decoders := make([]decodeFunc, len(types))
+	for i, ct := range types {
+		columns[i] = ct.Name()
+		decoders[i] = d.column(ct.DatabaseTypeName())
+	}
+
+	values := make([]interface{}, len(columns))
+	valuePtrs := make([]interface{}, len(columns))
+	for i := range values {
+		valuePtrs[i] = &values[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(valuePtrs...); err != nil {
+			return err
+		}
+
+		rowMap := make(map[string]interface{}, len(columns))
+		for i, col := range columns {
+			if values[i] == nil {
+				rowMap[col] = nil
+				continue
+			}
+			v, err := decoders[i](values[i])
+			if err != nil {
+				return fmt.Errorf("failed to decode column '%s': %w", col, err)
+			}
+			rowMap[col] = v
+		}
+		if !fn(rowMap) {
+			return nil
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error during rows iteration: %w", err)
+	}
+	return nil
+}
+
+// column picks the decoder for a type name as reported by lib/pq ("INT4",
+// "JSONB", "_TEXT" for text[], "" for types it does not know).
+func (d rowDecoder) column(typ string) decodeFunc {
+	if elem, ok := strings.CutPrefix(typ, "_"); ok {
+		decodeElem := d.text(elem)
+		return func(v any) (any, error) {
+			return parseArray(asString(v), decodeElem)
+		}
+	}
+
+	switch typ {
+	case "BYTEA":
+		return func(v any) (any, error) {
+			b, ok := v.([]byte)
+			if !ok {
+				return nil, fmt.Errorf("unexpected %T for bytea", v)
+			}
+			return binary(b), nil
+		}
+	case "TIMESTAMPTZ", "TIMESTAMP", "DATE", "TIME", "TIMETZ":
+		return func(v any) (any, error) {
+			t, ok := v.(time.Time)
+			if !ok {
+				return asString(v), nil // infinity, -infinity
+			}
+			return formatTime(typ, t), nil
+		}
+	case "FLOAT4", "FLOAT8":
+		return func(v any) (any, error) {
+			if f, ok := v.(float64); ok {
+				return jsonFloat(f), nil
+			}
+			return d.text(typ)(asString(v))
+		}
+	case "INT2", "INT4", "INT8", "BOOL":
+		return func(v any) (any, error) {
+			if s, ok := v.([]byte); ok {
+				return d.text(typ)(string(s))
+			}
+			return v, nil
+		}
+	default:
+		decodeText := d.text(typ)
+		return func(v any) (any, error) {
+			return decodeText(asString(v))
+		}
+	}
+}
+
+// textFunc decodes a value from its Postgres text representation, as found
+// in array literals and in columns lib/pq leaves as raw bytes.
+type textFunc func(s string) (any, error)
+
+func (d rowDecoder) text(typ string) textFunc {
+	switch typ {
+	case "INT2", "INT4", "INT8", "OID":
+		return func(s string) (any, error) { return strconv.ParseInt(s, 10, 64) }
+	case "FLOAT4", "FLOAT8":
+		return func(s string) (any, error) {
+			f, err := strconv.ParseFloat(s, 64)
+			if err != nil {
+				return nil, err
+			}
+			return jsonFloat(f), nil
+		}
+	case "NUMERIC":
+		return func(s string) (any, error) {
+			// NaN and the infinities have no JSON number form.
+			if d.numericNumbers && json.Valid([]byte(s)) {
+				return json.Number(s), nil
+			}
+			return s, nil
+		}
+	case "BOOL":
+		return func(s string) (any, error) { return s == "t" || s == "true", nil }
+	case "JSON", "JSONB":
+		return func(s string) (any, error) { return json.RawMessage(s), nil }
+	case "UUID":
+		return func(s string) (any, error) { return strings.ToLower(s), nil }
+	case "BYTEA":
+		return func(s string) (any, error) {
+			b, err := hex.DecodeString(strings.TrimPrefix(s, `\x`))
+			if err != nil {
+				return nil, err
+			}
+			return binary(b), nil
+		}
+	case "TIMESTAMPTZ", "TIMESTAMP", "DATE":
+		return func(s string) (any, error) {
+			t, err := pq.ParseTimestamp(time.UTC, s)
+			if err != nil {
+				return s, nil // infinity, -infinity
+			}
+			return formatTime(typ, t), nil
+		}
+	default:
+		return func(s string) (any, error) { return s, nil }
+	}
+}
+
+func asString(v any) string {
+	switch v := v.(type) {
+	case []byte:
+		return string(v)
+	case string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func binary(b []byte) map[string]any {
+	return map[string]any{binaryMarker: base64.StdEncoding.EncodeToString(b)}
+}
+
+// jsonFloat keeps NaN and the infinities, which JSON cannot encode as
+// numbers, as their Postgres spelling.
+func jsonFloat(f float64) any {
+	switch {
+	case math.IsNaN(f):
+		return "NaN"
+	case math.IsInf(f, 1):
+		return "Infinity"
+	case math.IsInf(f, -1):
+		return "-Infinity"
+	}
+	return f
+}
+
+func formatTime(typ string, t time.Time) string {
+	switch typ {
+	case "DATE":
+		return t.Format(time.DateOnly)
+	case "TIME":
+		return t.Format("15:04:05.999999")
+	case "TIMETZ":
+		return t.Format("15:04:05.999999Z07:00")
+	case "TIMESTAMP":
+		// No time zone: emit the wall clock without an offset.
+		return t.Format("2006-01-02T15:04:05.999999")
+	default:
+		return t.Format(time.RFC3339Nano)
+	}
+}
+
+// parseArray parses a Postgres array literal such as {1,NULL,"a b"} or
+// {{1,2},{3,4}} into nested slices, decoding each element with elem.
+func parseArray(s string, elem textFunc) (any, error) {
+	// Arrays with non-default bounds are prefixed with "[1:2]=".
+	if strings.HasPrefix(s, "[") {
+		if i := strings.Index(s, "={"); i >= 0 {
+			s = s[i+1:]
+		}
+	}
+	p := arrayParser{s: s, elem: elem}
+	v, err := p.array()
+	if err != nil {
+		return nil, err
+	}
+	if p.i != len(s) {
+		return nil, fmt.Errorf("trailing data in array literal")
+	}
+	return v, nil
+}
+
+type arrayParser struct {
+	s    string
+	i    int
+	elem textFunc
+}
+
+func (p *arrayParser) array() ([]any, error) {
+	if p.i >= len(p.s) || p.s[p.i] != '{' {
+		return nil, fmt.Errorf("malformed array literal")
+	}
+	p.i++
+	out := []any{}
+	if p.i < len(p.s) && p.s[p.i] == '}' {
+		p.i++
+		return out, nil
+	}
+	for {
+		if p.i >= len(p.s) {
+			return nil, fmt.Errorf("unterminated array literal")
+		}
+		var (
+			v   any
+			err error
+		)
+		switch p.s[p.i] {
+		case '{':
+			v, err = p.array()
+		case '"':
+			v, err = p.quoted()
+		default:
+			v, err = p.bare()
+		}
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+
+		if p.i >= len(p.s) {
+			return nil, fmt.Errorf("unterminated array literal")
+		}
+		switch p.s[p.i] {
+		case ',':
+			p.i++
+		case '}':
+			p.i++
+			return out, nil
+		default:
+			return nil, fmt.Errorf("malformed array literal")
+		}
+	}
+}
+
+func (p *arrayParser) quoted() (any, error) {
+	var b strings.Builder
+	for p.i++; p.i < len(p.s); p.i++ {
+		switch c := p.s[p.i]; c {
+		case '\\':
+			p.i++
+			if p.i < len(p.s) {
+				b.WriteByte(p.s[p.i])
+			}
+		case '"':
+			p.i++
+			return p.elem(b.String())
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return nil, fmt.Errorf("unterminated array element")
+}
+
+func (p *arrayParser) bare() (any, error) {
+	start := p.i
+	for p.i < len(p.s) && p.s[p.i] != ',' && p.s[p.i] != '}' {
+		p.i++
+	}
+	s := strings.TrimSpace(p.s[start:p.i])
+	if strings.EqualFold(s, "NULL") {
+		return nil, nil
+	}
+	return p.elem(s)
+}
@@ -0,0 +1,130 @@
+package postgres
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRowDecoder(t *testing.T) {
+	at := time.Date(2024, 5, 1, 12, 30, 0, 500000, time.FixedZone("", 2*60*60))
+	cases := []struct {
+		typ     string
+		value   driver.Value // as lib/pq scans it
+		want    any
+		numbers any // with numericNumbers, if different
+	}{
+		{"INT4", int64(7), int64(7), nil},
+		{"INT8", []byte("9007199254740993"), int64(9007199254740993), nil},
+		{"BOOL", true, true, nil},
+		{"FLOAT8", 1.5, 1.5, nil},
+		{"FLOAT8", math.NaN(), "NaN", nil},
+		{"FLOAT4", math.Inf(-1), "-Infinity", nil},
+		{"NUMERIC", []byte("12.30"), "12.30", json.Number("12.30")},
+		{"NUMERIC", []byte("NaN"), "NaN", "NaN"},
+		{"TEXT", "hello", "hello", nil},
+		{"UUID", []byte("A0EEBC99-9C0B-4EF8-BB6D-6BB9BD380A11"), "a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11", nil},
+		{"JSONB", []byte(`{"a": [1, 2]}`), json.RawMessage(`{"a": [1, 2]}`), nil},
+		{"BYTEA", []byte{0xde, 0xad}, map[string]any{binaryMarker: "3q0="}, nil},
+		{"TIMESTAMPTZ", at, "2024-05-01T12:30:00.0005+02:00", nil},
+		{"TIMESTAMP", at, "2024-05-01T12:30:00.0005", nil},
+		{"TIMESTAMPTZ", []byte("infinity"), "infinity", nil},
+		{"DATE", at, "2024-05-01", nil},
+		{"TIME", at, "12:30:00.0005", nil},
+		{"TIMETZ", at, "12:30:00.0005+02:00", nil},
+		{"_TEXT", []byte(`{a,"b c",NULL,"say \"hi\""}`), []any{"a", "b c", nil, `say "hi"`}, nil},
+		{"_INT4", []byte(`{{1,2},{3,4}}`), []any{[]any{int64(1), int64(2)}, []any{int64(3), int64(4)}}, nil},
+		{"_INT4", []byte(`[0:1]={5,6}`), []any{int64(5), int64(6)}, nil},
+		{"_NUMERIC", []byte(`{1.10,NULL}`), []any{"1.10", nil}, []any{json.Number("1.10"), nil}},
+		{"_BOOL", []byte(`{t,f}`), []any{true, false}, nil},
+		{"_BYTEA", []byte(`{"\\x6869"}`), []any{map[string]any{binaryMarker: "aGk="}}, nil},
+		{"_DATE", []byte(`{2024-05-01}`), []any{"2024-05-01"}, nil},
+		{"_TEXT", []byte(`{}`), []any{}, nil},
+		{"INTERVAL", []byte("1 day"), "1 day", nil},
+		{"", []byte("unknown"), "unknown", nil},
+		{"TEXT", nil, nil, nil},
+	}
+	for _, numbers := range []bool{false, true} {
+		for _, c := range cases {
+			db := &fakeDB{results: map[string]fakeResult{"q": {
+				columns: []string{"v"},
+				types:   []string{c.typ},
+				rows:    [][]driver.Value{{c.value}},
+			}}}
+			rows, err := db.open().QueryContext(context.Background(), "q")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := rowDecoder{numericNumbers: numbers}.decode(rows)
+			rows.Close()
+			if err != nil {
+				t.Errorf("%s %v: %v", c.typ, c.value, err)
+				continue
+			}
+			want := c.want
+			if numbers && c.numbers != nil {
+				want = c.numbers
+			}
+			if len(got) != 1 || !reflect.DeepEqual(got[0]["v"], want) {
+				t.Errorf("%s %v (numeric numbers %v): decoded %#v, want %#v", c.typ, c.value, numbers, got, want)
+			}
+		}
+	}
+}
+
+func TestRowDecoderRejectsMalformedValues(t *testing.T) {
+	cases := []struct {
+		typ   string
+		value driver.Value
+	}{
+		{"INT4", []byte("seven")},
+		{"FLOAT8", []byte("1,5")},
+		{"BYTEA", "not bytes"},
+		{"_INT4", []byte(`{1,2`)},
+		{"_INT4", []byte(`{1,x}`)},
+		{"_TEXT", []byte(`{a}b`)},
+		{"_TEXT", []byte(`{"a}`)},
+		{"_BYTEA", []byte(`{"\\xzz"}`)},
+	}
+	for _, c := range cases {
+		db := &fakeDB{results: map[string]fakeResult{"q": {
+			columns: []string{"v"},
+			types:   []string{c.typ},
+			rows:    [][]driver.Value{{c.value}},
+		}}}
+		rows, err := db.open().QueryContext(context.Background(), "q")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, err := (rowDecoder{}).decode(rows); err == nil {
+			t.Errorf("%s %v: decoded %#v, want an error", c.typ, c.value, got)
+		}
+		rows.Close()
+	}
+}
+
+func TestRowDecoderEachStops(t *testing.T) {
+	db := &fakeDB{results: map[string]fakeResult{"q": {
+		columns: []string{"id", "email"},
+		types:   []string{"INT4", "TEXT"},
+		rows:    [][]driver.Value{{int64(1), "a@example.com"}, {int64(2), "b@example.com"}, {int64(3), nil}},
+	}}}
+	rows, err := db.open().QueryContext(context.Background(), "q")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var got []map[string]any
+	err = rowDecoder{}.each(rows, func(row map[string]any) bool {
+		got = append(got, row)
+		return len(got) < 2
+	})
+	want := []map[string]any{{"id": int64(1), "email": "a@example.com"}, {"id": int64(2), "email": "b@example.com"}}
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Fatalf("each = %v, %v; want %v", got, err, want)
+	}
+}
@@ -0,0 +1,44 @@
+// Package postgres
+// internal/datasource/postgres/describe.go
+package postgres
+
+import (
+	"context"
+	"strings"
+
+	"github.com/thegodeveloper/data-gateway/internal/domain"
+)
+
+// udtTypes maps catalog type names to the types their decoded values have.
+// Types not listed decode to text.
+var udtTypes = map[string]domain.FieldType{
+	"int2": domain.FieldInt, "int4": domain.FieldInt,
+	"int8": domain.FieldBigInt, "oid": domain.FieldBigInt,
+	"float4": domain.FieldFloat, "float8": domain.FieldFloat,
+	"numeric": domain.FieldDecimal,
+	"bool":    domain.FieldBool,
+	"date":    domain.FieldTime, "time": domain.FieldTime, "timetz": domain.FieldTime,
+	"timestamp": domain.FieldTime, "timestamptz": domain.FieldTime,
+	"json": domain.FieldJSON, "jsonb": domain.FieldJSON, "bytea": domain.FieldJSON,
+}
+
+// Describe reports the columns of a table from the catalog, in ordinal
+// order. Arrays are reported as JSON.
+func (p *PostgresSource) Describe(ctx context.Context, req domain.DescribeRequest) (*domain.TableSchema, error) {
+	e, err := p.expose(ctx, req.Target, nil)
+	if err != nil {
+		return nil, err
+	}
+	schema := &domain.TableSchema{Fields: make([]domain.Field, 0, len(e.order)), Key: e.key}
+	for _, col := range e.order {
+		typ, ok := udtTypes[e.types[col]]
+		switch {
+		case strings.HasPrefix(e.types[col], "_"):
+			typ = domain.FieldJSON
+		case !ok:
+			typ = domain.FieldString
+		}
+		schema.Fields = append(schema.Fields, domain.Field{Name: col, Type: typ})
+	}
+	return schema, nil
+}
@@ -0,0 +1,169 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// fakeDB is a database/sql driver that logs the calls it gets ("prepare q",
+// "query q", "exec q", "close q", "begin", "commit", "rollback") and answers
+// queries with canned results, or fails them with canned errors.
+type fakeDB struct {
+	results map[string]fakeResult
+	errs    map[string]error
+
+	mu  sync.Mutex
+	log []string
+}
+
+// fakeResult is the rows of a query, with column types named the way lib/pq
+// reports them ("INT4", "_TEXT", ...).
+type fakeResult struct {
+	columns []string
+	types   []string
+	rows    [][]driver.Value
+}
+
+// open returns a pool over db with a single connection, so statements
+// prepared outside a transaction are reused inside one.
+func (db *fakeDB) open() *sql.DB {
+	pool := sql.OpenDB(db)
+	pool.SetMaxOpenConns(1)
+	return pool
+}
+
+func (db *fakeDB) record(format string, args ...any) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.log = append(db.log, fmt.Sprintf(format, args...))
+}
+
+// calls returns the logged calls that start with prefix.
+func (db *fakeDB) calls(prefix string) []string {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	var out []string
+	for _, call := range db.log {
+		if strings.HasPrefix(call, prefix) {
+			out = append(out, call)
+		}
+	}
+	return out
+}
+
+func (db *fakeDB) Connect(context.Context) (driver.Conn, error) { return fakeConn{db}, nil }
+func (db *fakeDB) Driver() driver.Driver                        { return db }
+func (db *fakeDB) Open(string) (driver.Conn, error)             { return fakeConn{db}, nil }
+
+type fakeConn struct{ db *fakeDB }
+
+func (c fakeConn) Prepare(query string) (driver.Stmt, error) {
+	c.db.record("prepare %s", query)
+	return fakeStmt{c.db, query}, nil
+}
+
+func (c fakeConn) Close() error { return nil }
+func (c fakeConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c fakeConn) BeginTx(context.Context, driver.TxOptions) (driver.Tx, error) {
+	c.db.record("begin")
+	return fakeTx{c.db}, nil
+}
+
+// CheckNamedValue passes arguments through unconverted, so tests see what
+// the adapter bound.
+func (c fakeConn) CheckNamedValue(*driver.NamedValue) error { return nil }
+
+func (c fakeConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	return c.db.query(query, args)
+}
+
+func (c fakeConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	return c.db.exec(query, args)
+}
+
+func (db *fakeDB) query(query string, args []driver.NamedValue) (driver.Rows, error) {
+	db.record("query %s%s", query, formatArgs(args))
+	if err := db.errs[query]; err != nil {
+		return nil, err
+	}
+	return &fakeRows{result: db.results[query]}, nil
+}
+
+func (db *fakeDB) exec(query string, args []driver.NamedValue) (driver.Result, error) {
+	db.record("exec %s%s", query, formatArgs(args))
+	if err := db.errs[query]; err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func formatArgs(args []driver.NamedValue) string {
+	if len(args) == 0 {
+		return ""
+	}
+	values := make([]any, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+	return fmt.Sprint(" ", values)
+}
+
+type fakeStmt struct {
+	db    *fakeDB
+	query string
+}
+
+func (s fakeStmt) Close() error {
+	s.db.record("close %s", s.query)
+	return nil
+}
+
+func (s fakeStmt) NumInput() int                              { return -1 }
+func (s fakeStmt) CheckNamedValue(*driver.NamedValue) error   { return nil }
+func (s fakeStmt) Exec([]driver.Value) (driver.Result, error) { panic("unused") }
+func (s fakeStmt) Query([]driver.Value) (driver.Rows, error)  { panic("unused") }
+func (s fakeStmt) ExecContext(_ context.Context, args []driver.NamedValue) (driver.Result, error) {
+	return s.db.exec(s.query, args)
+}
+
+func (s fakeStmt) QueryContext(_ context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	return s.db.query(s.query, args)
+}
+
+type fakeTx struct{ db *fakeDB }
+
+func (tx fakeTx) Commit() error {
+	tx.db.record("commit")
+	return nil
+}
+
+func (tx fakeTx) Rollback() error {
+	tx.db.record("rollback")
+	return nil
+}
+
+type fakeRows struct {
+	result fakeResult
+	i      int
+}
+
+func (r *fakeRows) Columns() []string                       { return r.result.columns }
+func (r *fakeRows) Close() error                            { return nil }
+func (r *fakeRows) ColumnTypeDatabaseTypeName(i int) string { return r.result.types[i] }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.i == len(r.result.rows) {
+		return io.EOF
+	}
+	copy(dest, r.result.rows[r.i])
+	r.i++
+	return nil
+}
@@ -0,0 +1,393 @@
+// Package postgres
+// internal/datasource/postgres/feed.go
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/thegodeveloper/data-gateway/internal/domain"
+	"github.com/thegodeveloper/data-gateway/pkg/common"
+)
+
+// readBatch is how many changes one read of the slot asks for. The server
+// finishes the transaction it is in, so a read may return more.
+const readBatch = 1000
+
+// walPosition orders row changes: the commit LSN of their transaction, then
+// their index in it. Changes are decoded in commit order, so unlike the LSN
+// of each change, positions only grow.
+type walPosition struct {
+	commit uint64
+	index  int
+}
+
+func (p walPosition) less(o walPosition) bool {
+	return p.commit < o.commit || p.commit == o.commit && p.index < o.index
+}
+
+// String renders the position as "LSN:index", e.g. "0/16B3748:2".
+func (p walPosition) String() string {
+	return fmt.Sprintf("%X/%X:%d", p.commit>>32, uint32(p.commit), p.index)
+}
+
+func parseWalPosition(s string) (walPosition, error) {
+	lsnText, indexText, ok := strings.Cut(s, ":")
+	if !ok {
+		return walPosition{}, fmt.Errorf("%w: invalid resume position '%s'", domain.ErrInvalidRequest, s)
+	}
+	lsn, err := parseLSN(lsnText)
+	if err != nil {
+		return walPosition{}, fmt.Errorf("%w: invalid resume position '%s'", domain.ErrInvalidRequest, s)
+	}
+	index, err := strconv.Atoi(indexText)
+	if err != nil || index < 0 {
+		return walPosition{}, fmt.Errorf("%w: invalid resume position '%s'", domain.ErrInvalidRequest, s)
+	}
+	return walPosition{commit: lsn, index: index}, nil
+}
+
+// parseLSN reads PostgreSQL's "hi/lo" hexadecimal form of a WAL position.
+func parseLSN(s string) (uint64, error) {
+	hiText, loText, ok := strings.Cut(s, "/")
+	if !ok {
+		return 0, fmt.Errorf("invalid LSN '%s'", s)
+	}
+	hi, err := strconv.ParseUint(hiText, 16, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid LSN '%s'", s)
+	}
+	lo, err := strconv.ParseUint(loText, 16, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid LSN '%s'", s)
+	}
+	return hi<<32 | lo, nil
+}
+
+// rowChange is a decoded change with the configured name of its table.
+type rowChange struct {
+	pos    walPosition
+	table  string
+	change domain.Change
+}
+
+// rowFeed reads row changes from a wal2json replication slot and fans them
+// out to every subscriber. The gateway is the slot's only consumer: changes
+// are consumed as they are read and the most recent ones are kept in memory,
+// so subscribers can resume from any position still buffered.
+type rowFeed struct {
+	db        *sql.DB
+	slot      string
+	tables    map[string]string // "schema.table" or "*.table" -> configured name
+	addTables string
+	poll      time.Duration
+	size      int
+	stop      context.CancelFunc
+	done      chan struct{}
+
+	mu      sync.Mutex
+	changes []rowChange // oldest first
+	// floor is the newest position no longer buffered. Resuming from before
+	// it would skip changes.
+	floor walPosition
+	// wake is closed when changes are added.
+	wake chan struct{}
+}
+
+// newRowFeed checks the slot, creating it if asked to, and starts reading
+// it. Positions from before the start cannot be resumed from: an earlier
+// process consumed those changes.
+func newRowFeed(ctx context.Context, db *sql.DB, cfg Changes) (*rowFeed, error) {
+	f := &rowFeed{
+		db:     db,
+		slot:   cfg.Slot,
+		tables: make(map[string]string, len(cfg.Tables)),
+		poll:   cfg.PollInterval,
+		size:   cfg.Buffer,
+		done:   make(chan struct{}),
+		wake:   make(chan struct{}),
+	}
+	if f.poll <= 0 {
+		f.poll = time.Second
+	}
+	if f.size <= 0 {
+		f.size = 10000
+	}
+	patterns := make([]string, 0, len(cfg.Tables))
+	for _, name := range cfg.Tables {
+		t, err := parseTable(name)
+		if err != nil {
+			return nil, fmt.Errorf("invalid change feed table '%s'", name)
+		}
+		pattern := "*." + t.name
+		if t.schema != "" {
+			pattern = t.String()
+		}
+		f.tables[pattern] = name
+		patterns = append(patterns, pattern)
+	}
+	f.addTables = strings.Join(patterns, ",")
+
+	var confirmed sql.NullString
+	err := db.QueryRowContext(ctx, `SELECT confirmed_flush_lsn::text FROM pg_replication_slots WHERE slot_name = $1`, f.slot).Scan(&confirmed)
+	if err == sql.ErrNoRows && cfg.CreateSlot {
+		err = db.QueryRowContext(ctx, `SELECT lsn::text FROM pg_create_logical_replication_slot($1, 'wal2json')`, f.slot).Scan(&confirmed)
+	}
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("replication slot '%s' does not exist", f.slot)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to check replication slot '%s': %w", f.slot, err)
+	}
+	if confirmed.Valid {
+		lsn, err := parseLSN(confirmed.String)
+		if err != nil {
+			return nil, err
+		}
+		f.floor = walPosition{commit: lsn, index: int(^uint(0) >> 1)}
+	}
+
+	runCtx, stop := context.WithCancel(context.Background())
+	f.stop = stop
+	go f.run(runCtx)
+	return f, nil
+}
+
+// close stops reading the slot. Subscribers keep what was buffered.
+func (f *rowFeed) close() {
+	f.stop()
+	<-f.done
+}
+
+func (f *rowFeed) run(ctx context.Context) {
+	defer close(f.done)
+	for {
+		n, err := f.read(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			common.Error("reading replication slot '%s': %v", f.slot, err)
+		}
+		if err == nil && n >= readBatch {
+			continue
+		}
+		select {
+		case <-time.After(f.poll):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// walMessage is one wal2json format-version 2 message.
+type walMessage struct {
+	Action    string      `json:"action"`
+	Schema    string      `json:"schema"`
+	Table     string      `json:"table"`
+	Timestamp string      `json:"timestamp"`
+	Columns   []walColumn `json:"columns"`
+	Identity  []walColumn `json:"identity"`
+	PK        []walColumn `json:"pk"`
+}
+
+type walColumn struct {
+	Name  string `json:"name"`
+	Value any    `json:"value"`
+}
+
+// walOps maps wal2json actions to change kinds.
+var walOps = map[string]domain.ChangeOp{
+	"I": domain.ChangeInsert,
+	"U": domain.ChangeUpdate,
+	"D": domain.ChangeDelete,
+	"T": "truncate",
+}
+
+// read consumes the next changes from the slot and buffers the ones of
+// committed transactions. It returns how many messages it read.
+func (f *rowFeed) read(ctx context.Context) (int, error) {
+	rows, err := f.db.QueryContext(ctx, `SELECT lsn::text, data FROM pg_logical_slot_get_changes($1, NULL, $2,
+		'format-version', '2', 'include-transaction', 'true', 'include-pk', 'true',
+		'include-timestamp', 'true', 'add-tables', $3)`, f.slot, readBatch, f.addTables)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	var (
+		n       int
+		pending []rowChange
+		decoded []rowChange
+	)
+	for rows.Next() {
+		var lsn, data string
+		if err := rows.Scan(&lsn, &data); err != nil {
+			return n, err
+		}
+		n++
+		dec := json.NewDecoder(strings.NewReader(data))
+		// Numbers keep their exact digits, as numeric columns do in queries.
+		dec.UseNumber()
+		var msg walMessage
+		if err := dec.Decode(&msg); err != nil {
+			return n, fmt.Errorf("invalid wal2json message at %s: %w", lsn, err)
+		}
+
+		switch msg.Action {
+		case "B":
+			pending = pending[:0]
+		case "C":
+			commit, err := parseLSN(lsn)
+			if err != nil {
+				return n, err
+			}
+			for i := range pending {
+				pending[i].pos = walPosition{commit: commit, index: i}
+				pending[i].change.ID = pending[i].pos.String()
+				if pending[i].change.Time.IsZero() {
+					pending[i].change.Time = walTime(msg.Timestamp)
+				}
+			}
+			decoded = append(decoded, pending...)
+			pending = nil
+		default:
+			if rc, ok := f.decode(msg); ok {
+				pending = append(pending, rc)
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return n, err
+	}
+	f.add(decoded)
+	return n, nil
+}
+
+// decode converts a row message of a configured table.
+func (f *rowFeed) decode(msg walMessage) (rowChange, bool) {
+	op, ok := walOps[msg.Action]
+	if !ok {
+		return rowChange{}, false
+	}
+	name, ok := f.tables[msg.Schema+"."+msg.Table]
+	if !ok {
+		if name, ok = f.tables["*."+msg.Table]; !ok {
+			return rowChange{}, false
+		}
+	}
+
+	change := domain.Change{Op: op, Target: name, Time: walTime(msg.Timestamp)}
+	columns := walValues(msg.Columns)
+	switch op {
+	case domain.ChangeInsert, domain.ChangeUpdate:
+		change.Document = columns
+		if len(msg.PK) > 0 {
+			change.Key = make(map[string]any, len(msg.PK))
+			for _, pk := range msg.PK {
+				change.Key[pk.Name] = columns[pk.Name]
+			}
+		}
+		change.Before = walValues(msg.Identity)
+	case domain.ChangeDelete:
+		change.Key = walValues(msg.Identity)
+	}
+	return rowChange{table: name, change: change}, true
+}
+
+func walValues(columns []walColumn) map[string]any {
+	if len(columns) == 0 {
+		return nil
+	}
+	values := make(map[string]any, len(columns))
+	for _, c := range columns {
+		values[c.Name] = c.Value
+	}
+	return values
+}
+
+// walTime parses wal2json's timestamptz text; an unknown form leaves the
+// time unset.
+func walTime(s string) time.Time {
+	for _, layout := range []string{"2006-01-02 15:04:05.999999-07", "2006-01-02 15:04:05.999999-07:00"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.UTC()
+		}
+	}
+	return time.Time{}
+}
+
+// add buffers changes, drops the oldest beyond the buffer size and wakes
+// the subscribers.
+func (f *rowFeed) add(changes []rowChange) {
+	if len(changes) == 0 {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.changes = append(f.changes, changes...)
+	if drop := len(f.changes) - f.size; drop > 0 {
+		f.floor = f.changes[drop-1].pos
+		f.changes = append([]rowChange(nil), f.changes[drop:]...)
+	}
+	close(f.wake)
+	f.wake = make(chan struct{})
+}
+
+// latest is the position of the newest buffered change.
+func (f *rowFeed) latest() walPosition {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.changes) == 0 {
+		return f.floor
+	}
+	return f.changes[len(f.changes)-1].pos
+}
+
+// since returns the buffered changes after pos and a channel closed when
+// more arrive. ok is false when changes after pos were already dropped.
+func (f *rowFeed) since(pos walPosition) (changes []rowChange, wake <-chan struct{}, ok bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if pos.less(f.floor) {
+		return nil, nil, false
+	}
+	i := sort.Search(len(f.changes), func(i int) bool { return pos.less(f.changes[i].pos) })
+	return append([]rowChange(nil), f.changes[i:]...), f.wake, true
+}
+
+// subscribe streams the changes after pos of the given tables, each mapped
+// to its exposed columns (nil meaning all).
+func (f *rowFeed) subscribe(ctx context.Context, pos walPosition, tables map[string]map[string]bool) domain.ChangeStream {
+	return func(yield func(domain.Change, error) bool) {
+		for {
+			changes, wake, ok := f.since(pos)
+			if !ok {
+				yield(domain.Change{}, fmt.Errorf("change feed moved past position %s; changes were dropped", pos))
+				return
+			}
+			for _, rc := range changes {
+				pos = rc.pos
+				columns, ok := tables[rc.table]
+				if !ok {
+					continue
+				}
+				if !yield(rc.change.Expose(columns), nil) {
+					return
+				}
+			}
+			select {
+			case <-wake:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/thegodeveloper/data-gateway/internal/domain"
+)
+
+func TestParseWalPosition(t *testing.T) {
+	valid := []struct {
+		in   string
+		want walPosition
+	}{
+		{"0/16B3748:2", walPosition{commit: 0x16B3748, index: 2}},
+		{"0/16b3748:2", walPosition{commit: 0x16B3748, index: 2}},
+		{"1/0:0", walPosition{commit: 1 << 32}},
+		{"FFFFFFFF/FFFFFFFF:10", walPosition{commit: 1<<64 - 1, index: 10}},
+	}
+	for _, tt := range valid {
+		got, err := parseWalPosition(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("parseWalPosition(%q) = %v, %v; want %v", tt.in, got, err, tt.want)
+			continue
+		}
+		if back, err := parseWalPosition(got.String()); err != nil || back != got {
+			t.Errorf("parseWalPosition(%q) does not round-trip: %v, %v", got.String(), back, err)
+		}
+	}
+
+	invalid := []string{"", "0/16B3748", "16B3748:1", "0/XYZ:1", "G/0:1", "0/1:-1", "0/1:x", "0/1:", "100000000/0:0", "0/100000000:0", "0/1:2:3"}
+	for _, in := range invalid {
+		if got, err := parseWalPosition(in); !errors.Is(err, domain.ErrInvalidRequest) {
+			t.Errorf("parseWalPosition(%q) = %v, %v; want ErrInvalidRequest", in, got, err)
+		}
+	}
+}
+
+func testRowFeed(size int, start walPosition) *rowFeed {
+	return &rowFeed{size: size, floor: start, wake: make(chan struct{})}
+}
+
+func change(table string, commit uint64, index int) rowChange {
+	return rowChange{
+		pos:    walPosition{commit: commit, index: index},
+		table:  table,
+		change: domain.Change{Op: domain.ChangeInsert, Target: table, Document: map[string]any{"id": index, "secret": "x"}},
+	}
+}
+
+func positions(changes []rowChange) []walPosition {
+	var out []walPosition
+	for _, c := range changes {
+		out = append(out, c.pos)
+	}
+	return out
+}
+
+func TestRowFeedSince(t *testing.T) {
+	start := walPosition{commit: 1}
+	f := testRowFeed(3, start)
+	if got := f.latest(); got != start {
+		t.Fatalf("latest of an empty feed = %v, want the start %v", got, start)
+	}
+	f.add([]rowChange{change("users", 2, 0), change("users", 2, 1), change("orders", 3, 0)})
+	f.add([]rowChange{change("users", 4, 0), change("users", 4, 1)})
+
+	pos := func(commit uint64, index int) walPosition { return walPosition{commit: commit, index: index} }
+	tests := []struct {
+		name string
+		from walPosition
+		want []walPosition
+		ok   bool
+	}{
+		{"before the start", pos(0, 5), nil, false},
+		{"the start, since dropped", start, nil, false},
+		{"a dropped change", pos(2, 0), nil, false},
+		{"the floor", pos(2, 1), []walPosition{pos(3, 0), pos(4, 0), pos(4, 1)}, true},
+		{"inside a transaction", pos(4, 0), []walPosition{pos(4, 1)}, true},
+		{"between buffered changes", pos(3, 7), []walPosition{pos(4, 0), pos(4, 1)}, true},
+		{"the latest", pos(4, 1), nil, true},
+		{"ahead of the feed", pos(9, 0), nil, true},
+	}
+	for _, tt := range tests {
+		changes, wake, ok := f.since(tt.from)
+		if ok != tt.ok || !reflect.DeepEqual(positions(changes), tt.want) {
+			t.Errorf("%s: since(%v) = %v, %v; want %v, %v", tt.name, tt.from, positions(changes), ok, tt.want, tt.ok)
+		}
+		if ok && wake == nil {
+			t.Errorf("%s: no wake channel", tt.name)
+		}
+	}
+	if got := f.latest(); got != pos(4, 1) {
+		t.Errorf("latest = %v, want 4:1", got)
+	}
+}
+
+func TestRowFeedSubscribe(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	f := testRowFeed(10, walPosition{commit: 1})
+	f.add([]rowChange{change("users", 2, 0), change("orders", 2, 1)})
+
+	stream := f.subscribe(ctx, walPosition{commit: 1}, map[string]map[string]bool{"users": {"id": true}})
+	var got []domain.Change
+	for c, err := range stream {
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, c)
+		if len(got) == 1 {
+			// Arrives after the subscriber caught up.
+			go f.add([]rowChange{change("users", 3, 0)})
+		}
+		if len(got) == 2 {
+			break
+		}
+	}
+	want := []map[string]any{{"id": 0}, {"id": 0}}
+	if len(got) != 2 || !reflect.DeepEqual(got[0].Document, want[0]) || !reflect.DeepEqual(got[1].Document, want[1]) {
+		t.Fatalf("changes = %+v, want the two users changes without their hidden columns", got)
+	}
+
+	f = testRowFeed(1, walPosition{commit: 1})
+	f.add([]rowChange{change("users", 2, 0), change("users", 3, 0)})
+	for _, err := range f.subscribe(ctx, walPosition{commit: 1}, map[string]map[string]bool{"users": nil}) {
+		if err == nil {
+			t.Fatal("a subscriber from before the floor got changes")
+		}
+		break
+	}
+}
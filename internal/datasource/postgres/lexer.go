@@ -0,0 +1,128 @@
+// Package postgres
+// internal/datasource/postgres/lexer.go
+package postgres
+
+import (
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	tokWord     tokenKind = iota // keyword or unquoted identifier, lowercased
+	tokQuoted                    // "quoted identifier", unescaped
+	tokString                    // string literal of any form
+	tokNumber                    // numeric literal
+	tokParam                     // $n parameter
+	tokOperator                  // operators and other punctuation
+	tokLParen
+	tokRParen
+	tokComma
+	tokDot
+	tokSemicolon
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+// lex splits SQL into tokens following Postgres' lexical rules: unquoted
+// identifiers fold to lower case, quoted identifiers keep their case, and
+// comments, string literals (plain, E-prefixed and dollar-quoted) and casts
+// are recognised so their contents can never be mistaken for keywords.
+func lex(sql string) []token {
+	var tokens []token
+	for i := 0; i < len(sql); {
+		c := sql[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f':
+			i++
+		case c == '-' && strings.HasPrefix(sql[i:], "--"):
+			end := strings.IndexByte(sql[i:], '\n')
+			if end < 0 {
+				return tokens
+			}
+			i += end
+		case c == '/' && strings.HasPrefix(sql[i:], "/*"):
+			i = skipBlockComment(sql, i)
+		case c == '\'':
+			end := skipQuoted(sql, i)
+			tokens = append(tokens, token{kind: tokString, text: sql[i:end]})
+			i = end
+		case c == '"':
+			end := skipQuoted(sql, i)
+			inner := sql[i+1 : max(end-1, i+1)]
+			tokens = append(tokens, token{kind: tokQuoted, text: strings.ReplaceAll(inner, `""`, `"`)})
+			i = end
+		case c == '$' && dollarTag(sql[i:]) != "":
+			tag := dollarTag(sql[i:])
+			end := strings.Index(sql[i+len(tag):], tag)
+			if end < 0 {
+				end = len(sql)
+			} else {
+				end = i + len(tag) + end + len(tag)
+			}
+			tokens = append(tokens, token{kind: tokString, text: sql[i:end]})
+			i = end
+		case c == '$' && i+1 < len(sql) && sql[i+1] >= '0' && sql[i+1] <= '9':
+			j := i + 1
+			for j < len(sql) && sql[j] >= '0' && sql[j] <= '9' {
+				j++
+			}
+			tokens = append(tokens, token{kind: tokParam, text: sql[i:j]})
+			i = j
+		case isIdentStart(c) || c >= 0x80:
+			j := i + 1
+			for j < len(sql) && (isIdentPart(sql[j]) || sql[j] == '$' || sql[j] >= 0x80) {
+				j++
+			}
+			// E'...', B'...', X'...' and U&'...' prefixes belong to the literal.
+			if j < len(sql) && sql[j] == '\'' && j-i == 1 {
+				end := skipQuoted(sql, j)
+				tokens = append(tokens, token{kind: tokString, text: sql[i:end]})
+				i = end
+				continue
+			}
+			if j-i == 1 && (c == 'u' || c == 'U') && strings.HasPrefix(sql[j:], "&'") {
+				end := skipQuoted(sql, j+1)
+				tokens = append(tokens, token{kind: tokString, text: sql[i:end]})
+				i = end
+				continue
+			}
+			tokens = append(tokens, token{kind: tokWord, text: strings.ToLower(sql[i:j])})
+			i = j
+		case c >= '0' && c <= '9' || (c == '.' && i+1 < len(sql) && sql[i+1] >= '0' && sql[i+1] <= '9'):
+			j := i + 1
+			for j < len(sql) && (isIdentPart(sql[j]) || sql[j] == '.') {
+				j++
+			}
+			tokens = append(tokens, token{kind: tokNumber, text: sql[i:j]})
+			i = j
+		case c == '(':
+			tokens = append(tokens, token{kind: tokLParen, text: "("})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{kind: tokRParen, text: ")"})
+			i++
+		case c == ',':
+			tokens = append(tokens, token{kind: tokComma, text: ","})
+			i++
+		case c == '.':
+			tokens = append(tokens, token{kind: tokDot, text: "."})
+			i++
+		case c == ';':
+			tokens = append(tokens, token{kind: tokSemicolon, text: ";"})
+			i++
+		default:
+			j := i + 1
+			for j < len(sql) && strings.IndexByte("+-*/<>=~!@#%^&|`?:[]", sql[j]) >= 0 &&
+				!strings.HasPrefix(sql[j:], "--") && !strings.HasPrefix(sql[j:], "/*") {
+				j++
+			}
+			tokens = append(tokens, token{kind: tokOperator, text: sql[i:j]})
+			i = j
+		}
+	}
+	return tokens
+}
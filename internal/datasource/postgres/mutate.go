@@ -0,0 +1,295 @@
+// Package postgres
+// internal/datasource/postgres/mutate.go
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/thegodeveloper/data-gateway/internal/domain"
+)
+
+// execer is satisfied by *sql.DB and *sql.Tx.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// Mutate inserts, updates, upserts or deletes rows of an exposed table. Only
+// columns the catalog reports and the route's allow-list exposes can be
+// written, filtered on or returned.
+func (p *PostgresSource) Mutate(ctx context.Context, req domain.MutationRequest) (*domain.MutationResult, error) {
+	return p.mutate(ctx, p.db, req)
+}
+
+func (p *PostgresSource) mutate(ctx context.Context, db execer, req domain.MutationRequest) (*domain.MutationResult, error) {
+	m := req.Mutation
+	e, err := p.expose(ctx, m.Target, req.Access)
+	if err != nil {
+		return nil, err
+	}
+	sqlStr, args, err := translateMutation(m, e)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &domain.MutationResult{Op: m.Op}
+	if len(m.Returning) == 0 {
+		r, err := db.ExecContext(ctx, sqlStr, args...)
+		if err != nil {
+			return nil, writeError(err)
+		}
+		if res.Affected, err = r.RowsAffected(); err != nil {
+			return nil, err
+		}
+		return res, nil
+	}
+
+	rows, err := db.QueryContext(ctx, sqlStr, args...)
+	if err != nil {
+		return nil, writeError(err)
+	}
+	defer rows.Close()
+	if res.Rows, err = p.decoder.decode(rows); err != nil {
+		return nil, writeError(err)
+	}
+	res.Affected = int64(len(res.Rows))
+	return res, nil
+}
+
+// translateMutation renders a mutation as one parameterized statement.
+func translateMutation(m domain.Mutation, e *exposed) (string, []any, error) {
+	b := &sqlBuilder{exposed: e}
+
+	switch m.Op {
+	case domain.MutationInsert, domain.MutationUpsert:
+		if err := b.insert(m); err != nil {
+			return "", nil, err
+		}
+	case domain.MutationUpdate:
+		fmt.Fprintf(&b.sql, "UPDATE %s SET ", e.table.quoted())
+		if err := b.assignments(m); err != nil {
+			return "", nil, err
+		}
+		if err := b.where(m.Filter()); err != nil {
+			return "", nil, err
+		}
+	case domain.MutationDelete:
+		fmt.Fprintf(&b.sql, "DELETE FROM %s", e.table.quoted())
+		if err := b.where(m.Filter()); err != nil {
+			return "", nil, err
+		}
+	default:
+		return "", nil, fmt.Errorf("%w: unknown mutation op '%s'", domain.ErrInvalidRequest, m.Op)
+	}
+
+	if len(m.Returning) > 0 {
+		columns := e.selectList()
+		if len(m.Returning) != 1 || m.Returning[0] != "*" {
+			quoted := make([]string, len(m.Returning))
+			for i, field := range m.Returning {
+				col, err := b.column(field)
+				if err != nil {
+					return "", nil, err
+				}
+				quoted[i] = col
+			}
+			columns = strings.Join(quoted, ", ")
+		}
+		fmt.Fprintf(&b.sql, " RETURNING %s", columns)
+	}
+
+	return b.sql.String(), b.args, nil
+}
+
+// insert renders INSERT ... VALUES with one row per document. Columns a
+// document leaves out take their DEFAULT. Upserts add ON CONFLICT, updating
+// every non-conflict column from EXCLUDED when Condition holds.
+func (b *sqlBuilder) insert(m domain.Mutation) error {
+	if m.Op == domain.MutationInsert && m.Condition != nil {
+		return fmt.Errorf("%w: postgres inserts do not take a 'condition'", domain.ErrInvalidRequest)
+	}
+	if m.Op == domain.MutationUpsert && len(m.OnConflict) == 0 {
+		return fmt.Errorf("%w: postgres upsert needs 'on_conflict' columns", domain.ErrInvalidRequest)
+	}
+
+	seen := make(map[string]bool)
+	var columns []string
+	for _, doc := range m.Documents {
+		for col := range doc {
+			if !seen[col] {
+				seen[col] = true
+				columns = append(columns, col)
+			}
+		}
+	}
+	if len(columns) == 0 {
+		return fmt.Errorf("%w: %s documents name no columns", domain.ErrInvalidRequest, m.Op)
+	}
+	sort.Strings(columns)
+
+	quoted := make([]string, len(columns))
+	for i, col := range columns {
+		q, err := b.column(col)
+		if err != nil {
+			return err
+		}
+		quoted[i] = q
+	}
+	fmt.Fprintf(&b.sql, "INSERT INTO %s (%s) VALUES ", b.exposed.table.quoted(), strings.Join(quoted, ", "))
+
+	for i, doc := range m.Documents {
+		if i > 0 {
+			b.sql.WriteString(", ")
+		}
+		b.sql.WriteByte('(')
+		for j, col := range columns {
+			if j > 0 {
+				b.sql.WriteString(", ")
+			}
+			v, ok := doc[col]
+			if !ok {
+				b.sql.WriteString("DEFAULT")
+				continue
+			}
+			placeholder, err := b.bindColumn(col, v)
+			if err != nil {
+				return err
+			}
+			b.sql.WriteString(placeholder)
+		}
+		b.sql.WriteByte(')')
+	}
+
+	if m.Op != domain.MutationUpsert {
+		return nil
+	}
+
+	conflict := make(map[string]bool, len(m.OnConflict))
+	targets := make([]string, len(m.OnConflict))
+	for i, col := range m.OnConflict {
+		q, err := b.column(col)
+		if err != nil {
+			return err
+		}
+		conflict[col] = true
+		targets[i] = q
+	}
+	var updates []string
+	for i, col := range columns {
+		if !conflict[col] {
+			updates = append(updates, fmt.Sprintf("%s = EXCLUDED.%s", quoted[i], quoted[i]))
+		}
+	}
+	if len(updates) == 0 {
+		if m.Condition != nil {
+			return fmt.Errorf("%w: an upsert without columns to update cannot take a 'condition'", domain.ErrInvalidRequest)
+		}
+		fmt.Fprintf(&b.sql, " ON CONFLICT (%s) DO NOTHING", strings.Join(targets, ", "))
+		return nil
+	}
+	fmt.Fprintf(&b.sql, " ON CONFLICT (%s) DO UPDATE SET %s", strings.Join(targets, ", "), strings.Join(updates, ", "))
+	return b.where(m.Condition)
+}
+
+// assignments renders the SET list of an UPDATE.
+func (b *sqlBuilder) assignments(m domain.Mutation) error {
+	var parts []string
+	for _, col := range sortedKeys(m.Set) {
+		q, err := b.column(col)
+		if err != nil {
+			return err
+		}
+		placeholder, err := b.bindColumn(col, m.Set[col])
+		if err != nil {
+			return err
+		}
+		parts = append(parts, fmt.Sprintf("%s = %s", q, placeholder))
+	}
+	for _, col := range sortedKeys(m.Inc) {
+		q, err := b.column(col)
+		if err != nil {
+			return err
+		}
+		parts = append(parts, fmt.Sprintf("%s = %s + %s", q, q, b.bind(m.Inc[col])))
+	}
+	for _, col := range m.Unset {
+		q, err := b.column(col)
+		if err != nil {
+			return err
+		}
+		parts = append(parts, q+" = NULL")
+	}
+	b.sql.WriteString(strings.Join(parts, ", "))
+	return nil
+}
+
+func (b *sqlBuilder) where(c *domain.Condition) error {
+	if c == nil {
+		return nil
+	}
+	b.sql.WriteString(" WHERE ")
+	return b.condition(*c)
+}
+
+// bindColumn binds a document value coerced for the column's type.
+func (b *sqlBuilder) bindColumn(col string, v any) (string, error) {
+	value, err := columnValue(b.exposed.types[col], v)
+	if err != nil {
+		return "", fmt.Errorf("%w: value for column '%s': %v", domain.ErrInvalidRequest, col, err)
+	}
+	return b.bind(value), nil
+}
+
+// columnValue coerces a JSON value for a column of the given udt_name.
+// Strings coerce cannot interpret for the type are left for Postgres to
+// parse, so types it has no rule for (inet, interval, ...) still work.
+func columnValue(udt string, v any) (any, error) {
+	hint := udt
+	if elem, ok := strings.CutPrefix(udt, "_"); ok {
+		hint = elem + "[]"
+	}
+	value, err := coerce(v, hint)
+	if err == nil {
+		return value, nil
+	}
+	if s, ok := v.(string); ok {
+		return s, nil
+	}
+	if errors.Is(err, errUnknownHint) {
+		return coerce(v, "")
+	}
+	return nil, err
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// writeError classifies constraint violations and bad input as caller
+// errors: unique and exclusion violations are conflicts, other integrity
+// and data errors are invalid requests.
+func writeError(err error) error {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return err
+	}
+	switch {
+	case pqErr.Code == "23505" || pqErr.Code == "23P01":
+		return fmt.Errorf("%w: %s", domain.ErrConflict, pqErr.Message)
+	case pqErr.Code.Class() == "23" || pqErr.Code.Class() == "22":
+		return fmt.Errorf("%w: %s", domain.ErrInvalidRequest, pqErr.Message)
+	default:
+		return err
+	}
+}
@@ -0,0 +1,45 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/thegodeveloper/data-gateway/internal/domain"
+)
+
+func TestTranslateMutation(t *testing.T) {
+	e, err := testSource().expose(context.Background(), "orders", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := domain.Mutation{
+		Op:        domain.MutationInsert,
+		Target:    "orders",
+		Documents: []map[string]any{{"id": 1.0, "total": "9.50"}, {"id": 2.0}},
+	}
+	sql, args, err := translateMutation(m, e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `INSERT INTO "orders" ("id", "total") VALUES ($1, $2), ($3, DEFAULT)`
+	if sql != want {
+		t.Errorf("sql = %s\nwant  %s", sql, want)
+	}
+	if len(args) != 3 {
+		t.Errorf("args = %v", args)
+	}
+}
+
+func TestTranslateMutationRejectsEmptyDocuments(t *testing.T) {
+	e, err := testSource().expose(context.Background(), "orders", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, op := range []domain.MutationOp{domain.MutationInsert, domain.MutationUpsert} {
+		m := domain.Mutation{Op: op, Target: "orders", Documents: []map[string]any{{}, {}}, OnConflict: []string{"id"}}
+		if _, _, err := translateMutation(m, e); !errors.Is(err, domain.ErrInvalidRequest) {
+			t.Errorf("%s of empty documents: error = %v, want ErrInvalidRequest", op, err)
+		}
+	}
+}
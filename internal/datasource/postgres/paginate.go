@@ -0,0 +1,197 @@
+// Package postgres
+// internal/datasource/postgres/paginate.go
+package postgres
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/thegodeveloper/data-gateway/internal/domain"
+)
+
+// keyset is the order a paginated query reads in: the requested sort fields
+// followed by the primary key columns they leave out, so every row has a
+// unique position. after holds the last row's values of those columns.
+type keyset struct {
+	columns []string
+	desc    []bool
+	// hidden are keyset columns the caller did not select; they are read
+	// for the cursor and removed from the returned rows.
+	hidden []string
+	after  []any
+	size   int
+}
+
+func newKeyset(q domain.Query, e *exposed) (*keyset, error) {
+	if len(e.key) == 0 {
+		return nil, fmt.Errorf("%w: table '%s' has no primary key to paginate by", domain.ErrInvalidRequest, e.table)
+	}
+
+	ks := &keyset{size: q.PageSize}
+	seen := make(map[string]bool)
+	for _, s := range q.Sort {
+		if !seen[s.Field] {
+			seen[s.Field] = true
+			ks.columns = append(ks.columns, s.Field)
+			ks.desc = append(ks.desc, s.Desc)
+		}
+	}
+	for _, col := range e.key {
+		if !seen[col] {
+			seen[col] = true
+			ks.columns = append(ks.columns, col)
+			ks.desc = append(ks.desc, false)
+		}
+	}
+	for _, col := range ks.columns {
+		if err := e.column(col); err != nil {
+			return nil, fmt.Errorf("%w: paginating needs column '%s', which this route does not expose", domain.ErrInvalidRequest, col)
+		}
+	}
+
+	if len(q.Select) > 0 {
+		selected := make(map[string]bool, len(q.Select))
+		for _, field := range q.Select {
+			selected[field] = true
+		}
+		for _, col := range ks.columns {
+			if !selected[col] {
+				ks.hidden = append(ks.hidden, col)
+			}
+		}
+	}
+
+	if q.Cursor != nil {
+		after, err := decodeCursor(q.Cursor, len(ks.columns))
+		if err != nil {
+			return nil, err
+		}
+		ks.after = after
+	}
+	return ks, nil
+}
+
+func (ks *keyset) order() []domain.SortField {
+	order := make([]domain.SortField, len(ks.columns))
+	for i, col := range ks.columns {
+		order[i] = domain.SortField{Field: col, Desc: ks.desc[i]}
+	}
+	return order
+}
+
+// seek renders the predicate for rows after ks.after. With a single
+// direction it is a row comparison, which an index on the keyset columns can
+// serve; mixed directions expand to (a > $1) OR (a = $1 AND b < $2) ...
+func (b *sqlBuilder) seek(ks *keyset) error {
+	quoted := make([]string, len(ks.columns))
+	for i, field := range ks.columns {
+		col, err := b.column(field)
+		if err != nil {
+			return err
+		}
+		quoted[i] = col
+	}
+
+	uniform := true
+	for _, desc := range ks.desc {
+		uniform = uniform && desc == ks.desc[0]
+	}
+	if uniform {
+		op := ">"
+		if ks.desc[0] {
+			op = "<"
+		}
+		placeholders := make([]string, len(ks.after))
+		for i, v := range ks.after {
+			placeholders[i] = b.bind(v)
+		}
+		fmt.Fprintf(&b.sql, "(%s) %s (%s)", strings.Join(quoted, ", "), op, strings.Join(placeholders, ", "))
+		return nil
+	}
+
+	b.sql.WriteByte('(')
+	for i := range ks.columns {
+		if i > 0 {
+			b.sql.WriteString(" OR ")
+		}
+		b.sql.WriteByte('(')
+		for j := 0; j < i; j++ {
+			fmt.Fprintf(&b.sql, "%s = %s AND ", quoted[j], b.bind(ks.after[j]))
+		}
+		op := ">"
+		if ks.desc[i] {
+			op = "<"
+		}
+		fmt.Fprintf(&b.sql, "%s %s %s)", quoted[i], op, b.bind(ks.after[i]))
+	}
+	b.sql.WriteByte(')')
+	return nil
+}
+
+// page reads one page of a structured query in keyset order. The cursor is
+// the JSON array of the last row's keyset values.
+func (p *PostgresSource) page(ctx context.Context, q domain.Query, e *exposed) (*domain.Page, error) {
+	ks, err := newKeyset(q, e)
+	if err != nil {
+		return nil, err
+	}
+	sqlStr, args, err := translate(q, e, ks)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := p.query(ctx, sqlStr, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &domain.Page{Items: rows}
+	if len(rows) > ks.size {
+		page.Items = rows[:ks.size]
+		last := page.Items[ks.size-1]
+		values := make([]any, len(ks.columns))
+		for i, col := range ks.columns {
+			switch v := last[col].(type) {
+			case string, bool, int64, float64, json.Number:
+				values[i] = v
+			case nil:
+				return nil, fmt.Errorf("%w: column '%s' is NULL; paginated sort columns must not be", domain.ErrInvalidRequest, col)
+			default:
+				return nil, fmt.Errorf("%w: cannot paginate by column '%s' of type %s", domain.ErrInvalidRequest, col, e.types[col])
+			}
+		}
+		if page.Next, err = json.Marshal(values); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, row := range page.Items {
+		for _, col := range ks.hidden {
+			delete(row, col)
+		}
+	}
+	if page.Items == nil {
+		page.Items = []map[string]any{}
+	}
+	return page, nil
+}
+
+// decodeCursor reads back the keyset values. Numbers stay in their decimal
+// text, which Postgres parses for the column's type without losing
+// precision.
+func decodeCursor(cursor []byte, n int) ([]any, error) {
+	dec := json.NewDecoder(bytes.NewReader(cursor))
+	dec.UseNumber()
+	var values []any
+	if err := dec.Decode(&values); err != nil || len(values) != n {
+		return nil, fmt.Errorf("%w: 'page_token' does not match this query", domain.ErrInvalidRequest)
+	}
+	for i, v := range values {
+		if num, ok := v.(json.Number); ok {
+			values[i] = num.String()
+		}
+	}
+	return values, nil
+}
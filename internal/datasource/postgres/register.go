@@ -0,0 +1,66 @@
+// Package postgres
+// internal/datasource/postgres/register.go
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/thegodeveloper/data-gateway/internal/datasource"
+	"github.com/thegodeveloper/data-gateway/internal/domain"
+)
+
+func init() {
+	datasource.Register("postgres", newFromSettings)
+}
+
+// Settings configures a "postgres" data source instance.
+type Settings struct {
+	ConnStr string `mapstructure:"conn_str"`
+	// RawSQL holds the policies for params.query.
+	RawSQL RawSQL `mapstructure:"raw_sql"`
+	// StatementCache is how many parameterized statements stay prepared
+	// (default 256, negative disables the cache).
+	StatementCache int `mapstructure:"statement_cache"`
+	// Numeric selects how numeric columns are returned: "string" (default)
+	// or "number", a JSON number with the exact digits.
+	Numeric string `mapstructure:"numeric"`
+	// Changes configures NOTIFY channels and the row change feed.
+	Changes Changes `mapstructure:"changes"`
+}
+
+func newFromSettings(ctx context.Context, raw map[string]any) (domain.DataSource, error) {
+	var settings Settings
+	if err := datasource.DecodeSettings(raw, &settings); err != nil {
+		return nil, err
+	}
+	if settings.ConnStr == "" {
+		return nil, errors.New("missing 'conn_str' setting")
+	}
+	switch settings.Numeric {
+	case "", "string", "number":
+	default:
+		return nil, fmt.Errorf("invalid 'numeric' setting '%s', want \"string\" or \"number\"", settings.Numeric)
+	}
+
+	db, err := NewDB(ctx, settings.ConnStr)
+	if err != nil {
+		return nil, err
+	}
+	src := NewPostgresSource(db)
+	src.connStr = settings.ConnStr
+	src.decoder.numericNumbers = settings.Numeric == "number"
+	if settings.StatementCache != 0 {
+		src.stmts = newStmtCache(db, settings.StatementCache)
+	}
+	if err := src.SetRawSQL(settings.RawSQL); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if err := src.SetChanges(ctx, settings.Changes); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return src, nil
+}
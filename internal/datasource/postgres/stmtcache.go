@@ -0,0 +1,149 @@
+// Package postgres
+// internal/datasource/postgres/stmtcache.go
+package postgres
+
+import (
+	"container/list"
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+
+	"github.com/lib/pq"
+)
+
+const defaultStatementCache = 256
+
+// stmtCache keeps the most recently used parameterized statements prepared.
+// database/sql re-prepares a statement on each pooled connection the first
+// time it runs there, and Tx.StmtContext reuses it inside transactions.
+type stmtCache struct {
+	db  *sql.DB
+	max int
+
+	mu      sync.Mutex
+	order   *list.List // front is most recently used
+	entries map[string]*list.Element
+}
+
+type cachedStmt struct {
+	query   string
+	stmt    *sql.Stmt
+	refs    int  // queries currently using stmt
+	evicted bool // close once refs drops to zero
+}
+
+func newStmtCache(db *sql.DB, max int) *stmtCache {
+	return &stmtCache{db: db, max: max, order: list.New(), entries: make(map[string]*list.Element)}
+}
+
+// acquire returns the prepared statement for query, preparing it on a miss.
+// The caller must release it.
+func (c *stmtCache) acquire(ctx context.Context, query string) (*cachedStmt, error) {
+	c.mu.Lock()
+	if el, ok := c.entries[query]; ok {
+		c.order.MoveToFront(el)
+		entry := el.Value.(*cachedStmt)
+		entry.refs++
+		c.mu.Unlock()
+		return entry, nil
+	}
+	c.mu.Unlock()
+
+	stmt, err := c.db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[query]; ok {
+		// Another request prepared it first.
+		stmt.Close()
+		entry := el.Value.(*cachedStmt)
+		entry.refs++
+		return entry, nil
+	}
+	entry := &cachedStmt{query: query, stmt: stmt, refs: 1}
+	c.entries[query] = c.order.PushFront(entry)
+	for c.order.Len() > c.max {
+		c.remove(c.order.Back())
+	}
+	return entry, nil
+}
+
+func (c *stmtCache) release(entry *cachedStmt) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry.refs--
+	if entry.evicted && entry.refs == 0 {
+		entry.stmt.Close()
+	}
+}
+
+// evict drops query, e.g. after DDL made its cached plan invalid.
+func (c *stmtCache) evict(query string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[query]; ok {
+		c.remove(el)
+	}
+}
+
+// remove must be called with mu held.
+func (c *stmtCache) remove(el *list.Element) {
+	entry := el.Value.(*cachedStmt)
+	c.order.Remove(el)
+	delete(c.entries, entry.query)
+	entry.evicted = true
+	if entry.refs == 0 {
+		entry.stmt.Close()
+	}
+}
+
+// query runs query, through the cache when it has arguments, and hands the
+// rows to scan. Statements without arguments may hold several commands and
+// are sent as simple queries. tx may be nil.
+func (c *stmtCache) query(ctx context.Context, tx *sql.Tx, query string, args []any, scan func(*sql.Rows) error) error {
+	var (
+		rows *sql.Rows
+		err  error
+	)
+	switch {
+	case len(args) == 0 || c.max <= 0:
+		if tx != nil {
+			rows, err = tx.QueryContext(ctx, query, args...)
+		} else {
+			rows, err = c.db.QueryContext(ctx, query, args...)
+		}
+	default:
+		entry, acquireErr := c.acquire(ctx, query)
+		if acquireErr != nil {
+			return acquireErr
+		}
+		defer c.release(entry)
+		stmt := entry.stmt
+		if tx != nil {
+			stmt = tx.StmtContext(ctx, stmt)
+		}
+		if rows, err = stmt.QueryContext(ctx, args...); stale(err) {
+			c.evict(query)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	return scan(rows)
+}
+
+// stale reports errors after which a prepared statement must be re-prepared:
+// "cached plan must not change result type" after DDL, or a statement the
+// server no longer knows.
+func stale(err error) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return false
+	}
+	return pqErr.Code == "0A000" || pqErr.Code == "26000"
+}
@@ -0,0 +1,119 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"testing"
+
+	"github.com/lib/pq"
+)
+
+func TestStmtCache(t *testing.T) {
+	const (
+		q1 = "SELECT * FROM orders WHERE id = $1"
+		q2 = "SELECT * FROM orders WHERE customer_id = $1"
+		q3 = "SELECT * FROM orders WHERE status = $1"
+		q4 = "SELECT * FROM users WHERE id = $1"
+	)
+	cases := []struct {
+		name     string
+		max      int
+		errs     map[string]error
+		queries  []string // each run with one argument
+		prepared []string
+		closed   []string
+	}{
+		{"prepares once", 2, nil, []string{q1, q1, q1}, []string{q1}, nil},
+		{"evicts the least recently used", 2, nil, []string{q1, q2, q1, q3, q2}, []string{q1, q2, q3, q2}, []string{q2, q1}},
+		{"re-prepares after a changed plan", 2, map[string]error{q1: &pq.Error{Code: "0A000"}}, []string{q1, q1}, []string{q1, q1}, []string{q1, q1}},
+		{"re-prepares an unknown statement", 2, map[string]error{q1: &pq.Error{Code: "26000"}}, []string{q1, q1}, []string{q1, q1}, []string{q1, q1}},
+		{"keeps statements that fail otherwise", 2, map[string]error{q1: &pq.Error{Code: "23505"}}, []string{q1, q1}, []string{q1}, nil},
+		{"disabled", 0, nil, []string{q1, q4}, nil, nil},
+	}
+	for _, c := range cases {
+		db := &fakeDB{errs: c.errs}
+		pool := db.open()
+		cache := newStmtCache(pool, c.max)
+		for _, q := range c.queries {
+			cache.query(context.Background(), nil, q, []any{int64(1)}, func(*sql.Rows) error { return nil })
+		}
+		if got := trimCalls(db.calls("prepare "), "prepare "); !reflect.DeepEqual(got, c.prepared) {
+			t.Errorf("%s: prepared %q, want %q", c.name, got, c.prepared)
+		}
+		if got := trimCalls(db.calls("close "), "close "); !reflect.DeepEqual(got, c.closed) {
+			t.Errorf("%s: closed %q, want %q", c.name, got, c.closed)
+		}
+		if got := len(db.calls("query ")); got != len(c.queries) {
+			t.Errorf("%s: ran %d queries, want %d", c.name, got, len(c.queries))
+		}
+		pool.Close()
+	}
+}
+
+func TestStmtCacheSendsQueriesWithoutArgsUnprepared(t *testing.T) {
+	db := &fakeDB{}
+	cache := newStmtCache(db.open(), 2)
+	if err := cache.query(context.Background(), nil, "SELECT 1; SELECT 2", nil, func(*sql.Rows) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if prepared := db.calls("prepare "); len(prepared) != 0 {
+		t.Fatalf("prepared %q", prepared)
+	}
+}
+
+func TestStmtCacheClosesEvictedStatementsOnRelease(t *testing.T) {
+	const q = "SELECT * FROM orders WHERE id = $1"
+	db := &fakeDB{}
+	cache := newStmtCache(db.open(), 2)
+	entry, err := cache.acquire(context.Background(), q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache.evict(q)
+	if closed := db.calls("close "); len(closed) != 0 {
+		t.Fatalf("closed %q while in use", closed)
+	}
+	cache.release(entry)
+	if closed := db.calls("close "); len(closed) != 1 {
+		t.Fatalf("closed %q after release, want the statement", closed)
+	}
+}
+
+func TestStmtCacheRunsInTransactions(t *testing.T) {
+	const q = "SELECT * FROM orders WHERE id = $1"
+	db := &fakeDB{}
+	pool := db.open()
+	cache := newStmtCache(pool, 2)
+	ctx := context.Background()
+	scan := func(*sql.Rows) error { return nil }
+	if err := cache.query(ctx, nil, q, []any{int64(1)}, scan); err != nil {
+		t.Fatal(err)
+	}
+	// The pool's one connection is held by each transaction, so only a
+	// cached statement can run in it.
+	for range 2 {
+		tx, err := pool.BeginTx(ctx, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := cache.query(ctx, tx, q, []any{int64(1)}, scan); err != nil {
+			t.Fatal(err)
+		}
+		tx.Commit()
+	}
+	if prepared := db.calls("prepare "); len(prepared) != 1 {
+		t.Fatalf("prepared %q, want once", prepared)
+	}
+	if queries := db.calls("query "); len(queries) != 3 {
+		t.Fatalf("ran %q, want 3 queries", queries)
+	}
+}
+
+func trimCalls(calls []string, prefix string) []string {
+	var out []string
+	for _, call := range calls {
+		out = append(out, call[len(prefix):])
+	}
+	return out
+}
@@ -0,0 +1,34 @@
+// Package postgres
+// internal/datasource/postgres/transaction.go
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/thegodeveloper/data-gateway/internal/domain"
+)
+
+// Transact runs every step in one database transaction. The first failing
+// step rolls the transaction back and is reported as a domain.StepError.
+func (p *PostgresSource) Transact(ctx context.Context, req domain.TransactionRequest) (*domain.TransactionResult, error) {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	res := &domain.TransactionResult{Steps: make([]*domain.MutationResult, len(req.Steps))}
+	for i, step := range req.Steps {
+		r, err := p.mutate(ctx, tx, domain.MutationRequest{Source: req.Source, Params: req.Params, Mutation: step, Access: req.Access})
+		if err != nil {
+			return nil, &domain.StepError{Step: i, Op: step.Op, Err: err}
+		}
+		res.Steps[i] = r
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, writeError(fmt.Errorf("failed to commit transaction: %w", err))
+	}
+	return res, nil
+}
@@ -0,0 +1,63 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/thegodeveloper/data-gateway/internal/domain"
+)
+
+func TestTransactRollsBackOnAFailingStep(t *testing.T) {
+	ctx := context.Background()
+	insert := domain.Mutation{Op: domain.MutationInsert, Target: "users", Documents: []map[string]any{{"id": 1, "email": "a@example.com"}}}
+	remove := domain.Mutation{Op: domain.MutationDelete, Target: "users", Key: map[string]any{"id": 2}}
+	unknown := domain.Mutation{Op: domain.MutationUpdate, Target: "users", Key: map[string]any{"id": 1}, Set: map[string]any{"ghost": 1}}
+
+	p := testSource()
+	e, err := p.expose(ctx, "users", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	removeSQL, _, err := translateMutation(remove, e)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		steps []domain.Mutation
+		errs  map[string]error
+		step  int   // failing step, -1 for none
+		is    error // what the step error wraps
+		calls []string
+	}{
+		{"commits", []domain.Mutation{insert, remove}, nil, -1, nil, []string{"begin", "exec", "exec", "commit"}},
+		{"rolls back a failed write", []domain.Mutation{insert, remove}, map[string]error{removeSQL: &pq.Error{Code: "23503", Message: "still referenced"}},
+			1, domain.ErrInvalidRequest, []string{"begin", "exec", "exec", "rollback"}},
+		{"rolls back an invalid step", []domain.Mutation{insert, unknown, remove}, nil, 1, domain.ErrInvalidRequest, []string{"begin", "exec", "rollback"}},
+	}
+	for _, tt := range tests {
+		db := &fakeDB{errs: tt.errs}
+		p.db = db.open()
+		res, err := p.Transact(ctx, domain.TransactionRequest{Source: "pg", Steps: tt.steps})
+
+		var stepErr *domain.StepError
+		switch {
+		case tt.step < 0 && (err != nil || len(res.Steps) != len(tt.steps)):
+			t.Errorf("%s: Transact = %+v, %v", tt.name, res, err)
+		case tt.step >= 0 && (!errors.As(err, &stepErr) || stepErr.Step != tt.step || !errors.Is(err, tt.is)):
+			t.Errorf("%s: error = %v, want step %d to fail with %v", tt.name, err, tt.step, tt.is)
+		}
+		var calls []string
+		for _, call := range db.calls("") {
+			calls = append(calls, strings.Fields(call)[0])
+		}
+		if !slices.Equal(calls, tt.calls) {
+			t.Errorf("%s: calls = %v, want %v", tt.name, calls, tt.calls)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+// Package postgres
+// internal/datasource/postgres/translate.go
+package postgres
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/thegodeveloper/data-gateway/internal/domain"
+)
+
+var comparisons = map[domain.Operator]string{
+	domain.OpEq:   "=",
+	domain.OpNe:   "<>",
+	domain.OpGt:   ">",
+	domain.OpGte:  ">=",
+	domain.OpLt:   "<",
+	domain.OpLte:  "<=",
+	domain.OpLike: "LIKE",
+}
+
+// translate renders a structured query as a parameterized SELECT against an
+// exposed table. Every identifier is checked against the exposed columns and
+// quoted, and every value is a bind argument. With a keyset, the query reads
+// one page in keyset order instead of honouring Sort, Limit and Offset.
+func translate(q domain.Query, e *exposed, ks *keyset) (string, []any, error) {
+	b := &sqlBuilder{exposed: e}
+
+	columns := e.selectList()
+	if len(q.Select) > 0 {
+		fields := q.Select
+		if ks != nil {
+			fields = append(append([]string(nil), fields...), ks.hidden...)
+		}
+		quoted := make([]string, len(fields))
+		for i, field := range fields {
+			col, err := b.column(field)
+			if err != nil {
+				return "", nil, err
+			}
+			quoted[i] = col
+		}
+		columns = strings.Join(quoted, ", ")
+	}
+	fmt.Fprintf(&b.sql, "SELECT %s FROM %s", columns, e.table.quoted())
+
+	seek := ks != nil && ks.after != nil
+	if q.Where != nil || seek {
+		b.sql.WriteString(" WHERE ")
+	}
+	if q.Where != nil {
+		if err := b.condition(*q.Where); err != nil {
+			return "", nil, err
+		}
+		if seek {
+			b.sql.WriteString(" AND ")
+		}
+	}
+	if seek {
+		if err := b.seek(ks); err != nil {
+			return "", nil, err
+		}
+	}
+
+	sort := q.Sort
+	if ks != nil {
+		sort = ks.order()
+	}
+	if len(sort) > 0 {
+		b.sql.WriteString(" ORDER BY ")
+		for i, s := range sort {
+			if i > 0 {
+				b.sql.WriteString(", ")
+			}
+			col, err := b.column(s.Field)
+			if err != nil {
+				return "", nil, err
+			}
+			b.sql.WriteString(col)
+			if s.Desc {
+				b.sql.WriteString(" DESC")
+			}
+		}
+	}
+	if ks != nil {
+		// One extra row tells whether another page follows.
+		fmt.Fprintf(&b.sql, " LIMIT %s", b.bind(ks.size+1))
+		return b.sql.String(), b.args, nil
+	}
+	if q.Limit > 0 {
+		fmt.Fprintf(&b.sql, " LIMIT %s", b.bind(q.Limit))
+	}
+	if q.Offset > 0 {
+		fmt.Fprintf(&b.sql, " OFFSET %s", b.bind(q.Offset))
+	}
+
+	return b.sql.String(), b.args, nil
+}
+
+type sqlBuilder struct {
+	sql     strings.Builder
+	args    []any
+	exposed *exposed
+}
+
+func (b *sqlBuilder) column(name string) (string, error) {
+	if err := b.exposed.column(name); err != nil {
+		return "", err
+	}
+	return pq.QuoteIdentifier(name), nil
+}
+
+func (b *sqlBuilder) bind(value any) string {
+	b.args = append(b.args, value)
+	return fmt.Sprintf("$%d", len(b.args))
+}
+
+func (b *sqlBuilder) condition(c domain.Condition) error {
+	if c.And != nil || c.Or != nil {
+		children, joiner := c.And, " AND "
+		if c.Or != nil {
+			children, joiner = c.Or, " OR "
+		}
+		b.sql.WriteByte('(')
+		for i, child := range children {
+			if i > 0 {
+				b.sql.WriteString(joiner)
+			}
+			if err := b.condition(child); err != nil {
+				return err
+			}
+		}
+		b.sql.WriteByte(')')
+		return nil
+	}
+
+	column, err := b.column(c.Field)
+	if err != nil {
+		return err
+	}
+	switch c.Op {
+	case domain.OpExists:
+		if c.Value.(bool) {
+			fmt.Fprintf(&b.sql, "%s IS NOT NULL", column)
+		} else {
+			fmt.Fprintf(&b.sql, "%s IS NULL", column)
+		}
+	case domain.OpIn:
+		values := c.Value.([]any)
+		if len(values) == 0 {
+			b.sql.WriteString("FALSE")
+			return nil
+		}
+		placeholders := make([]string, len(values))
+		for i, v := range values {
+			placeholders[i] = b.bind(v)
+		}
+		fmt.Fprintf(&b.sql, "%s IN (%s)", column, strings.Join(placeholders, ", "))
+	case domain.OpEq, domain.OpNe:
+		if c.Value == nil {
+			if c.Op == domain.OpEq {
+				fmt.Fprintf(&b.sql, "%s IS NULL", column)
+			} else {
+				fmt.Fprintf(&b.sql, "%s IS NOT NULL", column)
+			}
+			return nil
+		}
+		fmt.Fprintf(&b.sql, "%s %s %s", column, comparisons[c.Op], b.bind(c.Value))
+	default:
+		op, ok := comparisons[c.Op]
+		if !ok {
+			return fmt.Errorf("%w: operator '%s' is not supported by postgres", domain.ErrInvalidRequest, c.Op)
+		}
+		fmt.Fprintf(&b.sql, "%s %s %s", column, op, b.bind(c.Value))
+	}
+	return nil
+}
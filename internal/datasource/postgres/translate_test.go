@@ -0,0 +1,91 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/thegodeveloper/data-gateway/internal/domain"
+)
+
+func TestTranslate(t *testing.T) {
+	e, err := testSource().expose(context.Background(), "users", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := domain.Query{
+		Target: "users",
+		Select: []string{"id", "email"},
+		Where: &domain.Condition{Or: []domain.Condition{
+			{Field: "email", Op: domain.OpLike, Value: "%' OR '1'='1"},
+			{Field: "id", Op: domain.OpIn, Value: []any{1.0, 2.0}},
+		}},
+		Sort:  []domain.SortField{{Field: "id", Desc: true}},
+		Limit: 10,
+	}
+	sql, args, err := translate(q, e, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `SELECT "id", "email" FROM "users" WHERE ("email" LIKE $1 OR "id" IN ($2, $3)) ORDER BY "id" DESC LIMIT $4`
+	if sql != want {
+		t.Errorf("sql = %s\nwant  %s", sql, want)
+	}
+	if !reflect.DeepEqual(args, []any{"%' OR '1'='1", 1.0, 2.0, 10}) {
+		t.Errorf("args = %v", args)
+	}
+}
+
+func TestTranslateRejectsIdentifiers(t *testing.T) {
+	p := testSource()
+	all, err := p.expose(context.Background(), "users", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	narrowed, err := p.expose(context.Background(), "users", &domain.Access{Tables: map[string][]string{"users": {"id", "email"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	malicious := []string{
+		"id;DROP TABLE users",
+		"1=1 OR id",
+		"id--",
+		`"id"`,
+		`id" OR "1"="1`,
+		"ïd",
+		"id​",
+		"users.id",
+		"public.users.id",
+		"*",
+		"",
+		"ID",
+	}
+	for _, field := range malicious {
+		for name, q := range map[string]domain.Query{
+			"select": {Target: "users", Select: []string{field}},
+			"where":  {Target: "users", Where: &domain.Condition{Field: field, Op: domain.OpEq, Value: 1}},
+			"sort":   {Target: "users", Sort: []domain.SortField{{Field: field}}},
+		} {
+			if _, _, err := translate(q, all, nil); !errors.Is(err, domain.ErrInvalidRequest) {
+				t.Errorf("%s %q: error = %v, want ErrInvalidRequest", name, field, err)
+			}
+		}
+	}
+
+	// Columns outside the route's allow-list are unknown to the query.
+	for name, q := range map[string]domain.Query{
+		"select": {Target: "users", Select: []string{"password_hash"}},
+		"where":  {Target: "users", Where: &domain.Condition{Field: "password_hash", Op: domain.OpExists, Value: true}},
+		"nested": {Target: "users", Where: &domain.Condition{And: []domain.Condition{{Field: "id", Op: domain.OpEq, Value: 1}, {Field: "password_hash", Op: domain.OpLike, Value: "a%"}}}},
+		"sort":   {Target: "users", Sort: []domain.SortField{{Field: "password_hash"}}},
+	} {
+		if _, _, err := translate(q, narrowed, nil); !errors.Is(err, domain.ErrInvalidRequest) {
+			t.Errorf("allow-list %s: error = %v, want ErrInvalidRequest", name, err)
+		}
+	}
+	if sql, _, err := translate(domain.Query{Target: "users"}, narrowed, nil); err != nil || sql != `SELECT "id", "email" FROM "users"` {
+		t.Errorf("narrowed select = %q, %v", sql, err)
+	}
+}
@@ -0,0 +1,118 @@
+// Package datasource
+// internal/datasource/registry.go
+package datasource
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/go-viper/mapstructure/v2"
+	"github.com/thegodeveloper/data-gateway/internal/config"
+	"github.com/thegodeveloper/data-gateway/internal/domain"
+)
+
+// Factory builds a data source instance from its configured settings.
+type Factory func(ctx context.Context, settings map[string]any) (domain.DataSource, error)
+
+// Closer is implemented by data sources that own a connection or client.
+type Closer interface {
+	Close(ctx context.Context) error
+}
+
+var (
+	mu        sync.RWMutex
+	factories = make(map[string]Factory)
+)
+
+// Register makes a factory available under the given type name. Adapters call
+// it from init, so it panics on duplicates the way sql.Register does.
+func Register(typ string, factory Factory) {
+	mu.Lock()
+	defer mu.Unlock()
+	if factory == nil {
+		panic("datasource: Register factory is nil")
+	}
+	if _, dup := factories[typ]; dup {
+		panic("datasource: Register called twice for type " + typ)
+	}
+	factories[typ] = factory
+}
+
+// Types returns the registered type names in sorted order.
+func Types() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	types := make([]string, 0, len(factories))
+	for typ := range factories {
+		types = append(types, typ)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// Open builds every configured instance, keyed by instance name. If any
+// instance fails, the ones already opened are closed again.
+func Open(ctx context.Context, cfgs map[string]config.DataSource) (map[string]domain.DataSource, error) {
+	names := make([]string, 0, len(cfgs))
+	for name := range cfgs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	sources := make(map[string]domain.DataSource, len(cfgs))
+	for _, name := range names {
+		cfg := cfgs[name]
+
+		mu.RLock()
+		factory, ok := factories[cfg.Type]
+		mu.RUnlock()
+		if !ok {
+			Close(ctx, sources)
+			return nil, fmt.Errorf("data source '%s': unknown type '%s' (registered: %v)", name, cfg.Type, Types())
+		}
+
+		ds, err := factory(ctx, cfg.Settings)
+		if err != nil {
+			Close(ctx, sources)
+			return nil, fmt.Errorf("data source '%s': %w", name, err)
+		}
+		sources[name] = ds
+	}
+	return sources, nil
+}
+
+// Close releases every source that owns a connection. Errors are collected
+// rather than stopping at the first one.
+func Close(ctx context.Context, sources map[string]domain.DataSource) error {
+	var errs []error
+	for name, ds := range sources {
+		if c, ok := ds.(Closer); ok {
+			if err := c.Close(ctx); err != nil {
+				errs = append(errs, fmt.Errorf("data source '%s': %w", name, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// DecodeSettings decodes raw settings into an adapter's settings struct,
+// matching fields by their mapstructure tags and rejecting unknown keys so
+// typos in the config file fail loudly at startup.
+func DecodeSettings(settings map[string]any, out any) error {
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		Result:           out,
+		WeaklyTypedInput: true,
+		ErrorUnused:      true,
+		DecodeHook:       mapstructure.StringToTimeDurationHookFunc(),
+	})
+	if err != nil {
+		return err
+	}
+	if err := decoder.Decode(settings); err != nil {
+		return fmt.Errorf("invalid settings: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+// Package domain
+// domain/access.go
+package domain
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Access restricts what a request may touch. The gateway sets it from route
+// configuration; it is never decoded from a caller's JSON.
+type Access struct {
+	// Tables maps each exposed table or collection to its exposed columns.
+	// A "*" entry exposes every column. A nil map means no restriction.
+	Tables map[string][]string
+}
+
+// Table reports whether name is exposed and returns its column allow-list,
+// nil meaning every column.
+func (a *Access) Table(name string) (columns map[string]bool, ok bool) {
+	if a == nil || a.Tables == nil {
+		return nil, true
+	}
+	list, ok := a.Tables[name]
+	if !ok {
+		return nil, false
+	}
+	columns = make(map[string]bool, len(list))
+	for _, c := range list {
+		if c == "*" {
+			return nil, true
+		}
+		columns[c] = true
+	}
+	return columns, true
+}
+
+// CheckQuery rejects a structured query on target that selects, filters or
+// sorts on a field outside columns; nil columns allow every field. A nested
+// field such as "address.city" is checked by its top-level name.
+func CheckQuery(q *Query, columns map[string]bool, target string) error {
+	if columns == nil {
+		return nil
+	}
+	fields := slices.Clone(q.Select)
+	for _, s := range q.Sort {
+		fields = append(fields, s.Field)
+	}
+	if q.Where != nil {
+		fields = q.Where.fields(fields)
+	}
+	for _, field := range fields {
+		if !columns[TopLevel(field)] {
+			return fmt.Errorf("%w: unknown field '%s' on '%s'", ErrInvalidRequest, field, target)
+		}
+	}
+	return nil
+}
+
+// Restrict removes the fields outside columns from row; nil columns keep
+// everything.
+func Restrict(row map[string]any, columns map[string]bool) {
+	if columns == nil {
+		return
+	}
+	for name := range row {
+		if !columns[name] {
+			delete(row, name)
+		}
+	}
+}
+
+// TopLevel is the top-level field of a dotted path.
+func TopLevel(field string) string {
+	top, _, _ := strings.Cut(field, ".")
+	return top
+}
+
+// fields appends the fields the condition tests.
+func (c *Condition) fields(out []string) []string {
+	for i := range c.And {
+		out = c.And[i].fields(out)
+	}
+	for i := range c.Or {
+		out = c.Or[i].fields(out)
+	}
+	if c.Field != "" {
+		out = append(out, c.Field)
+	}
+	return out
+}
@@ -0,0 +1,19 @@
+// Package domain
+// domain/caller.go
+package domain
+
+import "context"
+
+type callerKey struct{}
+
+// WithCaller returns a context that identifies the service making the
+// request. Data sources use it to pick per-caller policies.
+func WithCaller(ctx context.Context, caller string) context.Context {
+	return context.WithValue(ctx, callerKey{}, caller)
+}
+
+// CallerFrom returns the caller recorded by WithCaller, or "" if none was.
+func CallerFrom(ctx context.Context) string {
+	caller, _ := ctx.Value(callerKey{}).(string)
+	return caller
+}
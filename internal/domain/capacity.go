@@ -0,0 +1,72 @@
+// Package domain
+// domain/capacity.go
+package domain
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Capacity adds up the read and write capacity units one request consumed
+// across every backend call it made.
+type Capacity struct {
+	mu          sync.Mutex
+	used        bool
+	read, write float64
+}
+
+// Add records the units of one backend call.
+func (c *Capacity) Add(read, write float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.used = true
+	c.read += read
+	c.write += write
+}
+
+// Units returns the totals so far; ok is false if nothing was recorded.
+func (c *Capacity) Units() (read, write float64, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.read, c.write, c.used
+}
+
+// CapacityUnits is the capacity a request consumed, as reported in the
+// bodies of results that have room for it.
+type CapacityUnits struct {
+	Read  float64 `json:"read"`
+	Write float64 `json:"write"`
+}
+
+type capacityKey struct{}
+
+// WithCapacity returns a context whose data source calls report the
+// capacity they consume to the returned Capacity.
+func WithCapacity(ctx context.Context) (context.Context, *Capacity) {
+	c := &Capacity{}
+	return context.WithValue(ctx, capacityKey{}, c), c
+}
+
+// CapacityFrom returns the Capacity set by WithCapacity, or nil if none was.
+func CapacityFrom(ctx context.Context) *Capacity {
+	c, _ := ctx.Value(capacityKey{}).(*Capacity)
+	return c
+}
+
+// ThrottleError is returned when a backend, or the gateway's own capacity
+// budget, refuses a request for lack of throughput. It matches ErrThrottled.
+type ThrottleError struct {
+	// Target is the table or collection that ran out of capacity.
+	Target string
+	// RetryAfter is how long the caller should wait; zero if unknown.
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *ThrottleError) Error() string {
+	return fmt.Sprintf("%v: '%s': %v", ErrThrottled, e.Target, e.Err)
+}
+
+func (e *ThrottleError) Unwrap() []error { return []error{ErrThrottled, e.Err} }
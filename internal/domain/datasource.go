@@ -2,13 +2,36 @@
 // domain/datasource.go
 package domain
 
-import "context"
+import (
+	"context"
+	"iter"
+)
 
+// QueryRequest targets one data source either with source-specific Params or
+// with a backend-neutral Query, which takes precedence when set. Params still
+// carry source-level options alongside a Query, e.g. the Mongo database.
 type QueryRequest struct {
 	Source string                 `json:"source"`
 	Params map[string]interface{} `json:"params"`
+	Query  *Query                 `json:"query,omitempty"`
+	// Access is the route's allow-list; nil for unrestricted requests.
+	Access *Access `json:"-"`
+	// Tables is the route's mapping of the table names it uses to the
+	// source's, for sources that support one; nil outside routes.
+	Tables map[string]string `json:"-"`
 }
 
 type DataSource interface {
 	Query(ctx context.Context, req QueryRequest) (any, error)
 }
+
+// RowStream yields result rows one at a time. A failure is yielded as the
+// last element with a nil row. Breaking out of the loop releases the
+// underlying rows or cursor.
+type RowStream = iter.Seq2[map[string]any, error]
+
+// Streamer is implemented by data sources that can return a result without
+// holding all of it in memory.
+type Streamer interface {
+	Stream(ctx context.Context, req QueryRequest) (RowStream, error)
+}
@@ -0,0 +1,34 @@
+// Package domain
+// domain/errors.go
+package domain
+
+import "errors"
+
+var (
+	// ErrUnknownSource is returned when a request names a data source that is
+	// not registered with the gateway.
+	ErrUnknownSource = errors.New("unknown data source")
+
+	// ErrRouteNotFound is returned when no route matches a request path.
+	ErrRouteNotFound = errors.New("no route for path")
+
+	// ErrMethodNotAllowed is returned when a route matches the path but not
+	// the request method.
+	ErrMethodNotAllowed = errors.New("method not allowed")
+
+	// ErrInvalidRequest marks errors caused by the caller's input rather than
+	// by the gateway or a backend, e.g. a missing route variable.
+	ErrInvalidRequest = errors.New("invalid request")
+
+	// ErrForbidden is returned when a request is well-formed but the caller's
+	// policy does not allow it, e.g. raw SQL that is not a SELECT.
+	ErrForbidden = errors.New("forbidden")
+
+	// ErrConflict is returned when a write collides with existing data: a
+	// duplicate key or a failed write condition.
+	ErrConflict = errors.New("conflict")
+
+	// ErrThrottled is returned when a request is refused for lack of
+	// throughput, either by the backend or by a gateway capacity budget.
+	ErrThrottled = errors.New("throttled")
+)
@@ -0,0 +1,131 @@
+// Package domain
+// domain/fanout.go
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+// Fan-out bounds.
+const (
+	// MaxFanOut bounds the sub-queries of a FanOutRequest.
+	MaxFanOut = 32
+	// DefaultFanOutTimeout is the shared deadline of the sub-queries unless
+	// the request sets its own.
+	DefaultFanOutTimeout = 10 * time.Second
+	// MaxFanOutTimeout bounds FanOutRequest.TimeoutMS.
+	MaxFanOutTimeout = time.Minute
+)
+
+// MergeStrategy is how the results of a fan-out are combined.
+type MergeStrategy string
+
+const (
+	// MergeConcat appends the results in the order of the sub-queries.
+	MergeConcat MergeStrategy = "concat"
+	// MergeSorted interleaves the results by SortBy.
+	MergeSorted MergeStrategy = "sorted"
+	// MergeFirst returns the first result to arrive and cancels the rest.
+	MergeFirst MergeStrategy = "first"
+)
+
+// FanOutRequest runs the same logical read against several sources at once,
+// e.g. the shards or regions of one data set, and merges the results.
+type FanOutRequest struct {
+	Queries []QueryRequest `json:"queries"`
+	// Merge defaults to MergeConcat.
+	Merge  MergeStrategy `json:"merge,omitempty"`
+	SortBy []SortField   `json:"sort_by,omitempty"`
+	// Limit caps the merged result; 0 keeps every row.
+	Limit int `json:"limit,omitempty"`
+	// TimeoutMS is the deadline every sub-query shares, in milliseconds.
+	TimeoutMS int `json:"timeout_ms,omitempty"`
+}
+
+// SubQueryStatus is the outcome of one sub-query of a fan-out.
+type SubQueryStatus string
+
+const (
+	SubQueryOK       SubQueryStatus = "ok"
+	SubQueryFailed   SubQueryStatus = "failed"
+	SubQueryTimedOut SubQueryStatus = "timeout"
+	// SubQuerySkipped is a sub-query cancelled or ignored because another
+	// one won a MergeFirst fan-out.
+	SubQuerySkipped SubQueryStatus = "skipped"
+)
+
+// FanOutResult is the merged rows and the outcome of each sub-query, in the
+// order of the request.
+type FanOutResult struct {
+	Items   []map[string]any `json:"items"`
+	Sources []SubQueryResult `json:"sources"`
+	// Partial is set when some sub-query failed or timed out, so Items
+	// lacks its rows.
+	Partial bool `json:"partial"`
+	// ConsumedCapacity is set by the transport when a data source recorded
+	// the capacity the request consumed.
+	ConsumedCapacity *CapacityUnits `json:"consumed_capacity,omitempty"`
+}
+
+type SubQueryResult struct {
+	Source string         `json:"source"`
+	Status SubQueryStatus `json:"status"`
+	Rows   int            `json:"rows"`
+	Error  string         `json:"error,omitempty"`
+}
+
+// Validate checks the request's shape and every sub-query.
+func (r *FanOutRequest) Validate() error {
+	if len(r.Queries) == 0 || len(r.Queries) > MaxFanOut {
+		return fmt.Errorf("%w: a fan-out needs between 1 and %d 'queries'", ErrInvalidRequest, MaxFanOut)
+	}
+	for i, q := range r.Queries {
+		if q.Source == "" {
+			return fmt.Errorf("%w: queries[%d] is missing 'source'", ErrInvalidRequest, i)
+		}
+		if q.Query == nil {
+			continue
+		}
+		if err := q.Query.Validate(); err != nil {
+			return fmt.Errorf("queries[%d]: %w", i, err)
+		}
+		if q.Query.PageSize > 0 {
+			return fmt.Errorf("%w: queries[%d]: fan-out queries cannot be paginated", ErrInvalidRequest, i)
+		}
+	}
+
+	switch r.Merge {
+	case "", MergeConcat, MergeFirst:
+		if len(r.SortBy) > 0 {
+			return fmt.Errorf("%w: 'sort_by' needs the 'sorted' merge", ErrInvalidRequest)
+		}
+	case MergeSorted:
+		if len(r.SortBy) == 0 {
+			return fmt.Errorf("%w: the 'sorted' merge needs 'sort_by'", ErrInvalidRequest)
+		}
+		for _, s := range r.SortBy {
+			if s.Field == "" {
+				return fmt.Errorf("%w: sort_by entry is missing 'field'", ErrInvalidRequest)
+			}
+		}
+	default:
+		return fmt.Errorf("%w: unknown merge '%s', want \"concat\", \"sorted\" or \"first\"", ErrInvalidRequest, r.Merge)
+	}
+
+	if r.Limit < 0 {
+		return fmt.Errorf("%w: 'limit' must not be negative", ErrInvalidRequest)
+	}
+	if r.TimeoutMS < 0 || time.Duration(r.TimeoutMS)*time.Millisecond > MaxFanOutTimeout {
+		return fmt.Errorf("%w: 'timeout_ms' must be between 1 and %d", ErrInvalidRequest, MaxFanOutTimeout.Milliseconds())
+	}
+	return nil
+}
+
+// Timeout is the shared deadline of the sub-queries.
+func (r *FanOutRequest) Timeout() time.Duration {
+	if r.TimeoutMS == 0 {
+		return DefaultFanOutTimeout
+	}
+	return time.Duration(r.TimeoutMS) * time.Millisecond
+}
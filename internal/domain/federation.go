@@ -0,0 +1,177 @@
+// Package domain
+// domain/federation.go
+package domain
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// Stage limits of a FederatedRequest.
+const (
+	// DefaultStageLimit is how many rows a stage may read unless it sets
+	// its own limit.
+	DefaultStageLimit = 1000
+	// MaxStageLimit bounds Stage.Limit.
+	MaxStageLimit = 10000
+	// DefaultJoinBatch is how many keys one lookup of a joined stage asks
+	// for: the most a DynamoDB BatchGetItem call takes.
+	DefaultJoinBatch = 100
+	// MaxJoinBatch bounds Join.Batch. Sources that take fewer keys at a
+	// time implement KeyBatcher.
+	MaxJoinBatch = 1000
+)
+
+// KeyBatcher is implemented by data sources that take fewer keys in one
+// "in" condition than MaxJoinBatch. Joins onto them look up at most MaxKeys
+// keys at a time, whatever Join.Batch asks for.
+type KeyBatcher interface {
+	MaxKeys() int
+}
+
+// JoinKey is the text form join values are matched by, so that keys compare
+// equal across sources that decode numbers to different types: a Postgres
+// bigint 1000000, a Mongo int32 and a DynamoDB float64 1e+06 all give
+// "1000000". Missing and composite values are not keys.
+func JoinKey(v any) (string, bool) {
+	switch n := v.(type) {
+	case nil, map[string]any, []any:
+		return "", false
+	case float64:
+		return strconv.FormatFloat(n, 'f', -1, 64), true
+	case float32:
+		return strconv.FormatFloat(float64(n), 'f', -1, 32), true
+	case json.Number:
+		if i, err := n.Int64(); err == nil {
+			return strconv.FormatInt(i, 10), true
+		}
+		if f, err := n.Float64(); err == nil {
+			return strconv.FormatFloat(f, 'f', -1, 64), true
+		}
+		return n.String(), true
+	}
+	return fmt.Sprint(v), true
+}
+
+// FederatedRequest reads from several data sources and joins the results.
+// The first stage reads the root rows; every later stage joins onto the
+// rows of an earlier one, fetching its matches with batched key lookups.
+type FederatedRequest struct {
+	Stages []Stage `json:"stages"`
+}
+
+// Stage is one structured query of a federated request.
+type Stage struct {
+	// Name identifies the stage in joins and in the result's provenance.
+	Name   string         `json:"name"`
+	Source string         `json:"source"`
+	Params map[string]any `json:"params,omitempty"`
+	Query  Query          `json:"query"`
+	// Join links the stage to an earlier one; the first stage has none.
+	Join *Join `json:"join,omitempty"`
+	// Limit is how many rows the stage may read in all (default
+	// DefaultStageLimit). A stage that matches more fails the request
+	// rather than returning a partial join.
+	Limit int `json:"limit,omitempty"`
+}
+
+// Join looks up the rows of a stage whose On field equals the From field
+// of an earlier stage's rows. Rows without a match are kept.
+type Join struct {
+	// Stage is the earlier stage joined onto; empty means the previous one.
+	Stage string `json:"stage,omitempty"`
+	From  string `json:"from"`
+	On    string `json:"on"`
+	// As is the field the matches are put under: the first match, or all
+	// of them as a list when Many is set. Empty merges the first match's
+	// fields into the row, keeping the row's own fields on a clash.
+	As   string `json:"as,omitempty"`
+	Many bool   `json:"many,omitempty"`
+	// Batch is how many keys one lookup asks for (default
+	// DefaultJoinBatch).
+	Batch int `json:"batch,omitempty"`
+}
+
+// FederatedResult is the root rows with every join applied.
+type FederatedResult struct {
+	Items []map[string]any `json:"items"`
+	// Provenance names where each field of the items came from, by its
+	// dotted path: "name", "orders", "orders.sku".
+	Provenance map[string]FieldSource `json:"provenance"`
+	// ConsumedCapacity is set by the transport when a data source recorded
+	// the capacity the request consumed.
+	ConsumedCapacity *CapacityUnits `json:"consumed_capacity,omitempty"`
+}
+
+// FieldSource is the stage a field was read by.
+type FieldSource struct {
+	Stage  string `json:"stage"`
+	Source string `json:"source"`
+	Target string `json:"target"`
+}
+
+// Validate checks the shape of the request and of every stage's query.
+func (r *FederatedRequest) Validate() error {
+	if len(r.Stages) == 0 {
+		return fmt.Errorf("%w: federated request has no 'stages'", ErrInvalidRequest)
+	}
+	seen := make(map[string]bool, len(r.Stages))
+	for i := range r.Stages {
+		st := &r.Stages[i]
+		if st.Name == "" {
+			return fmt.Errorf("%w: stage %d is missing 'name'", ErrInvalidRequest, i)
+		}
+		if seen[st.Name] {
+			return fmt.Errorf("%w: stage name '%s' is used twice", ErrInvalidRequest, st.Name)
+		}
+		if st.Source == "" {
+			return fmt.Errorf("%w: stage '%s' is missing 'source'", ErrInvalidRequest, st.Name)
+		}
+		if err := st.Query.Validate(); err != nil {
+			return fmt.Errorf("stage '%s': %w", st.Name, err)
+		}
+		if st.Query.PageSize > 0 {
+			return fmt.Errorf("%w: stage '%s': federated stages cannot be paginated", ErrInvalidRequest, st.Name)
+		}
+		if st.Limit < 0 || st.Limit > MaxStageLimit {
+			return fmt.Errorf("%w: stage '%s': 'limit' must be between 1 and %d", ErrInvalidRequest, st.Name, MaxStageLimit)
+		}
+		if err := st.validateJoin(i, seen); err != nil {
+			return err
+		}
+		seen[st.Name] = true
+	}
+	return nil
+}
+
+func (st *Stage) validateJoin(i int, earlier map[string]bool) error {
+	if i == 0 {
+		if st.Join != nil {
+			return fmt.Errorf("%w: the first stage '%s' cannot join", ErrInvalidRequest, st.Name)
+		}
+		return nil
+	}
+	j := st.Join
+	if j == nil {
+		return fmt.Errorf("%w: stage '%s' is missing 'join'", ErrInvalidRequest, st.Name)
+	}
+	if j.Stage != "" && !earlier[j.Stage] {
+		return fmt.Errorf("%w: stage '%s' joins unknown or later stage '%s'", ErrInvalidRequest, st.Name, j.Stage)
+	}
+	if j.From == "" || j.On == "" {
+		return fmt.Errorf("%w: stage '%s': join needs 'from' and 'on'", ErrInvalidRequest, st.Name)
+	}
+	if j.Many && j.As == "" {
+		return fmt.Errorf("%w: stage '%s': a 'many' join needs 'as'", ErrInvalidRequest, st.Name)
+	}
+	if j.Batch < 0 || j.Batch > MaxJoinBatch {
+		return fmt.Errorf("%w: stage '%s': 'batch' must be between 1 and %d", ErrInvalidRequest, st.Name, MaxJoinBatch)
+	}
+	// Each lookup reads the matches of its own keys, so limits and offsets
+	// would cut across rows of different parents.
+	if st.Query.Limit > 0 || st.Query.Offset > 0 {
+		return fmt.Errorf("%w: stage '%s': joined stages take 'limit' on the stage, not on its query", ErrInvalidRequest, st.Name)
+	}
+	return nil
+}
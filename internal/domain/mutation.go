@@ -0,0 +1,181 @@
+// Package domain
+// domain/mutation.go
+package domain
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// MutationOp is the kind of write a Mutation performs.
+type MutationOp string
+
+const (
+	MutationInsert MutationOp = "insert"
+	MutationUpdate MutationOp = "update"
+	MutationUpsert MutationOp = "upsert"
+	MutationDelete MutationOp = "delete"
+)
+
+// Mutation is a backend-neutral write, translated by each adapter the same
+// way a Query is.
+type Mutation struct {
+	Op MutationOp `json:"op"`
+	// Target is the table or collection to write.
+	Target string `json:"target"`
+	// Documents are the rows or items to insert or upsert.
+	Documents []map[string]any `json:"documents,omitempty"`
+	// Key selects a single row or item by equality on its key fields.
+	// DynamoDB updates and deletes require it; elsewhere it is shorthand
+	// for equality predicates ANDed with Where.
+	Key   map[string]any `json:"key,omitempty"`
+	Where *Condition     `json:"where,omitempty"`
+	// All must be set to update or delete without a Key or Where.
+	All bool `json:"all,omitempty"`
+	// Set, Unset and Inc describe an update: assign values, clear fields
+	// and add to numeric fields.
+	Set   map[string]any `json:"set,omitempty"`
+	Unset []string       `json:"unset,omitempty"`
+	Inc   map[string]any `json:"inc,omitempty"`
+	// OnConflict names the fields that identify an existing row for an
+	// upsert. DynamoDB always uses the table's primary key.
+	OnConflict []string `json:"on_conflict,omitempty"`
+	// Condition must hold for the write to apply. DynamoDB evaluates it
+	// against the existing item and fails the write with ErrConflict;
+	// elsewhere it narrows the rows written.
+	Condition *Condition `json:"condition,omitempty"`
+	// Returning lists the fields to return from written rows; ["*"]
+	// returns every field.
+	Returning []string `json:"returning,omitempty"`
+}
+
+// MutationRequest targets one data source with a Mutation. Params carry
+// source-level options, e.g. the Mongo database.
+type MutationRequest struct {
+	Source   string         `json:"source"`
+	Params   map[string]any `json:"params,omitempty"`
+	Mutation Mutation       `json:"mutation"`
+	// Access is the route's allow-list; nil for unrestricted requests.
+	Access *Access `json:"-"`
+	// Tables is the route's mapping of the table names it uses to the
+	// source's, for sources that support one; nil outside routes.
+	Tables map[string]string `json:"-"`
+}
+
+// MutationResult reports what a write changed.
+type MutationResult struct {
+	Op       MutationOp       `json:"-"`
+	Affected int64            `json:"affected"`
+	Rows     []map[string]any `json:"rows,omitempty"`
+	// ConsumedCapacity is set by the transport when a data source recorded
+	// the capacity the request consumed.
+	ConsumedCapacity *CapacityUnits `json:"consumed_capacity,omitempty"`
+}
+
+// Mutator is implemented by data sources that accept writes.
+type Mutator interface {
+	Mutate(ctx context.Context, req MutationRequest) (*MutationResult, error)
+}
+
+// Validate checks the mutation's shape. Adapters may still reject parts
+// their backend cannot express.
+func (m *Mutation) Validate() error {
+	if m.Target == "" {
+		return fmt.Errorf("%w: mutation is missing 'target'", ErrInvalidRequest)
+	}
+
+	hasDocuments := len(m.Documents) > 0
+	hasUpdate := len(m.Set) > 0 || len(m.Unset) > 0 || len(m.Inc) > 0
+	hasFilter := len(m.Key) > 0 || m.Where != nil
+
+	switch m.Op {
+	case MutationInsert, MutationUpsert:
+		if !hasDocuments {
+			return fmt.Errorf("%w: %s needs 'documents'", ErrInvalidRequest, m.Op)
+		}
+		if hasUpdate || hasFilter || m.All {
+			return fmt.Errorf("%w: %s takes 'documents', not 'set', 'key' or 'where'", ErrInvalidRequest, m.Op)
+		}
+	case MutationUpdate, MutationDelete:
+		if hasDocuments {
+			return fmt.Errorf("%w: %s does not take 'documents'", ErrInvalidRequest, m.Op)
+		}
+		if m.Op == MutationUpdate && !hasUpdate {
+			return fmt.Errorf("%w: update needs 'set', 'unset' or 'inc'", ErrInvalidRequest)
+		}
+		if m.Op == MutationDelete && hasUpdate {
+			return fmt.Errorf("%w: delete does not take 'set', 'unset' or 'inc'", ErrInvalidRequest)
+		}
+		if !hasFilter && !m.All {
+			return fmt.Errorf("%w: %s needs 'key' or 'where', or 'all' to write every row", ErrInvalidRequest, m.Op)
+		}
+	default:
+		return fmt.Errorf("%w: unknown mutation op '%s'", ErrInvalidRequest, m.Op)
+	}
+
+	if m.Op != MutationUpsert && len(m.OnConflict) > 0 {
+		return fmt.Errorf("%w: 'on_conflict' only applies to upsert", ErrInvalidRequest)
+	}
+	for field, v := range m.Inc {
+		switch v.(type) {
+		case float64, int, int64:
+		default:
+			return fmt.Errorf("%w: inc.%s must be a number", ErrInvalidRequest, field)
+		}
+	}
+	for field := range m.Set {
+		if _, ok := m.Inc[field]; ok {
+			return fmt.Errorf("%w: field '%s' is both set and incremented", ErrInvalidRequest, field)
+		}
+	}
+	for _, field := range m.Unset {
+		if _, ok := m.Set[field]; ok {
+			return fmt.Errorf("%w: field '%s' is both set and unset", ErrInvalidRequest, field)
+		}
+		if _, ok := m.Inc[field]; ok {
+			return fmt.Errorf("%w: field '%s' is both incremented and unset", ErrInvalidRequest, field)
+		}
+	}
+	if m.Where != nil {
+		if err := m.Where.validate("where"); err != nil {
+			return err
+		}
+	}
+	if m.Condition != nil {
+		if err := m.Condition.validate("condition"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Filter combines Key, Where and Condition into one condition, or nil when
+// the mutation applies to every row. For adapters where a condition simply
+// narrows the rows written.
+func (m *Mutation) Filter() *Condition {
+	fields := make([]string, 0, len(m.Key))
+	for field := range m.Key {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	var parts []Condition
+	for _, field := range fields {
+		parts = append(parts, Condition{Field: field, Op: OpEq, Value: m.Key[field]})
+	}
+	if m.Where != nil {
+		parts = append(parts, *m.Where)
+	}
+	if m.Condition != nil {
+		parts = append(parts, *m.Condition)
+	}
+	switch len(parts) {
+	case 0:
+		return nil
+	case 1:
+		return &parts[0]
+	default:
+		return &Condition{And: parts}
+	}
+}
@@ -0,0 +1,24 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMutationValidateConflictingUpdates(t *testing.T) {
+	for name, m := range map[string]Mutation{
+		"set and unset": {Set: map[string]any{"status": "open"}, Unset: []string{"status"}},
+		"inc and unset": {Inc: map[string]any{"count": 1.0}, Unset: []string{"count"}},
+		"set and inc":   {Set: map[string]any{"count": 2.0}, Inc: map[string]any{"count": 1.0}},
+	} {
+		m.Op, m.Target, m.All = MutationUpdate, "orders", true
+		if err := m.Validate(); !errors.Is(err, ErrInvalidRequest) {
+			t.Errorf("%s: Validate() = %v, want ErrInvalidRequest", name, err)
+		}
+	}
+
+	m := Mutation{Op: MutationUpdate, Target: "orders", All: true, Set: map[string]any{"status": "open"}, Unset: []string{"note"}}
+	if err := m.Validate(); err != nil {
+		t.Errorf("Validate() = %v", err)
+	}
+}
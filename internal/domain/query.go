@@ -0,0 +1,151 @@
+// Package domain
+// domain/query.go
+package domain
+
+import "fmt"
+
+// Operator is a field comparison in a structured query.
+type Operator string
+
+const (
+	OpEq     Operator = "eq"
+	OpNe     Operator = "ne"
+	OpGt     Operator = "gt"
+	OpGte    Operator = "gte"
+	OpLt     Operator = "lt"
+	OpLte    Operator = "lte"
+	OpIn     Operator = "in"
+	OpLike   Operator = "like"
+	OpExists Operator = "exists"
+)
+
+// Query is a backend-neutral read. Each adapter translates it into its own
+// language (parameterized SQL, a bson filter, Dynamo expressions), so a caller
+// can move a collection between backends without rewriting its requests.
+type Query struct {
+	// Target is the table or collection to read.
+	Target string     `json:"target"`
+	Where  *Condition `json:"where,omitempty"`
+	// Select lists the fields to return; empty means all fields.
+	Select []string    `json:"select,omitempty"`
+	Sort   []SortField `json:"sort,omitempty"`
+	Limit  int         `json:"limit,omitempty"`
+	Offset int         `json:"offset,omitempty"`
+	// PageSize asks for one page of results as a Page instead of the whole
+	// result; PageToken is the NextToken of the previous page.
+	PageSize  int    `json:"page_size,omitempty"`
+	PageToken string `json:"page_token,omitempty"`
+	// Cursor is the verified position PageToken encodes, in the adapter's
+	// own format. It is set by the gateway, never by callers.
+	Cursor []byte `json:"-"`
+}
+
+// MaxPageSize bounds Query.PageSize.
+const MaxPageSize = 1000
+
+// Page is one page of a paginated query. NextToken is empty on the last
+// page.
+type Page struct {
+	Items     []map[string]any `json:"items"`
+	NextToken string           `json:"next_token,omitempty"`
+	// Next is the adapter's cursor for the following page, or nil on the
+	// last page. The gateway signs it into NextToken.
+	Next []byte `json:"-"`
+	// ConsumedCapacity is set by the transport when a data source recorded
+	// the capacity the request consumed.
+	ConsumedCapacity *CapacityUnits `json:"consumed_capacity,omitempty"`
+}
+
+// Condition is either a field predicate (Field, Op, Value) or a group of
+// nested conditions joined with And or Or.
+type Condition struct {
+	And   []Condition `json:"and,omitempty"`
+	Or    []Condition `json:"or,omitempty"`
+	Field string      `json:"field,omitempty"`
+	Op    Operator    `json:"op,omitempty"`
+	Value any         `json:"value"`
+}
+
+type SortField struct {
+	Field string `json:"field"`
+	Desc  bool   `json:"desc,omitempty"`
+}
+
+// Validate checks the query's shape. Translators may still reject operators
+// their backend cannot express.
+func (q *Query) Validate() error {
+	if q.Target == "" {
+		return fmt.Errorf("%w: query is missing 'target'", ErrInvalidRequest)
+	}
+	if q.Limit < 0 || q.Offset < 0 {
+		return fmt.Errorf("%w: 'limit' and 'offset' must not be negative", ErrInvalidRequest)
+	}
+	if q.PageSize < 0 || q.PageSize > MaxPageSize {
+		return fmt.Errorf("%w: 'page_size' must be between 1 and %d", ErrInvalidRequest, MaxPageSize)
+	}
+	if q.PageSize > 0 && (q.Limit > 0 || q.Offset > 0) {
+		return fmt.Errorf("%w: 'page_size' cannot be combined with 'limit' or 'offset'", ErrInvalidRequest)
+	}
+	if q.PageToken != "" && q.PageSize == 0 {
+		return fmt.Errorf("%w: 'page_token' needs 'page_size'", ErrInvalidRequest)
+	}
+	for _, s := range q.Sort {
+		if s.Field == "" {
+			return fmt.Errorf("%w: sort entry is missing 'field'", ErrInvalidRequest)
+		}
+	}
+	if q.Where != nil {
+		return q.Where.validate("where")
+	}
+	return nil
+}
+
+func (c *Condition) validate(path string) error {
+	groups := 0
+	if c.And != nil {
+		groups++
+	}
+	if c.Or != nil {
+		groups++
+	}
+	if groups > 0 {
+		if groups > 1 || c.Field != "" || c.Op != "" {
+			return fmt.Errorf("%w: %s must be either a predicate or a single 'and'/'or' group", ErrInvalidRequest, path)
+		}
+		children, name := c.And, "and"
+		if c.Or != nil {
+			children, name = c.Or, "or"
+		}
+		if len(children) == 0 {
+			return fmt.Errorf("%w: %s.%s must not be empty", ErrInvalidRequest, path, name)
+		}
+		for i := range children {
+			if err := children[i].validate(fmt.Sprintf("%s.%s[%d]", path, name, i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if c.Field == "" {
+		return fmt.Errorf("%w: %s is missing 'field'", ErrInvalidRequest, path)
+	}
+	switch c.Op {
+	case OpEq, OpNe, OpGt, OpGte, OpLt, OpLte:
+	case OpIn:
+		if _, ok := c.Value.([]any); !ok {
+			return fmt.Errorf("%w: %s: 'in' needs an array value", ErrInvalidRequest, path)
+		}
+	case OpLike:
+		if _, ok := c.Value.(string); !ok {
+			return fmt.Errorf("%w: %s: 'like' needs a string pattern", ErrInvalidRequest, path)
+		}
+	case OpExists:
+		if _, ok := c.Value.(bool); !ok {
+			return fmt.Errorf("%w: %s: 'exists' needs a boolean value", ErrInvalidRequest, path)
+		}
+	default:
+		return fmt.Errorf("%w: %s: unknown operator '%s'", ErrInvalidRequest, path, c.Op)
+	}
+	return nil
+}
@@ -0,0 +1,14 @@
+// Package domain
+// domain/route.go
+package domain
+
+import "net/url"
+
+// RouteRequest is a call to a configured business endpoint. Its path
+// parameters, query string and body fill the route's query template.
+type RouteRequest struct {
+	Method string
+	Path   string
+	Query  url.Values
+	Body   map[string]any
+}
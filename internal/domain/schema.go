@@ -0,0 +1,62 @@
+// Package domain
+// domain/schema.go
+package domain
+
+import "context"
+
+// FieldType is the backend-neutral type of a field, as sources report it in
+// their results.
+type FieldType string
+
+const (
+	FieldString FieldType = "string"
+	// FieldInt is an integer of at most 32 bits, FieldBigInt a wider one.
+	FieldInt    FieldType = "int"
+	FieldBigInt FieldType = "bigint"
+	FieldFloat  FieldType = "float"
+	// FieldDecimal is an exact number, returned as a JSON number or string.
+	FieldDecimal FieldType = "decimal"
+	FieldBool    FieldType = "bool"
+	// FieldTime is a date, time or timestamp, returned as text.
+	FieldTime FieldType = "time"
+	// FieldJSON is an object, a list, or a field whose values differ in
+	// type.
+	FieldJSON FieldType = "json"
+)
+
+// FieldTypes are the valid field types.
+var FieldTypes = map[FieldType]bool{
+	FieldString: true, FieldInt: true, FieldBigInt: true, FieldFloat: true,
+	FieldDecimal: true, FieldBool: true, FieldTime: true, FieldJSON: true,
+}
+
+// Field is one field of a table or collection.
+type Field struct {
+	Name string    `json:"name"`
+	Type FieldType `json:"type"`
+}
+
+// TableSchema is the shape of a table or collection. Sources without a
+// fixed schema report what they observe, e.g. in a sample of documents.
+type TableSchema struct {
+	Fields []Field `json:"fields"`
+	// Key lists the primary key fields, in key order.
+	Key []string `json:"key,omitempty"`
+}
+
+// DescribeRequest names the table or collection to describe. Params carry
+// source-level options, e.g. the Mongo database.
+type DescribeRequest struct {
+	Source string
+	Target string
+	Params map[string]any
+	// Sample is how many documents a schemaless source reads to infer its
+	// fields.
+	Sample int
+}
+
+// Describer is implemented by data sources that can report the fields of
+// their tables or collections.
+type Describer interface {
+	Describe(ctx context.Context, req DescribeRequest) (*TableSchema, error)
+}
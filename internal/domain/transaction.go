@@ -0,0 +1,48 @@
+// Package domain
+// domain/transaction.go
+package domain
+
+import (
+	"context"
+	"fmt"
+)
+
+// TransactionRequest runs several mutations against one data source as a
+// single atomic unit: either every step applies or none does.
+type TransactionRequest struct {
+	Source string         `json:"source"`
+	Params map[string]any `json:"params,omitempty"`
+	Steps  []Mutation     `json:"steps"`
+	// Access is the route's allow-list; nil for unrestricted requests.
+	Access *Access `json:"-"`
+}
+
+// TransactionResult holds one result per step, in order.
+type TransactionResult struct {
+	Steps []*MutationResult `json:"steps"`
+	// ConsumedCapacity is set by the transport when a data source recorded
+	// the capacity the request consumed.
+	ConsumedCapacity *CapacityUnits `json:"consumed_capacity,omitempty"`
+}
+
+// Transactor is implemented by data sources that can apply several
+// mutations atomically.
+type Transactor interface {
+	Transact(ctx context.Context, req TransactionRequest) (*TransactionResult, error)
+}
+
+// StepError reports the step that made a transaction fail. The transaction
+// was rolled back, so no step was applied.
+type StepError struct {
+	Step int // zero-based index into Steps
+	Op   MutationOp
+	Err  error
+}
+
+func (e *StepError) Error() string {
+	return fmt.Sprintf("step %d (%s): %v", e.Step, e.Op, e.Err)
+}
+
+func (e *StepError) Unwrap() error {
+	return e.Err
+}
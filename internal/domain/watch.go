@@ -0,0 +1,85 @@
+// Package domain
+// domain/watch.go
+package domain
+
+import (
+	"context"
+	"iter"
+	"time"
+)
+
+// WatchRequest subscribes to the changes of one data source. Params select
+// what to watch, e.g. the Mongo database and collection.
+type WatchRequest struct {
+	Source string         `json:"source"`
+	Params map[string]any `json:"params"`
+	// ResumeAfter is the ID of the last change the caller received. The
+	// feed continues with the change after it; empty starts from now.
+	ResumeAfter string `json:"resume_after,omitempty"`
+	// Access is the route's allow-list; nil for unrestricted requests.
+	Access *Access `json:"-"`
+}
+
+// ChangeOp is the kind of change a Change reports. Sources pass through
+// kinds of their own, e.g. Mongo's "drop".
+type ChangeOp string
+
+const (
+	ChangeInsert  ChangeOp = "insert"
+	ChangeUpdate  ChangeOp = "update"
+	ChangeReplace ChangeOp = "replace"
+	ChangeDelete  ChangeOp = "delete"
+)
+
+// Change is one event of a change feed.
+type Change struct {
+	// ID is an opaque resume position: watching again with it as
+	// ResumeAfter delivers the changes after this one.
+	ID string   `json:"id"`
+	Op ChangeOp `json:"op"`
+	// Target is the table or collection that changed.
+	Target string `json:"target,omitempty"`
+	// Key identifies the changed row or document.
+	Key map[string]any `json:"key,omitempty"`
+	// Document is the row or document after the change and Before the one
+	// it replaced, when the source provides them.
+	Document map[string]any `json:"document,omitempty"`
+	Before   map[string]any `json:"before,omitempty"`
+	// Updated and Removed list the fields an update set and cleared.
+	Updated map[string]any `json:"updated,omitempty"`
+	Removed []string       `json:"removed,omitempty"`
+	Time    time.Time      `json:"time,omitzero"`
+}
+
+// Expose keeps only the given columns of the key, the document, the before
+// image and the updated fields; nil columns keep everything.
+func (c Change) Expose(columns map[string]bool) Change {
+	if columns == nil {
+		return c
+	}
+	for _, values := range []*map[string]any{&c.Key, &c.Document, &c.Before, &c.Updated} {
+		if *values == nil {
+			continue
+		}
+		kept := make(map[string]any, len(*values))
+		for name, v := range *values {
+			if columns[name] {
+				kept[name] = v
+			}
+		}
+		*values = kept
+	}
+	return c
+}
+
+// ChangeStream yields changes as they happen until the context ends or the
+// source closes the feed. A failure is yielded as the last element.
+// Breaking out of the loop closes the feed.
+type ChangeStream = iter.Seq2[Change, error]
+
+// Watcher is implemented by data sources that can report their changes.
+// The feed is open when Watch returns, so setup errors surface before any
+// change is sent; the caller must range over the stream to release it.
+type Watcher interface {
+	Watch(ctx context.Context, req WatchRequest) (ChangeStream, error)
+}
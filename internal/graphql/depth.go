@@ -0,0 +1,65 @@
+// Package graphql
+// internal/graphql/depth.go
+package graphql
+
+import (
+	"strings"
+
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+)
+
+// queryDepth is how deeply the operations of a query nest their fields; a
+// root field is at depth 1. Introspection fields are not counted, since
+// their depth is bounded by the schema rather than the data. A query that
+// does not parse has depth 0 and is left for graphql-go to report.
+func queryDepth(query string) int {
+	doc, err := parser.Parse(parser.ParseParams{Source: query})
+	if err != nil {
+		return 0
+	}
+	fragments := make(map[string]*ast.FragmentDefinition)
+	for _, def := range doc.Definitions {
+		if f, ok := def.(*ast.FragmentDefinition); ok {
+			fragments[f.Name.Value] = f
+		}
+	}
+
+	var depth func(set *ast.SelectionSet, visiting map[string]bool) int
+	depth = func(set *ast.SelectionSet, visiting map[string]bool) int {
+		if set == nil {
+			return 0
+		}
+		deepest := 0
+		for _, sel := range set.Selections {
+			d := 0
+			switch sel := sel.(type) {
+			case *ast.Field:
+				if strings.HasPrefix(sel.Name.Value, "__") {
+					continue
+				}
+				d = 1 + depth(sel.SelectionSet, visiting)
+			case *ast.InlineFragment:
+				d = depth(sel.SelectionSet, visiting)
+			case *ast.FragmentSpread:
+				// Fragment cycles are invalid; graphql-go reports them.
+				name := sel.Name.Value
+				if f, ok := fragments[name]; ok && !visiting[name] {
+					visiting[name] = true
+					d = depth(f.SelectionSet, visiting)
+					delete(visiting, name)
+				}
+			}
+			deepest = max(deepest, d)
+		}
+		return deepest
+	}
+
+	deepest := 0
+	for _, def := range doc.Definitions {
+		if op, ok := def.(*ast.OperationDefinition); ok {
+			deepest = max(deepest, depth(op.SelectionSet, make(map[string]bool)))
+		}
+	}
+	return deepest
+}
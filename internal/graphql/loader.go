@@ -0,0 +1,127 @@
+// Package graphql
+// internal/graphql/loader.go
+package graphql
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/thegodeveloper/data-gateway/internal/domain"
+)
+
+type loaderKey struct{}
+
+// loader batches the lookups of relation fields within one request. Each
+// lookup registers its key and returns a thunk. graphql-go calls thunks
+// breadth-first, after every field of their depth has been resolved, so the
+// first thunk called reads the matches of all of them at once, as "in"
+// conditions of DefaultJoinBatch keys.
+//
+// graphql-go resolves one field at a time, so a loader needs no locking.
+type loader struct {
+	s *Schema
+	// pending are the batches still collecting keys, by relation and
+	// selection.
+	pending map[string]*batch
+	// budget is how many more rows the request may read.
+	budget int
+}
+
+type batch struct {
+	rel  *relation
+	sel  []string
+	keys []any
+	seen map[string]bool
+	done bool
+	// matches are the rows of the target, by key.
+	matches map[string][]map[string]any
+	err     error
+}
+
+// load returns the value of relation r for a row whose From field is v:
+// a thunk, or the empty value when v is missing.
+func (l *loader) load(ctx context.Context, r *relation, sel []string, v any) any {
+	k, ok := domain.JoinKey(v)
+	if !ok {
+		if r.cfg.Many {
+			return []map[string]any{}
+		}
+		return nil
+	}
+
+	id := r.name + "\x00" + strings.Join(sel, ",")
+	b := l.pending[id]
+	if b == nil {
+		b = &batch{rel: r, sel: sel, seen: make(map[string]bool)}
+		l.pending[id] = b
+	}
+	if !b.seen[k] {
+		b.seen[k] = true
+		b.keys = append(b.keys, v)
+	}
+
+	return func() (any, error) {
+		if !b.done {
+			// Lookups registered from now on start a new batch.
+			if l.pending[id] == b {
+				delete(l.pending, id)
+			}
+			b.fetch(ctx, l.s)
+		}
+		if b.err != nil {
+			return nil, b.err
+		}
+		rows := b.matches[k]
+		if r.cfg.Many {
+			if rows == nil {
+				rows = []map[string]any{}
+			}
+			return rows, nil
+		}
+		if len(rows) == 0 {
+			return nil, nil
+		}
+		return rows[0], nil
+	}
+}
+
+// fetch reads the matches of every key of the batch. A relation field
+// returns at most its target's limit of rows, and one batch reads at most
+// MaxStageLimit rows in all; a batch matching more fails.
+func (b *batch) fetch(ctx context.Context, s *Schema) {
+	b.done = true
+	t, on := b.rel.target, b.rel.cfg.On
+	sel := b.sel
+	if !slices.Contains(sel, on) {
+		sel = append(slices.Clip(sel), on)
+	}
+
+	b.matches = make(map[string][]map[string]any)
+	total := 0
+	for keys := range slices.Chunk(b.keys, domain.DefaultJoinBatch) {
+		q := &domain.Query{
+			Target: t.cfg.Target,
+			Select: sel,
+			Where:  &domain.Condition{Field: on, Op: domain.OpIn, Value: keys},
+			Limit:  domain.MaxStageLimit - total + 1,
+		}
+		rows, err := s.rows(ctx, t, q)
+		if err != nil {
+			b.err = err
+			return
+		}
+		total += len(rows)
+		if total > domain.MaxStageLimit {
+			b.err = fmt.Errorf("%w: relation '%s' matched more than %d rows; narrow the query", domain.ErrInvalidRequest, b.rel.name, domain.MaxStageLimit)
+			return
+		}
+		for _, row := range rows {
+			k, ok := domain.JoinKey(row[on])
+			if ok && len(b.matches[k]) < t.limit {
+				b.matches[k] = append(b.matches[k], row)
+			}
+		}
+	}
+}
@@ -0,0 +1,207 @@
+// Package graphql
+// internal/graphql/scalars.go
+package graphql
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	gql "github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/thegodeveloper/data-gateway/internal/domain"
+)
+
+// BigInt carries integers wider than the 32 bits of Int. It is output as a
+// string, since JSON clients commonly lose digits above 2^53.
+var BigInt = gql.NewScalar(gql.ScalarConfig{
+	Name:        "BigInt",
+	Description: "A 64-bit integer, output as a string.",
+	Serialize: func(v any) any {
+		if n, ok := parseBigInt(v); ok {
+			return strconv.FormatInt(n, 10)
+		}
+		return nil
+	},
+	ParseValue: func(v any) any {
+		if n, ok := parseBigInt(v); ok {
+			return n
+		}
+		return nil
+	},
+	ParseLiteral: func(v ast.Value) any {
+		switch v := v.(type) {
+		case *ast.IntValue:
+			n, err := strconv.ParseInt(v.Value, 10, 64)
+			if err == nil {
+				return n
+			}
+		case *ast.StringValue:
+			n, err := strconv.ParseInt(v.Value, 10, 64)
+			if err == nil {
+				return n
+			}
+		}
+		return nil
+	},
+})
+
+// Decimal is an exact number, output as the source returns it: a JSON
+// number or, where that would lose precision, a string.
+var Decimal = gql.NewScalar(gql.ScalarConfig{
+	Name:        "Decimal",
+	Description: "An exact number, output as a JSON number or a string.",
+	Serialize:   decimalValue,
+	ParseValue:  decimalValue,
+	ParseLiteral: func(v ast.Value) any {
+		switch v := v.(type) {
+		case *ast.IntValue, *ast.FloatValue:
+			return literal(v)
+		case *ast.StringValue:
+			return v.Value
+		}
+		return nil
+	},
+})
+
+// DateTime is a date, time or timestamp in the text form its source uses,
+// RFC 3339 for timestamps.
+var DateTime = gql.NewScalar(gql.ScalarConfig{
+	Name:        "DateTime",
+	Description: "A date, time or timestamp as text; timestamps are RFC 3339.",
+	Serialize: func(v any) any {
+		switch v := v.(type) {
+		case string:
+			return v
+		case time.Time:
+			return v.UTC().Format(time.RFC3339Nano)
+		case nil:
+			return nil
+		}
+		return fmt.Sprint(v)
+	},
+	ParseValue: func(v any) any {
+		if s, ok := v.(string); ok {
+			return s
+		}
+		return nil
+	},
+	ParseLiteral: func(v ast.Value) any {
+		if s, ok := v.(*ast.StringValue); ok {
+			return s.Value
+		}
+		return nil
+	},
+})
+
+// JSON is any JSON value: objects, lists, and fields whose values differ in
+// type. It is also the type of the where argument.
+var JSON = gql.NewScalar(gql.ScalarConfig{
+	Name:         "JSON",
+	Description:  "Any JSON value.",
+	Serialize:    func(v any) any { return v },
+	ParseValue:   func(v any) any { return v },
+	ParseLiteral: literal,
+})
+
+// outputTypes map field types to GraphQL scalars.
+var outputTypes = map[domain.FieldType]*gql.Scalar{
+	domain.FieldString:  gql.String,
+	domain.FieldInt:     gql.Int,
+	domain.FieldBigInt:  BigInt,
+	domain.FieldFloat:   gql.Float,
+	domain.FieldDecimal: Decimal,
+	domain.FieldBool:    gql.Boolean,
+	domain.FieldTime:    DateTime,
+	domain.FieldJSON:    JSON,
+}
+
+// value prepares a field of a result row for the scalar of its type. The
+// built-in scalars do not know json.Number, which MongoDB's extended JSON
+// output and Postgres numerics produce, nor the raw bytes of DynamoDB
+// binary attributes.
+func value(typ domain.FieldType, v any) any {
+	switch v := v.(type) {
+	case json.Number:
+		switch typ {
+		case domain.FieldInt, domain.FieldBigInt:
+			if n, err := v.Int64(); err == nil {
+				return n
+			}
+		case domain.FieldFloat:
+			if f, err := v.Float64(); err == nil {
+				return f
+			}
+		}
+		return v
+	case []byte:
+		return base64.StdEncoding.EncodeToString(v)
+	}
+	return v
+}
+
+func parseBigInt(v any) (int64, bool) {
+	switch n := v.(type) {
+	case int:
+		return int64(n), true
+	case int32:
+		return int64(n), true
+	case int64:
+		return n, true
+	case float64:
+		if n == float64(int64(n)) {
+			return int64(n), true
+		}
+	case json.Number:
+		if i, err := n.Int64(); err == nil {
+			return i, true
+		}
+	case string:
+		if i, err := strconv.ParseInt(n, 10, 64); err == nil {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+func decimalValue(v any) any {
+	switch n := v.(type) {
+	case string, json.Number, float64, int, int32, int64:
+		return n
+	}
+	return nil
+}
+
+// literal converts an inline argument to the value encoding/json would
+// decode it to, so the adapters see what a JSON request would give them.
+func literal(v ast.Value) any {
+	switch v := v.(type) {
+	case *ast.ObjectValue:
+		out := make(map[string]any, len(v.Fields))
+		for _, f := range v.Fields {
+			out[f.Name.Value] = literal(f.Value)
+		}
+		return out
+	case *ast.ListValue:
+		out := make([]any, len(v.Values))
+		for i, item := range v.Values {
+			out[i] = literal(item)
+		}
+		return out
+	case *ast.StringValue:
+		return v.Value
+	case *ast.EnumValue:
+		return v.Value
+	case *ast.IntValue:
+		f, _ := strconv.ParseFloat(v.Value, 64)
+		return f
+	case *ast.FloatValue:
+		f, _ := strconv.ParseFloat(v.Value, 64)
+		return f
+	case *ast.BooleanValue:
+		return v.Value
+	}
+	return nil
+}
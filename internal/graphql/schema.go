@@ -0,0 +1,497 @@
+// Package graphql
+// internal/graphql/schema.go
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	gql "github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/thegodeveloper/data-gateway/internal/app"
+	"github.com/thegodeveloper/data-gateway/internal/config"
+	"github.com/thegodeveloper/data-gateway/internal/domain"
+	"github.com/thegodeveloper/data-gateway/pkg/common"
+)
+
+// DefaultLimit is the number of rows a field returns when its type sets no
+// limit.
+const DefaultLimit = 100
+
+// DefaultMaxDepth and DefaultMaxRows bound a request when the configuration
+// does not.
+const (
+	DefaultMaxDepth = 6
+	DefaultMaxRows  = 10000
+)
+
+// Names of the arguments of root fields. Fields with these names get no
+// equality argument.
+var reservedArgs = map[string]bool{"where": true, "sort": true, "limit": true, "offset": true}
+
+// Type names the schema defines itself.
+var reservedTypes = map[string]bool{
+	"Query": true, "SortInput": true, "String": true, "Int": true, "Float": true,
+	"Boolean": true, "ID": true, "BigInt": true, "Decimal": true, "DateTime": true, "JSON": true,
+}
+
+var namePattern = regexp.MustCompile(`^[_A-Za-z][_0-9A-Za-z]*$`)
+
+var sortInput = gql.NewInputObject(gql.InputObjectConfig{
+	Name:        "SortInput",
+	Description: "A sort key: a field of the type and its direction.",
+	Fields: gql.InputObjectConfigFieldMap{
+		"field": &gql.InputObjectFieldConfig{Type: gql.NewNonNull(gql.String)},
+		"desc":  &gql.InputObjectFieldConfig{Type: gql.Boolean},
+	},
+})
+
+// Schema is a GraphQL schema generated from the data sources. Its fields
+// compile to structured queries run through the GatewayService, so caller
+// policies, allow-lists and row limits apply to them as to /query.
+type Schema struct {
+	svc    *app.GatewayService
+	schema gql.Schema
+	// maxDepth and maxRows bound each request.
+	maxDepth int
+	maxRows  int
+}
+
+// objectType is a configured type and the fields it exposes.
+type objectType struct {
+	cfg    config.GraphQLType
+	fields map[string]domain.FieldType
+	// order lists the fields in the order the source describes them,
+	// followed by the declared ones it does not know.
+	order []string
+	// filters are the fields root queries take an equality argument for.
+	filters   []string
+	relations map[string]*relation
+	limit     int
+	access    *domain.Access
+	object    *gql.Object
+}
+
+// relation is a field of a type that resolves to rows of another type.
+type relation struct {
+	name   string
+	cfg    config.GraphQLRelation
+	target *objectType
+}
+
+// Request is a GraphQL request as POSTed in JSON or passed as GET query
+// parameters.
+type Request struct {
+	Query         string         `json:"query" form:"query"`
+	OperationName string         `json:"operationName" form:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+// NewSchema builds the schema of cfg. Each type's fields are described by
+// its data source, then extended and overridden by the declared ones, and
+// narrowed to its allow-list. Fields whose names GraphQL cannot express are
+// left out with an error logged.
+func NewSchema(ctx context.Context, svc *app.GatewayService, cfg config.GraphQL) (*Schema, error) {
+	if len(cfg.Types) == 0 {
+		return nil, fmt.Errorf("graphql needs at least one type")
+	}
+	if cfg.MaxDepth < 0 || cfg.MaxRows < 0 {
+		return nil, fmt.Errorf("graphql max_depth and max_rows must not be negative")
+	}
+	s := &Schema{svc: svc, maxDepth: cfg.MaxDepth, maxRows: cfg.MaxRows}
+	if s.maxDepth == 0 {
+		s.maxDepth = DefaultMaxDepth
+	}
+	if s.maxRows == 0 {
+		s.maxRows = DefaultMaxRows
+	}
+
+	types := make([]*objectType, 0, len(cfg.Types))
+	byName := make(map[string]*objectType, len(cfg.Types))
+	for _, tc := range cfg.Types {
+		if !validName(tc.Name) || reservedTypes[tc.Name] {
+			return nil, fmt.Errorf("graphql type '%s': invalid or reserved name", tc.Name)
+		}
+		if _, ok := byName[tc.Name]; ok {
+			return nil, fmt.Errorf("graphql type '%s' is declared twice", tc.Name)
+		}
+		t, err := s.describe(ctx, tc)
+		if err != nil {
+			return nil, fmt.Errorf("graphql type '%s': %w", tc.Name, err)
+		}
+		types = append(types, t)
+		byName[tc.Name] = t
+	}
+
+	for _, rc := range cfg.Relations {
+		if err := addRelation(rc, byName); err != nil {
+			return nil, fmt.Errorf("graphql relation '%s.%s': %w", rc.Type, rc.Field, err)
+		}
+	}
+
+	// Relations may form cycles, so object fields are built lazily.
+	for _, t := range types {
+		t.object = gql.NewObject(gql.ObjectConfig{
+			Name:   t.cfg.Name,
+			Fields: gql.FieldsThunk(func() gql.Fields { return s.fields(t) }),
+		})
+	}
+
+	roots := make(gql.Fields, len(types))
+	for _, t := range types {
+		name := t.cfg.Query
+		if name == "" {
+			r, size := utf8.DecodeRuneInString(t.cfg.Name)
+			name = string(unicode.ToLower(r)) + t.cfg.Name[size:]
+		}
+		if !validName(name) {
+			return nil, fmt.Errorf("graphql type '%s': invalid query name '%s'", t.cfg.Name, name)
+		}
+		if _, ok := roots[name]; ok {
+			return nil, fmt.Errorf("graphql type '%s': query '%s' is already used", t.cfg.Name, name)
+		}
+		roots[name] = s.rootField(t)
+	}
+
+	schema, err := gql.NewSchema(gql.SchemaConfig{
+		Query: gql.NewObject(gql.ObjectConfig{Name: "Query", Fields: roots}),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid graphql schema: %w", err)
+	}
+	s.schema = schema
+	return s, nil
+}
+
+// Execute runs a request. Errors, including those of the data sources, are
+// reported in the result as GraphQL errors. A query nesting deeper than
+// maxDepth is refused before any source is read.
+func (s *Schema) Execute(ctx context.Context, req Request) *gql.Result {
+	if depth := queryDepth(req.Query); depth > s.maxDepth {
+		return &gql.Result{Errors: []gqlerrors.FormattedError{
+			gqlerrors.NewFormattedError(fmt.Sprintf("query nests %d levels deep; at most %d are allowed", depth, s.maxDepth)),
+		}}
+	}
+	ctx = context.WithValue(ctx, loaderKey{}, &loader{s: s, pending: make(map[string]*batch), budget: s.maxRows})
+	return gql.Do(gql.Params{
+		Schema:         s.schema,
+		RequestString:  req.Query,
+		OperationName:  req.OperationName,
+		VariableValues: req.Variables,
+		Context:        ctx,
+	})
+}
+
+// describe collects the fields of a configured type.
+func (s *Schema) describe(ctx context.Context, tc config.GraphQLType) (*objectType, error) {
+	if tc.Source == "" || tc.Target == "" {
+		return nil, fmt.Errorf("'source' and 'target' are required")
+	}
+	t := &objectType{cfg: tc, fields: make(map[string]domain.FieldType), relations: make(map[string]*relation), limit: tc.Limit}
+	if t.limit == 0 {
+		t.limit = DefaultLimit
+	}
+	if t.limit < 0 || t.limit > domain.MaxStageLimit {
+		return nil, fmt.Errorf("'limit' must be between 1 and %d", domain.MaxStageLimit)
+	}
+
+	describes, err := s.svc.Describes(tc.Source)
+	if err != nil {
+		return nil, err
+	}
+	var described []domain.Field
+	switch {
+	case describes:
+		schema, err := s.svc.HandleDescribe(ctx, domain.DescribeRequest{
+			Source: tc.Source,
+			Target: tc.Target,
+			Params: tc.Params,
+			Sample: tc.Sample,
+		})
+		if err != nil {
+			return nil, err
+		}
+		described = schema.Fields
+	case len(tc.Fields) == 0:
+		return nil, fmt.Errorf("data source '%s' cannot describe '%s'; declare its fields", tc.Source, tc.Target)
+	}
+
+	for _, f := range described {
+		t.fields[f.Name] = f.Type
+		t.order = append(t.order, f.Name)
+	}
+	declared := make([]string, 0, len(tc.Fields))
+	for name, typ := range tc.Fields {
+		if !domain.FieldTypes[domain.FieldType(typ)] {
+			return nil, fmt.Errorf("field '%s' has unknown type '%s'", name, typ)
+		}
+		if _, ok := t.fields[name]; !ok {
+			declared = append(declared, name)
+		}
+		t.fields[name] = domain.FieldType(typ)
+	}
+	slices.Sort(declared)
+	t.order = append(t.order, declared...)
+
+	if len(tc.Allow) > 0 {
+		for _, name := range tc.Allow {
+			// A sampled collection may not show every allowed field.
+			if _, ok := t.fields[name]; !ok {
+				common.Error("graphql type '%s': allowed field '%s' was neither described nor declared", tc.Name, name)
+			}
+		}
+		t.order = slices.DeleteFunc(t.order, func(name string) bool { return !slices.Contains(tc.Allow, name) })
+		t.access = &domain.Access{Tables: map[string][]string{tc.Target: tc.Allow}}
+	}
+	t.order = slices.DeleteFunc(t.order, func(name string) bool {
+		if validName(name) {
+			return false
+		}
+		common.Error("graphql type '%s': field '%s' is not a valid GraphQL name and is left out", tc.Name, name)
+		return true
+	})
+	for name := range t.fields {
+		if !slices.Contains(t.order, name) {
+			delete(t.fields, name)
+		}
+	}
+	if len(t.order) == 0 {
+		return nil, fmt.Errorf("no fields to expose")
+	}
+
+	for _, name := range t.order {
+		if t.fields[name] != domain.FieldJSON && !reservedArgs[name] {
+			t.filters = append(t.filters, name)
+		}
+	}
+	return t, nil
+}
+
+// addRelation checks a relation and adds it to its type.
+func addRelation(rc config.GraphQLRelation, types map[string]*objectType) error {
+	from, ok := types[rc.Type]
+	if !ok {
+		return fmt.Errorf("unknown type '%s'", rc.Type)
+	}
+	target, ok := types[rc.Target]
+	if !ok {
+		return fmt.Errorf("unknown target type '%s'", rc.Target)
+	}
+	if !validName(rc.Field) {
+		return fmt.Errorf("invalid field name")
+	}
+	if _, ok := from.fields[rc.Field]; ok {
+		return fmt.Errorf("type '%s' already has a field '%s'", rc.Type, rc.Field)
+	}
+	if _, ok := from.relations[rc.Field]; ok {
+		return fmt.Errorf("type '%s' already has a relation '%s'", rc.Type, rc.Field)
+	}
+	if typ, ok := from.fields[rc.From]; !ok || typ == domain.FieldJSON {
+		return fmt.Errorf("'from' must be a scalar field exposed by '%s'", rc.Type)
+	}
+	if typ, ok := target.fields[rc.On]; !ok || typ == domain.FieldJSON {
+		return fmt.Errorf("'on' must be a scalar field exposed by '%s'", rc.Target)
+	}
+	from.relations[rc.Field] = &relation{name: rc.Type + "." + rc.Field, cfg: rc, target: target}
+	return nil
+}
+
+func (s *Schema) fields(t *objectType) gql.Fields {
+	fields := make(gql.Fields, len(t.order)+len(t.relations))
+	for _, name := range t.order {
+		typ := t.fields[name]
+		fields[name] = &gql.Field{
+			Type: outputTypes[typ],
+			Resolve: func(p gql.ResolveParams) (any, error) {
+				return value(typ, p.Source.(map[string]any)[name]), nil
+			},
+		}
+	}
+	for name, r := range t.relations {
+		var typ gql.Output = r.target.object
+		if r.cfg.Many {
+			typ = gql.NewNonNull(gql.NewList(gql.NewNonNull(r.target.object)))
+		}
+		fields[name] = &gql.Field{
+			Type: typ,
+			Resolve: func(p gql.ResolveParams) (any, error) {
+				l := p.Context.Value(loaderKey{}).(*loader)
+				return l.load(p.Context, r, selection(r.target, p.Info), p.Source.(map[string]any)[r.cfg.From]), nil
+			},
+		}
+	}
+	return fields
+}
+
+// rootField lists the rows of a type. Each scalar field can be matched for
+// equality by an argument of its name; where takes a structured query
+// condition for anything else.
+func (s *Schema) rootField(t *objectType) *gql.Field {
+	args := gql.FieldConfigArgument{
+		"where":  &gql.ArgumentConfig{Type: JSON, Description: "A structured query condition on the fields of the type."},
+		"sort":   &gql.ArgumentConfig{Type: gql.NewList(gql.NewNonNull(sortInput))},
+		"limit":  &gql.ArgumentConfig{Type: gql.Int, Description: fmt.Sprintf("At most %d.", t.limit)},
+		"offset": &gql.ArgumentConfig{Type: gql.Int},
+	}
+	for _, name := range t.filters {
+		args[name] = &gql.ArgumentConfig{Type: outputTypes[t.fields[name]]}
+	}
+	return &gql.Field{
+		Type: gql.NewNonNull(gql.NewList(gql.NewNonNull(t.object))),
+		Args: args,
+		Resolve: func(p gql.ResolveParams) (any, error) {
+			q, err := t.query(p.Args, selection(t, p.Info))
+			if err != nil {
+				return nil, err
+			}
+			return s.rows(p.Context, t, q)
+		},
+	}
+}
+
+// query compiles the arguments of a root field.
+func (t *objectType) query(args map[string]any, sel []string) (*domain.Query, error) {
+	q := &domain.Query{Target: t.cfg.Target, Select: sel, Limit: t.limit}
+
+	var conds []domain.Condition
+	for _, name := range t.filters {
+		if v, ok := args[name]; ok && v != nil {
+			conds = append(conds, domain.Condition{Field: name, Op: domain.OpEq, Value: v})
+		}
+	}
+	if where, ok := args["where"]; ok && where != nil {
+		data, err := json.Marshal(where)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid 'where': %v", domain.ErrInvalidRequest, err)
+		}
+		var cond domain.Condition
+		if err := json.Unmarshal(data, &cond); err != nil {
+			return nil, fmt.Errorf("%w: invalid 'where': %v", domain.ErrInvalidRequest, err)
+		}
+		if err := t.checkFields(cond); err != nil {
+			return nil, err
+		}
+		conds = append(conds, cond)
+	}
+	switch len(conds) {
+	case 0:
+	case 1:
+		q.Where = &conds[0]
+	default:
+		q.Where = &domain.Condition{And: conds}
+	}
+
+	if sortBy, ok := args["sort"].([]any); ok {
+		for _, entry := range sortBy {
+			entry, _ := entry.(map[string]any)
+			field, _ := entry["field"].(string)
+			desc, _ := entry["desc"].(bool)
+			if _, ok := t.fields[field]; !ok {
+				return nil, fmt.Errorf("%w: cannot sort by unknown field '%s'", domain.ErrInvalidRequest, field)
+			}
+			q.Sort = append(q.Sort, domain.SortField{Field: field, Desc: desc})
+		}
+	}
+	if limit, ok := args["limit"].(int); ok {
+		if limit < 1 || limit > t.limit {
+			return nil, fmt.Errorf("%w: 'limit' must be between 1 and %d", domain.ErrInvalidRequest, t.limit)
+		}
+		q.Limit = limit
+	}
+	if offset, ok := args["offset"].(int); ok {
+		q.Offset = offset
+	}
+	return q, nil
+}
+
+// checkFields rejects conditions on fields the type does not expose.
+func (t *objectType) checkFields(c domain.Condition) error {
+	for _, child := range slices.Concat(c.And, c.Or) {
+		if err := t.checkFields(child); err != nil {
+			return err
+		}
+	}
+	if c.Field != "" {
+		if _, ok := t.fields[c.Field]; !ok {
+			return fmt.Errorf("%w: unknown field '%s' in 'where'", domain.ErrInvalidRequest, c.Field)
+		}
+	}
+	return nil
+}
+
+// rows runs a query of a type, charging its rows to the request's budget.
+// The query reads at most one row more than the budget has left, enough to
+// tell that it ran out.
+func (s *Schema) rows(ctx context.Context, t *objectType, q *domain.Query) ([]map[string]any, error) {
+	l, _ := ctx.Value(loaderKey{}).(*loader)
+	if l != nil {
+		q.Limit = min(q.Limit, l.budget+1)
+	}
+	req := domain.QueryRequest{Source: t.cfg.Source, Params: t.cfg.Params, Query: q, Access: t.access}
+	result, err := s.svc.HandleQuery(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	rows, ok := result.([]map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("source '%s' returned %T instead of rows", t.cfg.Source, result)
+	}
+	if l != nil {
+		if len(rows) > l.budget {
+			l.budget = 0
+			return nil, fmt.Errorf("%w: the query reads more than %d rows; narrow it", domain.ErrInvalidRequest, s.maxRows)
+		}
+		l.budget -= len(rows)
+	}
+	return rows, nil
+}
+
+// selection lists the fields to read for the fields being resolved: the
+// scalar fields their selection sets ask for and the From fields of the
+// relations they ask for. A selection of only __typename reads the first
+// field.
+func selection(t *objectType, info gql.ResolveInfo) []string {
+	var sel []string
+	var walk func(set *ast.SelectionSet)
+	walk = func(set *ast.SelectionSet) {
+		if set == nil {
+			return
+		}
+		for _, s := range set.Selections {
+			switch s := s.(type) {
+			case *ast.Field:
+				name := s.Name.Value
+				if r, ok := t.relations[name]; ok {
+					name = r.cfg.From
+				}
+				if _, ok := t.fields[name]; ok && !slices.Contains(sel, name) {
+					sel = append(sel, name)
+				}
+			case *ast.InlineFragment:
+				walk(s.SelectionSet)
+			case *ast.FragmentSpread:
+				if def, ok := info.Fragments[s.Name.Value].(*ast.FragmentDefinition); ok {
+					walk(def.SelectionSet)
+				}
+			}
+		}
+	}
+	for _, f := range info.FieldASTs {
+		walk(f.SelectionSet)
+	}
+	if len(sel) == 0 {
+		sel = t.order[:1]
+	}
+	return sel
+}
+
+func validName(name string) bool {
+	return namePattern.MatchString(name) && !strings.HasPrefix(name, "__")
+}
@@ -0,0 +1,263 @@
+package graphql
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	gql "github.com/graphql-go/graphql"
+	"github.com/thegodeveloper/data-gateway/internal/app"
+	"github.com/thegodeveloper/data-gateway/internal/config"
+	"github.com/thegodeveloper/data-gateway/internal/domain"
+)
+
+type fakeTable struct {
+	fields []domain.Field
+	rows   []map[string]any
+}
+
+// fakeSource answers structured queries from memory and records them.
+type fakeSource struct {
+	tables  map[string]fakeTable
+	queries []domain.Query
+}
+
+func (f *fakeSource) Describe(_ context.Context, req domain.DescribeRequest) (*domain.TableSchema, error) {
+	t, ok := f.tables[req.Target]
+	if !ok {
+		return nil, fmt.Errorf("%w: no table '%s'", domain.ErrInvalidRequest, req.Target)
+	}
+	return &domain.TableSchema{Fields: t.fields}, nil
+}
+
+func (f *fakeSource) Query(_ context.Context, req domain.QueryRequest) (any, error) {
+	q := req.Query
+	f.queries = append(f.queries, *q)
+	out := []map[string]any{}
+	for _, row := range f.tables[q.Target].rows {
+		if q.Where != nil && !matches(*q.Where, row) {
+			continue
+		}
+		if len(out) == q.Limit {
+			break
+		}
+		projected := make(map[string]any, len(q.Select))
+		for _, field := range q.Select {
+			projected[field] = row[field]
+		}
+		out = append(out, projected)
+	}
+	return out, nil
+}
+
+func matches(c domain.Condition, row map[string]any) bool {
+	for _, sub := range c.And {
+		if !matches(sub, row) {
+			return false
+		}
+	}
+	switch c.Op {
+	case domain.OpEq:
+		return fmt.Sprint(row[c.Field]) == fmt.Sprint(c.Value)
+	case domain.OpIn:
+		return slices.ContainsFunc(c.Value.([]any), func(v any) bool { return fmt.Sprint(v) == fmt.Sprint(row[c.Field]) })
+	}
+	return true
+}
+
+func shop() *fakeSource {
+	return &fakeSource{tables: map[string]fakeTable{
+		"customers": {
+			fields: []domain.Field{
+				{Name: "id", Type: domain.FieldInt},
+				{Name: "name", Type: domain.FieldString},
+				{Name: "password_hash", Type: domain.FieldString},
+				{Name: "tags", Type: domain.FieldJSON},
+				{Name: "first-name", Type: domain.FieldString},
+			},
+			rows: []map[string]any{
+				{"id": 1, "name": "Ann", "password_hash": "x"},
+				{"id": 2, "name": "Bob", "password_hash": "y"},
+				{"id": 3, "name": "Cy", "password_hash": "z"},
+			},
+		},
+		"orders": {
+			fields: []domain.Field{
+				{Name: "id", Type: domain.FieldInt},
+				{Name: "customer_id", Type: domain.FieldInt},
+				{Name: "total", Type: domain.FieldFloat},
+			},
+			rows: []map[string]any{
+				{"id": 10, "customer_id": 1, "total": 5.0},
+				{"id": 11, "customer_id": 1, "total": 7.5},
+				{"id": 12, "customer_id": 2, "total": 1.0},
+				{"id": 13, "customer_id": 3, "total": 2.0},
+				{"id": 14, "customer_id": 3, "total": 3.0},
+			},
+		},
+	}}
+}
+
+func shopConfig() config.GraphQL {
+	return config.GraphQL{
+		Types: []config.GraphQLType{
+			{Name: "Customer", Source: "shop", Target: "customers", Allow: []string{"id", "name", "tags", "first-name", "since"}, Fields: map[string]string{"since": "time"}},
+			{Name: "Order", Source: "shop", Target: "orders", Fields: map[string]string{"total": "decimal"}},
+		},
+		Relations: []config.GraphQLRelation{
+			{Type: "Customer", Field: "orders", Target: "Order", From: "id", On: "customer_id", Many: true},
+			{Type: "Order", Field: "customer", Target: "Customer", From: "customer_id", On: "id"},
+		},
+	}
+}
+
+func newTestSchema(t *testing.T, src *fakeSource, cfg config.GraphQL) *Schema {
+	t.Helper()
+	svc := app.NewGatewayService(map[string]domain.DataSource{"shop": src}, nil)
+	s, err := NewSchema(context.Background(), svc, cfg)
+	if err != nil {
+		t.Fatalf("NewSchema: %v", err)
+	}
+	return s
+}
+
+func TestNewSchemaFields(t *testing.T) {
+	s := newTestSchema(t, shop(), shopConfig())
+
+	customer := s.schema.Type("Customer").(*gql.Object).Fields()
+	var names []string
+	for name := range customer {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	if want := []string{"id", "name", "orders", "since", "tags"}; !slices.Equal(names, want) {
+		t.Errorf("Customer fields = %v, want %v", names, want)
+	}
+	if typ := customer["since"].Type; typ != DateTime {
+		t.Errorf("declared field 'since' has type %v, want DateTime", typ)
+	}
+	if typ := customer["orders"].Type.String(); typ != "[Order!]!" {
+		t.Errorf("relation 'orders' has type %s, want [Order!]!", typ)
+	}
+	if typ := s.schema.Type("Order").(*gql.Object).Fields()["total"].Type; typ != Decimal {
+		t.Errorf("overridden field 'total' has type %v, want Decimal", typ)
+	}
+
+	root := s.schema.QueryType().Fields()["customer"]
+	args := make(map[string]bool)
+	for _, arg := range root.Args {
+		args[arg.Name()] = true
+	}
+	for _, name := range []string{"id", "name", "since", "where", "sort", "limit", "offset"} {
+		if !args[name] {
+			t.Errorf("root field 'customer' has no argument '%s'", name)
+		}
+	}
+	if args["tags"] {
+		t.Error("JSON field 'tags' has an equality argument")
+	}
+}
+
+func TestNewSchemaRejects(t *testing.T) {
+	cases := map[string]func(*config.GraphQL){
+		"reserved type name": func(c *config.GraphQL) { c.Types[0].Name = "Query" },
+		"duplicate type":     func(c *config.GraphQL) { c.Types[1].Name = "Customer" },
+		"unknown field type": func(c *config.GraphQL) { c.Types[1].Fields = map[string]string{"total": "money"} },
+		"limit too large":    func(c *config.GraphQL) { c.Types[0].Limit = domain.MaxStageLimit + 1 },
+		"unknown target":     func(c *config.GraphQL) { c.Relations[0].Target = "Invoice" },
+		"relation on JSON":   func(c *config.GraphQL) { c.Relations[0].From = "tags" },
+		"hidden from field":  func(c *config.GraphQL) { c.Relations[1].On = "password_hash" },
+		"field clash":        func(c *config.GraphQL) { c.Relations[0].Field = "name" },
+		"negative max_rows":  func(c *config.GraphQL) { c.MaxRows = -1 },
+	}
+	for name, edit := range cases {
+		cfg := shopConfig()
+		edit(&cfg)
+		svc := app.NewGatewayService(map[string]domain.DataSource{"shop": shop()}, nil)
+		if _, err := NewSchema(context.Background(), svc, cfg); err == nil {
+			t.Errorf("%s: NewSchema succeeded", name)
+		}
+	}
+}
+
+func TestRelationsAreBatched(t *testing.T) {
+	src := shop()
+	s := newTestSchema(t, src, shopConfig())
+
+	res := s.Execute(context.Background(), Request{Query: `{
+		customer(sort: [{field: "id"}]) { name orders { id customer { name } } }
+	}`})
+	if len(res.Errors) > 0 {
+		t.Fatalf("errors: %v", res.Errors)
+	}
+
+	// One root query, one batch of orders and one batch of customers.
+	if len(src.queries) != 3 {
+		t.Fatalf("ran %d queries, want 3: %+v", len(src.queries), src.queries)
+	}
+	orders := src.queries[1]
+	if orders.Target != "orders" || orders.Where.Op != domain.OpIn || len(orders.Where.Value.([]any)) != 3 {
+		t.Errorf("orders batch = %+v, want one 'in' query for 3 keys", orders)
+	}
+	if owners := src.queries[2].Where.Value.([]any); len(owners) != 3 {
+		t.Errorf("customer batch asked for keys %v, want the 3 distinct customer_ids", owners)
+	}
+
+	customers := res.Data.(map[string]any)["customer"].([]any)
+	ann := customers[0].(map[string]any)
+	if ann["name"] != "Ann" || len(ann["orders"].([]any)) != 2 {
+		t.Fatalf("first customer = %v, want Ann with 2 orders", ann)
+	}
+	owner := ann["orders"].([]any)[1].(map[string]any)["customer"].(map[string]any)
+	if owner["name"] != "Ann" {
+		t.Errorf("order's customer = %v, want Ann", owner)
+	}
+}
+
+func TestMaxDepth(t *testing.T) {
+	src := shop()
+	cfg := shopConfig()
+	cfg.MaxDepth = 3
+	s := newTestSchema(t, src, cfg)
+
+	res := s.Execute(context.Background(), Request{Query: `
+		{ customer { ...deep } }
+		fragment deep on Customer { orders { customer { name } } }
+	`})
+	if len(res.Errors) == 0 || !strings.Contains(res.Errors[0].Message, "4 levels deep") {
+		t.Fatalf("errors = %v, want a depth error", res.Errors)
+	}
+	if len(src.queries) != 0 {
+		t.Errorf("ran %d queries for a refused request", len(src.queries))
+	}
+
+	for _, query := range []string{
+		`{ customer { orders { id } } }`,
+		`{ __schema { types { fields { type { ofType { ofType { ofType { name } } } } } } } }`,
+	} {
+		if res := s.Execute(context.Background(), Request{Query: query}); len(res.Errors) > 0 {
+			t.Errorf("%s: %v", query, res.Errors)
+		}
+	}
+}
+
+func TestMaxRows(t *testing.T) {
+	cfg := shopConfig()
+	cfg.MaxRows = 6
+	s := newTestSchema(t, shop(), cfg)
+
+	// Three customers and five orders are eight rows.
+	res := s.Execute(context.Background(), Request{Query: `{ customer { orders { id } } }`})
+	if len(res.Errors) == 0 || !strings.Contains(res.Errors[0].Message, "more than 6 rows") {
+		t.Fatalf("errors = %v, want a row budget error", res.Errors)
+	}
+
+	// The budget is per request.
+	for range 2 {
+		if res := s.Execute(context.Background(), Request{Query: `{ order { id } }`}); len(res.Errors) > 0 {
+			t.Fatalf("errors: %v", res.Errors)
+		}
+	}
+}
@@ -0,0 +1,236 @@
+// Package route
+// internal/route/table.go
+package route
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/thegodeveloper/data-gateway/internal/config"
+	"github.com/thegodeveloper/data-gateway/internal/domain"
+)
+
+type segmentKind int
+
+// Kinds are ordered by specificity: a literal segment beats a parameter,
+// which beats a catch-all.
+const (
+	literal segmentKind = iota
+	param
+	catchAll
+)
+
+type segment struct {
+	kind  segmentKind
+	value string // literal text or parameter name
+}
+
+// Route is a compiled route table entry.
+type Route struct {
+	Method   string
+	Pattern  string
+	Source   string
+	Params   map[string]any
+	Query    map[string]any
+	Mutation map[string]any
+	Access   *domain.Access
+	Tables   map[string]string
+	segments []segment
+}
+
+// Table matches requests against the configured routes, most specific first.
+type Table struct {
+	routes []*Route
+}
+
+// NewTable compiles and orders the configured routes. Routes are ranked
+// segment by segment (literal before {param} before {rest...}), longer
+// patterns before their prefixes, and config order breaks remaining ties, so
+// matching never depends on map iteration order.
+func NewTable(cfgs []config.Route) (*Table, error) {
+	routes := make([]*Route, 0, len(cfgs))
+	seen := make(map[string]bool, len(cfgs))
+	for i, cfg := range cfgs {
+		r, err := compile(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("route %d (%s %s): %w", i, cfg.Method, cfg.Path, err)
+		}
+		key := r.Method + " " + r.shape()
+		if seen[key] {
+			return nil, fmt.Errorf("route %d (%s %s): duplicates an earlier route", i, cfg.Method, cfg.Path)
+		}
+		seen[key] = true
+		routes = append(routes, r)
+	}
+
+	sort.SliceStable(routes, func(i, j int) bool {
+		return moreSpecific(routes[i].segments, routes[j].segments)
+	})
+	return &Table{routes: routes}, nil
+}
+
+// Routes returns the compiled routes in match order.
+func (t *Table) Routes() []*Route {
+	return t.routes
+}
+
+// Match returns the route for method and path together with its path
+// parameters. A path that matches only under other methods yields
+// domain.ErrMethodNotAllowed.
+func (t *Table) Match(method, path string) (*Route, map[string]string, error) {
+	parts := split(path)
+	pathMatched := false
+	for _, r := range t.routes {
+		vars, ok := r.match(parts)
+		if !ok {
+			continue
+		}
+		if r.Method != method {
+			pathMatched = true
+			continue
+		}
+		return r, vars, nil
+	}
+	if pathMatched {
+		return nil, nil, fmt.Errorf("%w: %s %s", domain.ErrMethodNotAllowed, method, path)
+	}
+	return nil, nil, fmt.Errorf("%w '%s'", domain.ErrRouteNotFound, path)
+}
+
+func compile(cfg config.Route) (*Route, error) {
+	method := strings.ToUpper(cfg.Method)
+	if method == "" {
+		method = http.MethodGet
+	}
+	if !strings.HasPrefix(cfg.Path, "/") {
+		return nil, fmt.Errorf("path must start with '/'")
+	}
+	if cfg.Source == "" {
+		return nil, fmt.Errorf("missing source")
+	}
+
+	parts := split(cfg.Path)
+	segments := make([]segment, 0, len(parts))
+	names := make(map[string]bool)
+	for i, part := range parts {
+		if !strings.HasPrefix(part, "{") || !strings.HasSuffix(part, "}") {
+			if strings.ContainsAny(part, "{}") {
+				return nil, fmt.Errorf("malformed segment '%s'", part)
+			}
+			segments = append(segments, segment{kind: literal, value: part})
+			continue
+		}
+
+		name, kind := part[1:len(part)-1], param
+		if strings.HasSuffix(name, "...") {
+			if i != len(parts)-1 {
+				return nil, fmt.Errorf("catch-all '%s' must be the last segment", part)
+			}
+			name, kind = strings.TrimSuffix(name, "..."), catchAll
+		}
+		if name == "" || names[name] {
+			return nil, fmt.Errorf("missing or repeated parameter name in '%s'", part)
+		}
+		names[name] = true
+		segments = append(segments, segment{kind: kind, value: name})
+	}
+
+	params := cfg.Params
+	if params == nil {
+		params = map[string]any{}
+	}
+	if err := checkTemplate(params); err != nil {
+		return nil, err
+	}
+	if err := checkTemplate(cfg.Query); err != nil {
+		return nil, err
+	}
+	if err := checkTemplate(cfg.Mutation); err != nil {
+		return nil, err
+	}
+	if cfg.Query != nil && cfg.Mutation != nil {
+		return nil, fmt.Errorf("a route cannot have both a query and a mutation")
+	}
+
+	var access *domain.Access
+	if cfg.Allow != nil {
+		access = &domain.Access{Tables: cfg.Allow}
+	}
+
+	return &Route{
+		Method:   method,
+		Pattern:  cfg.Path,
+		Source:   cfg.Source,
+		Params:   params,
+		Query:    cfg.Query,
+		Mutation: cfg.Mutation,
+		Access:   access,
+		Tables:   cfg.Tables,
+		segments: segments,
+	}, nil
+}
+
+func (r *Route) match(parts []string) (map[string]string, bool) {
+	vars := make(map[string]string)
+	for i, seg := range r.segments {
+		if seg.kind == catchAll {
+			vars[seg.value] = strings.Join(parts[i:], "/")
+			return vars, true
+		}
+		if i >= len(parts) {
+			return nil, false
+		}
+		switch seg.kind {
+		case literal:
+			if parts[i] != seg.value {
+				return nil, false
+			}
+		case param:
+			if parts[i] == "" {
+				return nil, false
+			}
+			vars[seg.value] = parts[i]
+		}
+	}
+	if len(parts) != len(r.segments) {
+		return nil, false
+	}
+	return vars, true
+}
+
+// shape is the pattern with parameter names erased, so /a/{x} and /a/{y}
+// are recognised as the same route.
+func (r *Route) shape() string {
+	var b strings.Builder
+	for _, seg := range r.segments {
+		b.WriteByte('/')
+		switch seg.kind {
+		case literal:
+			b.WriteString(seg.value)
+		case param:
+			b.WriteString("{}")
+		case catchAll:
+			b.WriteString("{...}")
+		}
+	}
+	return b.String()
+}
+
+func moreSpecific(a, b []segment) bool {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i].kind != b[i].kind {
+			return a[i].kind < b[i].kind
+		}
+	}
+	return len(a) > len(b)
+}
+
+func split(path string) []string {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, "/")
+}
@@ -0,0 +1,212 @@
+// Package route
+// internal/route/template.go
+package route
+
+import (
+	"fmt"
+	"math"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/thegodeveloper/data-gateway/internal/domain"
+)
+
+// placeholder matches a template string that is exactly one variable
+// reference: {scope.name[:type][?]}. Placeholders are only recognised as whole
+// values, never spliced into larger strings, and only ever resolve to
+// scalars, so a request value can become a bind argument or filter value but
+// never part of SQL or an expression such as a Mongo operator.
+var placeholder = regexp.MustCompile(`^\{(path|query|body)\.([A-Za-z0-9_\-.]+)(?::(int|float|bool|string))?(\?)?\}$`)
+
+// Vars are the request values available to a template.
+type Vars struct {
+	Path  map[string]string
+	Query url.Values
+	Body  map[string]any
+}
+
+// missing is returned by resolve for an optional variable that is absent;
+// the enclosing map key or list element is dropped.
+type missing struct{}
+
+// Render fills tmpl with request values. It returns a new tree and leaves the
+// template untouched.
+func Render(tmpl map[string]any, vars Vars) (map[string]any, error) {
+	out, err := render(tmpl, vars)
+	if err != nil {
+		return nil, err
+	}
+	if m, ok := out.(map[string]any); ok {
+		return m, nil
+	}
+	return map[string]any{}, nil
+}
+
+func render(node any, vars Vars) (any, error) {
+	switch v := node.(type) {
+	case string:
+		m := placeholder.FindStringSubmatch(v)
+		if m == nil {
+			return v, nil
+		}
+		return resolve(m[1], m[2], m[3], m[4] == "?", vars)
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for key, item := range v {
+			r, err := render(item, vars)
+			if err != nil {
+				return nil, err
+			}
+			if _, absent := r.(missing); !absent {
+				out[key] = r
+			}
+		}
+		return out, nil
+	case []any:
+		out := make([]any, 0, len(v))
+		for _, item := range v {
+			r, err := render(item, vars)
+			if err != nil {
+				return nil, err
+			}
+			if _, absent := r.(missing); !absent {
+				out = append(out, r)
+			}
+		}
+		return out, nil
+	default:
+		return v, nil
+	}
+}
+
+func resolve(scope, name, typ string, optional bool, vars Vars) (any, error) {
+	var (
+		value any
+		found bool
+	)
+	switch scope {
+	case "path":
+		value, found = vars.Path[name]
+	case "query":
+		if values, ok := vars.Query[name]; ok && len(values) > 0 {
+			value, found = values[0], true
+		}
+	case "body":
+		value, found = lookup(vars.Body, name)
+	}
+
+	if !found || value == nil {
+		if optional {
+			return missing{}, nil
+		}
+		return nil, fmt.Errorf("%w: missing %s parameter '%s'", domain.ErrInvalidRequest, scope, name)
+	}
+	if !scalar(value) {
+		return nil, fmt.Errorf("%w: %s parameter '%s' must be a string, number or boolean", domain.ErrInvalidRequest, scope, name)
+	}
+	if typ == "" {
+		return value, nil
+	}
+
+	converted, err := convert(value, typ)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s parameter '%s': %v", domain.ErrInvalidRequest, scope, name, err)
+	}
+	return converted, nil
+}
+
+// scalar reports whether v is a single value rather than an object or a
+// list from the request body.
+func scalar(v any) bool {
+	switch v.(type) {
+	case string, float64, bool, int, int64:
+		return true
+	}
+	return false
+}
+
+// lookup follows a dotted path such as "customer.id" through nested objects.
+func lookup(body map[string]any, name string) (any, bool) {
+	var current any = body
+	for _, part := range strings.Split(name, ".") {
+		m, ok := current.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if current, ok = m[part]; !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+func convert(value any, typ string) (any, error) {
+	switch typ {
+	case "string":
+		if s, ok := value.(string); ok {
+			return s, nil
+		}
+		return fmt.Sprint(value), nil
+	case "int":
+		switch v := value.(type) {
+		case string:
+			return strconv.ParseInt(v, 10, 64)
+		case float64:
+			if v != math.Trunc(v) {
+				return nil, fmt.Errorf("%v is not an integer", v)
+			}
+			return int64(v), nil
+		case int, int64:
+			return v, nil
+		}
+	case "float":
+		switch v := value.(type) {
+		case string:
+			return strconv.ParseFloat(v, 64)
+		case float64, int, int64:
+			return v, nil
+		}
+	case "bool":
+		switch v := value.(type) {
+		case string:
+			return strconv.ParseBool(v)
+		case bool:
+			return v, nil
+		}
+	}
+	return nil, fmt.Errorf("cannot convert %T to %s", value, typ)
+}
+
+// checkTemplate rejects strings that look like placeholders but do not parse,
+// so a typo such as "{pth.id}" fails at startup instead of being sent to the
+// backend literally.
+func checkTemplate(node any) error {
+	switch v := node.(type) {
+	case string:
+		if looksLikePlaceholder(v) && !placeholder.MatchString(v) {
+			return fmt.Errorf("invalid placeholder '%s'", v)
+		}
+	case map[string]any:
+		for _, item := range v {
+			if err := checkTemplate(item); err != nil {
+				return err
+			}
+		}
+	case []any:
+		for _, item := range v {
+			if err := checkTemplate(item); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func looksLikePlaceholder(s string) bool {
+	if len(s) < 3 || s[0] != '{' || s[len(s)-1] != '}' {
+		return false
+	}
+	return !strings.ContainsAny(s[1:len(s)-1], " {}")
+}
@@ -0,0 +1,62 @@
+package route
+
+import (
+	"errors"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/thegodeveloper/data-gateway/internal/domain"
+)
+
+func TestRenderScalars(t *testing.T) {
+	vars := Vars{
+		Path:  map[string]string{"id": "42"},
+		Query: url.Values{"status": {"open"}},
+		Body:  map[string]any{"id": 7.0, "active": true, "customer": map[string]any{"id": "c-1"}},
+	}
+	tmpl := map[string]any{
+		"id":       "{path.id:int}",
+		"status":   "{query.status}",
+		"body_id":  "{body.id}",
+		"active":   "{body.active}",
+		"customer": "{body.customer.id}",
+		"note":     "{body.note?}",
+	}
+	want := map[string]any{
+		"id":       int64(42),
+		"status":   "open",
+		"body_id":  7.0,
+		"active":   true,
+		"customer": "c-1",
+	}
+
+	got, err := Render(tmpl, vars)
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Render = %v, want %v", got, want)
+	}
+}
+
+func TestRenderRejectsComposites(t *testing.T) {
+	body := map[string]any{
+		"operator": map[string]any{"$ne": nil},
+		"list":     []any{"a", "b"},
+	}
+	for _, tmpl := range []string{
+		"{body.operator}",
+		"{body.operator:string}",
+		"{body.list}",
+		"{body.list:string}",
+		"{body.operator?}",
+	} {
+		t.Run(tmpl, func(t *testing.T) {
+			_, err := Render(map[string]any{"customer_id": tmpl}, Vars{Body: body})
+			if !errors.Is(err, domain.ErrInvalidRequest) {
+				t.Fatalf("Render error = %v, want ErrInvalidRequest", err)
+			}
+		})
+	}
+}
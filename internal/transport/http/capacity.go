@@ -0,0 +1,105 @@
+// Package http
+// internal/transport/http/capacity.go
+package http
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/thegodeveloper/data-gateway/internal/domain"
+)
+
+// capacityHeader reports the capacity units a request consumed, e.g.
+// "read=12.5, write=0". It is only set when a data source recorded any.
+const capacityHeader = "X-Consumed-Capacity"
+
+// consumedCapacity lets data sources record the capacity a request consumes
+// and reports it in capacityHeader; handlers also put it in the body with
+// reportCapacity. Streamed responses, whose headers go out
+// before the rows are read, send it as a trailer instead (see writeStream).
+func consumedCapacity(c *gin.Context) {
+	ctx, used := domain.WithCapacity(c.Request.Context())
+	c.Request = c.Request.WithContext(ctx)
+	c.Writer = &capacityWriter{ResponseWriter: c.Writer, used: used}
+	c.Next()
+}
+
+// capacityWriter sets capacityHeader just before the headers are written.
+type capacityWriter struct {
+	gin.ResponseWriter
+	used *domain.Capacity
+}
+
+func (w *capacityWriter) WriteHeaderNow() {
+	w.setHeader()
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *capacityWriter) Write(data []byte) (int, error) {
+	w.setHeader()
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *capacityWriter) WriteString(s string) (int, error) {
+	w.setHeader()
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *capacityWriter) setHeader() {
+	if w.Written() || w.Header().Get("Trailer") != "" {
+		return
+	}
+	if value, ok := formatCapacity(w.used); ok {
+		w.Header().Set(capacityHeader, value)
+	}
+}
+
+// bodyCapacity returns the capacity the request consumed, or nil if no data
+// source recorded any.
+func bodyCapacity(c *gin.Context) *domain.CapacityUnits {
+	used := domain.CapacityFrom(c.Request.Context())
+	if used == nil {
+		return nil
+	}
+	read, write, ok := used.Units()
+	if !ok {
+		return nil
+	}
+	return &domain.CapacityUnits{Read: read, Write: write}
+}
+
+// reportCapacity puts the consumed capacity into results whose bodies have
+// room for it. Unpaginated query results are bare arrays and only get
+// capacityHeader.
+func reportCapacity(c *gin.Context, res any) {
+	units := bodyCapacity(c)
+	if units == nil {
+		return
+	}
+	switch r := res.(type) {
+	case *domain.Page:
+		r.ConsumedCapacity = units
+	case *domain.MutationResult:
+		r.ConsumedCapacity = units
+	case *domain.TransactionResult:
+		r.ConsumedCapacity = units
+	case *domain.FederatedResult:
+		r.ConsumedCapacity = units
+	case *domain.FanOutResult:
+		r.ConsumedCapacity = units
+	}
+}
+
+func formatCapacity(used *domain.Capacity) (string, bool) {
+	if used == nil {
+		return "", false
+	}
+	read, write, ok := used.Units()
+	if !ok {
+		return "", false
+	}
+	return fmt.Sprintf("read=%s, write=%s",
+		strconv.FormatFloat(read, 'f', -1, 64),
+		strconv.FormatFloat(write, 'f', -1, 64)), true
+}
@@ -0,0 +1,49 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/thegodeveloper/data-gateway/internal/domain"
+)
+
+func TestConsumedCapacityInHeaderAndBody(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(consumedCapacity)
+	r.GET("/page", func(c *gin.Context) {
+		domain.CapacityFrom(c.Request.Context()).Add(2.5, 0)
+		res := &domain.Page{Items: []map[string]any{}}
+		reportCapacity(c, res)
+		c.JSON(http.StatusOK, res)
+	})
+	r.GET("/none", func(c *gin.Context) {
+		res := &domain.Page{Items: []map[string]any{}}
+		reportCapacity(c, res)
+		c.JSON(http.StatusOK, res)
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/page", nil))
+	if got := w.Header().Get(capacityHeader); got != "read=2.5, write=0" {
+		t.Errorf("%s = %q", capacityHeader, got)
+	}
+	var body struct {
+		ConsumedCapacity *domain.CapacityUnits `json:"consumed_capacity"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.ConsumedCapacity == nil || *body.ConsumedCapacity != (domain.CapacityUnits{Read: 2.5}) {
+		t.Errorf("body consumed_capacity = %+v, want read 2.5", body.ConsumedCapacity)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/none", nil))
+	if w.Header().Get(capacityHeader) != "" || w.Body.String() != `{"items":[]}` {
+		t.Errorf("unmetered response: header %q, body %s", w.Header().Get(capacityHeader), w.Body.String())
+	}
+}
@@ -0,0 +1,40 @@
+// Package http
+// internal/transport/http/graphql.go
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/thegodeveloper/data-gateway/internal/graphql"
+)
+
+// graphQL runs a GraphQL request. POST takes a JSON body with query,
+// operationName and variables; GET takes them as query parameters, with
+// variables as JSON. Field errors, including those of the data sources, are
+// answered with 200 in the result's errors, as GraphQL clients expect.
+func (h *handler) graphQL(c *gin.Context) {
+	var req graphql.Request
+	if c.Request.Method == http.MethodPost {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	} else {
+		req.Query = c.Query("query")
+		req.OperationName = c.Query("operationName")
+		if raw := c.Query("variables"); raw != "" {
+			if err := json.Unmarshal([]byte(raw), &req.Variables); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid 'variables': " + err.Error()})
+				return
+			}
+		}
+	}
+	if req.Query == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing 'query'"})
+		return
+	}
+
+	c.JSON(http.StatusOK, h.schema.Execute(c.Request.Context(), req))
+}
@@ -3,34 +3,256 @@
 package http
 
 import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+
 	"github.com/thegodeveloper/data-gateway/internal/app"
 	"github.com/thegodeveloper/data-gateway/internal/domain"
-	"net/http"
+	"github.com/thegodeveloper/data-gateway/internal/graphql"
 
 	"github.com/gin-gonic/gin"
 	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 )
 
-func StartServer(svc *app.GatewayService, port string) {
+// StartServer serves the gateway APIs:
+//
+//	POST /query        explicit {"source": ..., "params": ...} requests
+//	POST /mutate       explicit {"source": ..., "mutation": ...} writes
+//	POST /transaction  atomic {"source": ..., "steps": [mutation, ...]} writes
+//	POST /federate     {"stages": [...]} reads joined across sources
+//	POST /fanout       {"queries": [...], "merge": ...} concurrent reads, merged
+//	GET|POST /watch    change subscriptions over SSE or WebSocket (see watch)
+//	GET|POST /graphql  GraphQL over the configured types, when schema is set
+//	any other          business endpoints resolved through the route table
+//
+// Reads are streamed instead of buffered when the client asks for NDJSON or
+// passes ?stream=json (see writeStream).
+func StartServer(svc *app.GatewayService, schema *graphql.Schema, port string) error {
 	r := gin.Default()
 	r.Use(otelgin.Middleware("data-gateway"))
+	r.Use(callerIdentity)
+	r.Use(consumedCapacity)
+
+	h := &handler{svc: svc, schema: schema}
+	r.POST("/query", h.query)
+	r.POST("/mutate", h.mutate)
+	r.POST("/transaction", h.transaction)
+	r.POST("/federate", h.federate)
+	r.POST("/fanout", h.fanOut)
+	r.GET("/watch", h.watch)
+	r.POST("/watch", h.watch)
+	if schema != nil {
+		r.GET("/graphql", h.graphQL)
+		r.POST("/graphql", h.graphQL)
+	}
+	// Routes are configuration, not code, so anything gin does not know falls
+	// through to the gateway's own route table.
+	r.NoRoute(h.route)
+
+	return r.Run(":" + port)
+}
+
+type handler struct {
+	svc    *app.GatewayService
+	schema *graphql.Schema
+}
+
+func (h *handler) query(c *gin.Context) {
+	var req domain.QueryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if format := wantsStream(c); format != noStream {
+		rows, err := h.svc.HandleStream(c.Request.Context(), req)
+		if err != nil {
+			writeError(c, err)
+			return
+		}
+		writeStream(c, rows, format)
+		return
+	}
+
+	res, err := h.svc.HandleQuery(c.Request.Context(), req)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	reportCapacity(c, res)
+	c.JSON(http.StatusOK, res)
+}
+
+func (h *handler) mutate(c *gin.Context) {
+	var req domain.MutationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	res, err := h.svc.HandleMutation(c.Request.Context(), req)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	reportCapacity(c, res)
+	c.JSON(mutationStatus(res), res)
+}
+
+func (h *handler) transaction(c *gin.Context) {
+	var req domain.TransactionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	res, err := h.svc.HandleTransaction(c.Request.Context(), req)
+	if err != nil {
+		body := gin.H{"error": err.Error()}
+		var stepErr *domain.StepError
+		if errors.As(err, &stepErr) {
+			body["step"] = stepErr.Step
+		}
+		setRetryAfter(c, err)
+		c.JSON(statusFor(err), body)
+		return
+	}
+
+	reportCapacity(c, res)
+	c.JSON(http.StatusOK, res)
+}
+
+func (h *handler) federate(c *gin.Context) {
+	var req domain.FederatedRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	res, err := h.svc.HandleFederated(c.Request.Context(), req)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	reportCapacity(c, res)
+	c.JSON(http.StatusOK, res)
+}
+
+func (h *handler) fanOut(c *gin.Context) {
+	var req domain.FanOutRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	res, err := h.svc.HandleFanOut(c.Request.Context(), req)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	reportCapacity(c, res)
+	c.JSON(http.StatusOK, res)
+}
 
-	r.POST("/query", func(c *gin.Context) {
-		var req domain.QueryRequest
-		if err := c.ShouldBindJSON(&req); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+func (h *handler) route(c *gin.Context) {
+	var body map[string]any
+	if c.Request.ContentLength != 0 && c.Request.Method != http.MethodGet {
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
 			return
 		}
+	}
 
-		ctx := c.Request.Context()
-		res, err := svc.HandleQuery(ctx, req)
+	req := domain.RouteRequest{
+		Method: c.Request.Method,
+		Path:   c.Request.URL.Path,
+		Query:  c.Request.URL.Query(),
+		Body:   body,
+	}
+	if format := wantsStream(c); format != noStream {
+		rows, err := h.svc.StreamRoute(c.Request.Context(), req)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			writeError(c, err)
 			return
 		}
+		writeStream(c, rows, format)
+		return
+	}
 
-		c.JSON(http.StatusOK, res)
-	})
+	res, err := h.svc.HandleRoute(c.Request.Context(), req)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	status := http.StatusOK
+	if mr, ok := res.(*domain.MutationResult); ok {
+		status = mutationStatus(mr)
+	}
+	out := gin.H{"data": res}
+	if units := bodyCapacity(c); units != nil {
+		out["consumed_capacity"] = units
+	}
+	c.JSON(status, out)
+}
+
+// mutationStatus is 201 Created for inserts and 200 OK for other writes.
+func mutationStatus(res *domain.MutationResult) int {
+	if res.Op == domain.MutationInsert {
+		return http.StatusCreated
+	}
+	return http.StatusOK
+}
+
+// callerHeader names the calling service. The gateway does not authenticate
+// it; it is expected to be set by the mesh or auth proxy in front of it.
+const callerHeader = "X-Caller-ID"
+
+// callerIdentity records the caller on the request context so data sources
+// can apply per-caller policies.
+func callerIdentity(c *gin.Context) {
+	if caller := c.GetHeader(callerHeader); caller != "" {
+		c.Request = c.Request.WithContext(domain.WithCaller(c.Request.Context(), caller))
+	}
+	c.Next()
+}
+
+// writeError answers with the status statusFor picks and the error message.
+func writeError(c *gin.Context, err error) {
+	setRetryAfter(c, err)
+	c.JSON(statusFor(err), gin.H{"error": err.Error()})
+}
+
+// setRetryAfter tells a throttled caller how long to back off, when known.
+func setRetryAfter(c *gin.Context, err error) {
+	var throttled *domain.ThrottleError
+	if errors.As(err, &throttled) && throttled.RetryAfter > 0 {
+		seconds := int(math.Ceil(throttled.RetryAfter.Seconds()))
+		c.Header("Retry-After", strconv.Itoa(seconds))
+	}
+}
 
-	r.Run(":" + port)
+func statusFor(err error) int {
+	switch {
+	case errors.Is(err, domain.ErrRouteNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, domain.ErrMethodNotAllowed):
+		return http.StatusMethodNotAllowed
+	case errors.Is(err, domain.ErrUnknownSource), errors.Is(err, domain.ErrInvalidRequest):
+		return http.StatusBadRequest
+	case errors.Is(err, domain.ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, domain.ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, domain.ErrThrottled):
+		return http.StatusTooManyRequests
+	default:
+		return http.StatusInternalServerError
+	}
 }
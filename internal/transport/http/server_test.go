@@ -0,0 +1,78 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/thegodeveloper/data-gateway/internal/app"
+	"github.com/thegodeveloper/data-gateway/internal/domain"
+)
+
+// failing is a data source whose every read fails with err.
+type failing struct{ err error }
+
+func (f failing) Query(context.Context, domain.QueryRequest) (any, error) { return nil, f.err }
+
+func TestStatusFor(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{fmt.Errorf("%w: invalid table name 'users;DROP TABLE users'", domain.ErrInvalidRequest), http.StatusBadRequest},
+		{fmt.Errorf("query failed for 'pg': %w", fmt.Errorf("%w: unknown column 'ghost' on table 'users'", domain.ErrInvalidRequest)), http.StatusBadRequest},
+		{fmt.Errorf("%w: 'nope'", domain.ErrUnknownSource), http.StatusBadRequest},
+		{domain.ErrRouteNotFound, http.StatusNotFound},
+		{domain.ErrMethodNotAllowed, http.StatusMethodNotAllowed},
+		{fmt.Errorf("%w: raw SQL is disabled", domain.ErrForbidden), http.StatusForbidden},
+		{fmt.Errorf("%w: condition failed", domain.ErrConflict), http.StatusConflict},
+		{&domain.ThrottleError{Target: "orders", Err: errors.New("slow down")}, http.StatusTooManyRequests},
+		{errors.New("connection refused"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		if got := statusFor(tt.err); got != tt.want {
+			t.Errorf("statusFor(%v) = %d, want %d", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestQueryErrorsReachTheClient(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name       string
+		err        error
+		status     int
+		retryAfter string
+	}{
+		{"invalid request", fmt.Errorf("%w: invalid table name 'users;DROP TABLE users'", domain.ErrInvalidRequest), http.StatusBadRequest, ""},
+		{"throttled", &domain.ThrottleError{Target: "orders", RetryAfter: 1500 * time.Millisecond, Err: errors.New("budget")}, http.StatusTooManyRequests, "2"},
+	}
+	for _, tt := range tests {
+		svc := app.NewGatewayService(map[string]domain.DataSource{"pg": failing{tt.err}}, nil)
+		r := gin.New()
+		h := &handler{svc: svc}
+		r.POST("/query", h.query)
+
+		w := httptest.NewRecorder()
+		body := `{"source": "pg", "params": {"table": "users;DROP TABLE users"}}`
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/query", strings.NewReader(body)))
+
+		if w.Code != tt.status {
+			t.Errorf("%s: status = %d, want %d", tt.name, w.Code, tt.status)
+		}
+		if got := w.Header().Get("Retry-After"); got != tt.retryAfter {
+			t.Errorf("%s: Retry-After = %q, want %q", tt.name, got, tt.retryAfter)
+		}
+		var res struct{ Error string }
+		if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil || !strings.Contains(res.Error, tt.err.Error()) {
+			t.Errorf("%s: body = %s", tt.name, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,136 @@
+// Package http
+// internal/transport/http/stream.go
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/thegodeveloper/data-gateway/internal/domain"
+)
+
+type streamFormat int
+
+const (
+	noStream streamFormat = iota
+	ndjsonStream
+	jsonStream
+)
+
+const ndjsonType = "application/x-ndjson"
+
+// flushEvery bounds how many rows sit in the response buffer before they
+// are pushed to the client.
+const flushEvery = 100
+
+// wantsStream picks a streamed response from ?stream=ndjson|json or an
+// Accept header asking for NDJSON.
+func wantsStream(c *gin.Context) streamFormat {
+	switch c.Query("stream") {
+	case "ndjson":
+		return ndjsonStream
+	case "json", "true":
+		return jsonStream
+	}
+	if strings.Contains(c.GetHeader("Accept"), ndjsonType) {
+		return ndjsonStream
+	}
+	return noStream
+}
+
+// writeStream sends rows as they are produced. NDJSON is one JSON object per
+// line; the JSON form is {"data": [...]}, written element by element. Writes
+// block while the client is slow, which in turn holds back the data source,
+// and a failed write ends the iteration so the source releases its rows.
+//
+// An error before the first row gets a normal error response. Once the
+// status line is out, NDJSON ends with an {"error": ...} line and JSON with
+// an "error" member after "data"; a row that cannot be encoded ends the
+// stream the same way. The consumed capacity follows the rows as an HTTP
+// trailer.
+func writeStream(c *gin.Context, rows domain.RowStream, format streamFormat) {
+	w := c.Writer
+	// Rows are encoded into buf first so one that fails to encode leaves
+	// nothing half written.
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	started := false
+	n := 0
+
+	used := domain.CapacityFrom(c.Request.Context())
+	defer func() {
+		if value, ok := formatCapacity(used); ok && started {
+			w.Header().Set(capacityHeader, value)
+		}
+	}()
+
+	start := func() {
+		started = true
+		if used != nil {
+			c.Header("Trailer", capacityHeader)
+		}
+		if format == ndjsonStream {
+			c.Header("Content-Type", ndjsonType)
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		c.Header("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		w.WriteString(`{"data":[`)
+	}
+
+	fail := func(err error) {
+		if !started {
+			writeError(c, err)
+			return
+		}
+		out := json.NewEncoder(w)
+		if format == ndjsonStream {
+			out.Encode(gin.H{"error": err.Error()})
+		} else {
+			w.WriteString(`],"error":`)
+			out.Encode(err.Error())
+			w.WriteString("}")
+		}
+		w.Flush()
+	}
+
+	for row, err := range rows {
+		if err != nil {
+			fail(err)
+			return
+		}
+		buf.Reset()
+		if err := enc.Encode(row); err != nil {
+			fail(fmt.Errorf("failed to encode row %d: %w", n+1, err))
+			return
+		}
+
+		if !started {
+			start()
+		}
+		if format == jsonStream && n > 0 {
+			if _, err := w.WriteString(","); err != nil {
+				return
+			}
+		}
+		if _, err := w.Write(buf.Bytes()); err != nil {
+			return
+		}
+		if n++; n%flushEvery == 0 {
+			w.Flush()
+		}
+	}
+
+	if !started {
+		start()
+	}
+	if format == jsonStream {
+		w.WriteString("]}")
+	}
+	w.Flush()
+}
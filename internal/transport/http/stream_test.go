@@ -0,0 +1,258 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/thegodeveloper/data-gateway/internal/datasource/postgres"
+	"github.com/thegodeveloper/data-gateway/internal/domain"
+)
+
+// discard is a response writer that counts lines and keeps nothing, so the
+// heap only holds what writeStream itself retains.
+type discard struct {
+	header http.Header
+	lines  int
+}
+
+func (d *discard) Header() http.Header { return d.header }
+func (d *discard) WriteHeader(int)     {}
+func (d *discard) Flush()              {}
+
+func (d *discard) Write(p []byte) (int, error) {
+	d.lines += bytes.Count(p, []byte("\n"))
+	return len(p), nil
+}
+
+// rows yields n distinct rows and records the largest live heap seen while
+// they are written, sampled after a GC every sampleEvery rows.
+func rows(n int, peak *uint64) domain.RowStream {
+	const sampleEvery = 5000
+	return func(yield func(map[string]any, error) bool) {
+		var m runtime.MemStats
+		for i := range n {
+			row := map[string]any{
+				"id":    i,
+				"email": fmt.Sprintf("user-%d@example.com", i),
+				"note":  fmt.Sprintf("%0128d", i),
+			}
+			if !yield(row, nil) {
+				return
+			}
+			if i%sampleEvery == 0 {
+				runtime.GC()
+				runtime.ReadMemStats(&m)
+				*peak = max(*peak, m.HeapAlloc)
+			}
+		}
+	}
+}
+
+// pgUsers is a database/sql driver whose every query returns n generated
+// users rows, typed the way lib/pq reports them, so they reach writeStream
+// through the Postgres adapter's decoder. Like rows, it records the largest
+// live heap seen while they are read.
+type pgUsers struct {
+	n    int
+	peak *uint64
+}
+
+func (p pgUsers) Connect(context.Context) (driver.Conn, error)                 { return p, nil }
+func (p pgUsers) Driver() driver.Driver                                        { return p }
+func (p pgUsers) Open(string) (driver.Conn, error)                             { return p, nil }
+func (p pgUsers) Prepare(string) (driver.Stmt, error)                          { return p, nil }
+func (p pgUsers) Close() error                                                 { return nil }
+func (p pgUsers) Begin() (driver.Tx, error)                                    { return p, nil }
+func (p pgUsers) BeginTx(context.Context, driver.TxOptions) (driver.Tx, error) { return p, nil }
+func (p pgUsers) Commit() error                                                { return nil }
+func (p pgUsers) Rollback() error                                              { return nil }
+func (p pgUsers) NumInput() int                                                { return -1 }
+func (p pgUsers) Exec([]driver.Value) (driver.Result, error)                   { return driver.RowsAffected(0), nil }
+func (p pgUsers) Query([]driver.Value) (driver.Rows, error)                    { return &pgUserRows{pgUsers: p}, nil }
+
+type pgUserRows struct {
+	pgUsers
+	i int
+	m runtime.MemStats
+}
+
+var pgUserColumns = []struct{ name, typ string }{
+	{"id", "INT8"}, {"email", "TEXT"}, {"note", "TEXT"}, {"created_at", "TIMESTAMPTZ"},
+}
+
+func (r *pgUserRows) Columns() []string {
+	names := make([]string, len(pgUserColumns))
+	for i, col := range pgUserColumns {
+		names[i] = col.name
+	}
+	return names
+}
+
+func (r *pgUserRows) ColumnTypeDatabaseTypeName(i int) string { return pgUserColumns[i].typ }
+
+func (r *pgUserRows) Next(dest []driver.Value) error {
+	const sampleEvery = 5000
+	if r.i == r.n {
+		return io.EOF
+	}
+	dest[0] = int64(r.i)
+	dest[1] = fmt.Sprintf("user-%d@example.com", r.i)
+	dest[2] = fmt.Sprintf("%0128d", r.i)
+	dest[3] = time.Unix(int64(r.i), 0).UTC()
+	if r.i%sampleEvery == 0 {
+		runtime.GC()
+		runtime.ReadMemStats(&r.m)
+		*r.peak = max(*r.peak, r.m.HeapAlloc)
+	}
+	r.i++
+	return nil
+}
+
+// pgStream streams n rows from a Postgres source over pgUsers.
+func pgStream(t *testing.T, n int, peak *uint64) domain.RowStream {
+	t.Helper()
+	src := postgres.NewPostgresSource(sql.OpenDB(pgUsers{n: n, peak: peak}))
+	stream, err := src.Stream(context.Background(), domain.QueryRequest{
+		Source: "pg",
+		Params: map[string]any{"query": "SELECT id, email, note, created_at FROM users"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return stream
+}
+
+// streamHeap streams n rows from source as NDJSON and returns how far the
+// live heap grew above where it started.
+func streamHeap(t *testing.T, n int, source func(n int, peak *uint64) domain.RowStream) uint64 {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	w := &discard{header: make(http.Header)}
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/query?stream=ndjson", nil)
+
+	var m runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&m)
+	base, peak := m.HeapAlloc, m.HeapAlloc
+
+	writeStream(c, source(n, &peak), ndjsonStream)
+	if w.lines != n {
+		t.Fatalf("wrote %d lines, want %d", w.lines, n)
+	}
+	return peak - base
+}
+
+func TestWriteStreamMemoryIsBounded(t *testing.T) {
+	if testing.Short() {
+		t.Skip("streams a large result")
+	}
+	sources := []struct {
+		name   string
+		source func(n int, peak *uint64) domain.RowStream
+	}{
+		{"iterator", rows},
+		{"postgres", func(n int, peak *uint64) domain.RowStream { return pgStream(t, n, peak) }},
+	}
+	for _, src := range sources {
+		small := streamHeap(t, 10_000, src.source)
+		large := streamHeap(t, 200_000, src.source)
+
+		// 200k rows of ~200 bytes are ~40 MiB once encoded; retaining even a
+		// fraction of them would show here.
+		const ceiling = 4 << 20
+		if large > ceiling {
+			t.Fatalf("%s: heap grew by %d bytes streaming 200k rows, want at most %d", src.name, large, ceiling)
+		}
+		if large > small+1<<20 {
+			t.Fatalf("%s: heap growth depends on row count: %d bytes for 10k rows, %d for 200k", src.name, small, large)
+		}
+	}
+}
+
+func TestWriteStreamPostgresRows(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var peak uint64
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/query?stream=json", nil)
+	writeStream(c, pgStream(t, 3, &peak), jsonStream)
+
+	var body struct{ Data []map[string]any }
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("body %s: %v", w.Body, err)
+	}
+	want := map[string]any{"id": 2.0, "email": "user-2@example.com", "note": fmt.Sprintf("%0128d", 2), "created_at": "1970-01-01T00:00:02Z"}
+	if len(body.Data) != 3 || !reflect.DeepEqual(body.Data[2], want) {
+		t.Fatalf("data = %v, want 3 rows ending in %v", body.Data, want)
+	}
+}
+
+func TestWriteStreamEndsWithTheErrorOfAnUnencodableRow(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	stream := func(yield func(map[string]any, error) bool) {
+		_ = yield(map[string]any{"id": 1}, nil) &&
+			yield(map[string]any{"id": 2, "score": math.Inf(1)}, nil) &&
+			yield(map[string]any{"id": 3}, nil)
+	}
+	tests := []struct {
+		format streamFormat
+		want   string
+	}{
+		{jsonStream, `{"data":[{"id":1}
+],"error":"failed to encode row 2: json: unsupported value: +Inf"
+}`},
+		{ndjsonStream, `{"id":1}
+{"error":"failed to encode row 2: json: unsupported value: +Inf"}
+`},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/query", nil)
+		writeStream(c, stream, tt.format)
+		if got := w.Body.String(); got != tt.want {
+			t.Errorf("format %d: body = %q, want %q", tt.format, got, tt.want)
+		}
+		if tt.format == jsonStream && !json.Valid(w.Body.Bytes()) {
+			t.Errorf("format %d: body is not valid JSON", tt.format)
+		}
+	}
+}
+
+func TestWriteStreamAllocsPerRowAreConstant(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	perRow := func(n int) float64 {
+		allocs := testing.AllocsPerRun(5, func() {
+			w := &discard{header: make(http.Header)}
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, "/query?stream=ndjson", nil)
+			row := map[string]any{"id": 1, "email": "user@example.com"}
+			writeStream(c, func(yield func(map[string]any, error) bool) {
+				for range n {
+					if !yield(row, nil) {
+						return
+					}
+				}
+			}, ndjsonStream)
+		})
+		return allocs / float64(n)
+	}
+	small, large := perRow(1_000), perRow(50_000)
+	if large > small*1.1+0.5 {
+		t.Fatalf("allocations per row grow with the result: %.2f for 1k rows, %.2f for 50k", small, large)
+	}
+}
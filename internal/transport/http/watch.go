@@ -0,0 +1,181 @@
+// Package http
+// internal/transport/http/watch.go
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/thegodeveloper/data-gateway/internal/domain"
+)
+
+// heartbeat is how often an idle subscription is pinged, so proxies keep
+// the connection open and departed clients are noticed.
+const heartbeat = 15 * time.Second
+
+// writeWait bounds one WebSocket write to a slow client.
+const writeWait = 10 * time.Second
+
+var upgrader = websocket.Upgrader{}
+
+// watch subscribes to a data source's changes. GET /watch takes the source
+// and a JSON params object in the query string, for EventSource and
+// WebSocket clients; POST /watch takes a domain.WatchRequest body.
+//
+// Changes are sent as Server-Sent Events whose id is the change ID, or as
+// WebSocket text messages when the request is an upgrade. A reconnect
+// resumes after the Last-Event-ID header, which EventSource sends by itself,
+// or the last_event_id query parameter.
+func (h *handler) watch(c *gin.Context) {
+	var req domain.WatchRequest
+	if c.Request.Method == http.MethodPost {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	} else {
+		req.Source = c.Query("source")
+		if raw := c.Query("params"); raw != "" {
+			if err := json.Unmarshal([]byte(raw), &req.Params); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid 'params': " + err.Error()})
+				return
+			}
+		}
+		req.ResumeAfter = c.Query("last_event_id")
+	}
+	if id := c.GetHeader("Last-Event-ID"); id != "" {
+		req.ResumeAfter = id
+	}
+
+	// A hijacked WebSocket connection no longer cancels the request context,
+	// so the subscription gets its own.
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	defer cancel()
+
+	changes, err := h.svc.HandleWatch(ctx, req)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	events := pump(ctx, changes)
+	if websocket.IsWebSocketUpgrade(c.Request) {
+		writeWebSocket(c, cancel, events)
+		return
+	}
+	writeEvents(c, events)
+}
+
+type watchEvent struct {
+	change domain.Change
+	err    error
+}
+
+// pump ranges over changes on its own goroutine, so the writer can send
+// heartbeats while the source waits for the next change. It stops when ctx
+// ends.
+func pump(ctx context.Context, changes domain.ChangeStream) <-chan watchEvent {
+	events := make(chan watchEvent)
+	go func() {
+		defer close(events)
+		for change, err := range changes {
+			select {
+			case events <- watchEvent{change, err}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return events
+}
+
+// writeEvents sends changes as Server-Sent Events. A failure is sent as an
+// "error" event and ends the response.
+func writeEvents(c *gin.Context, events <-chan watchEvent) {
+	w := c.Writer
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	w.Flush()
+
+	ticker := time.NewTicker(heartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if _, err := w.WriteString(": ping\n\n"); err != nil {
+				return
+			}
+		case ev, ok := <-events:
+			if !ok {
+				return
+			}
+			var data []byte
+			if ev.err == nil {
+				data, ev.err = json.Marshal(ev.change)
+			}
+			if ev.err != nil {
+				data, _ = json.Marshal(gin.H{"error": ev.err.Error()})
+				fmt.Fprintf(w, "event: error\ndata: %s\n\n", data)
+				w.Flush()
+				return
+			}
+			if _, err := fmt.Fprintf(w, "id: %s\ndata: %s\n\n", ev.change.ID, data); err != nil {
+				return
+			}
+		}
+		w.Flush()
+	}
+}
+
+// writeWebSocket upgrades the connection and sends each change as a JSON
+// text message. A failure is sent as {"error": ...} before the connection is
+// closed.
+func writeWebSocket(c *gin.Context, cancel context.CancelFunc, events <-chan watchEvent) {
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// Upgrade has already answered with an error status.
+		return
+	}
+	defer conn.Close()
+
+	// Reading processes pongs and the client's close frame; the
+	// subscription ends when the client goes away.
+	go func() {
+		defer cancel()
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(heartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeWait)); err != nil {
+				return
+			}
+		case ev, ok := <-events:
+			conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if !ok {
+				conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+				return
+			}
+			if ev.err != nil {
+				conn.WriteJSON(gin.H{"error": ev.err.Error()})
+				conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseInternalServerErr, ""))
+				return
+			}
+			if err := conn.WriteJSON(ev.change); err != nil {
+				return
+			}
+		}
+	}
+}
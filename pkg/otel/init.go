@@ -3,7 +3,9 @@ package otel
 import (
 	"context"
 	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
 	"go.opentelemetry.io/otel/sdk/resource"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
@@ -32,3 +34,29 @@ func InitTracer(serviceName string) (func(context.Context) error, error) {
 
 	return tp.Shutdown, nil
 }
+
+// InitMeter installs a MeterProvider that exports to the same OTLP endpoint
+// as the tracer, periodically. Meters created before it is installed, such
+// as the DynamoDB capacity counters, start exporting once it is.
+func InitMeter(serviceName string) (func(context.Context) error, error) {
+	ctx := context.Background()
+
+	exporter, err := otlpmetrichttp.New(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	mp := sdkmetric.NewMeterProvider(
+		sdkmetric.WithReader(sdkmetric.NewPeriodicReader(exporter)),
+		sdkmetric.WithResource(resource.NewWithAttributes(
+			semconv.SchemaURL,
+			semconv.ServiceName(serviceName),
+		)),
+	)
+
+	otel.SetMeterProvider(mp)
+
+	log.Println("[otel] OpenTelemetry meter initialized")
+
+	return mp.Shutdown, nil
+}